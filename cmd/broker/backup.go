@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	badgerstore "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/storage/badger"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/config"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// runBackup implementa `broker backup`: con -url pide el backup a un nodo
+// en marcha (GET /admin/backup); sin él abre -data directamente, lo que
+// exige que el broker esté parado. El archivo se verifica al terminar.
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("broker backup", flag.ContinueOnError)
+	base := fs.String("url", "", "REST de un nodo en marcha (p. ej. http://localhost:8080)")
+	token := fs.String("token", os.Getenv("MOM_TOKEN"), "X-Token para -url (env MOM_TOKEN)")
+	dir := fs.String("data", config.Default().Storage.DataDir, "Badger dir (sin -url; broker parado)")
+	out := fs.String("out", "", "archivo de salida (obligatorio)")
+	since := fs.Uint64("since", 0, "versión desde la que copiar (0 = backup completo)")
+	prev := fs.String("since-file", "", "backup anterior: el nuevo es incremental desde él")
+	if err := fs.Parse(args); err != nil {
+		return exitFlag(err)
+	}
+	if *out == "" {
+		fmt.Fprintln(os.Stderr, "usage: broker backup -out file [-url http://node:8080 | -data dir] [-since N | -since-file prev.bak]")
+		return 2
+	}
+	if *prev != "" {
+		m, err := verifyFile(*prev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *prev, err)
+			return 1
+		}
+		*since = m.Next()
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *base != "" {
+		err = download(f, *base, *token, *since)
+	} else {
+		err = backupDir(f, *dir, *since)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		_ = os.Remove(*out)
+		return 1
+	}
+
+	m, err := verifyFile(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *out, err)
+		return 1
+	}
+	return printManifest(*out, m)
+}
+
+// download guarda en w el backup que sirve el nodo base.
+func download(w io.Writer, base, token string, since uint64) error {
+	u := strings.TrimRight(base, "/") + "/admin/backup?since=" + url.QueryEscape(strconv.FormatUint(since, 10))
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("%s: %s: %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// backupDir hace el backup abriendo el directorio de datos.
+func backupDir(w io.Writer, dir string, since uint64) error {
+	store, err := badgerstore.Open(dir, badgerstore.DefaultOptions())
+	if err != nil {
+		return fmt.Errorf("open %s (is the broker running? use -url): %w", dir, err)
+	}
+	defer store.Close()
+	_, err = store.Backup(context.Background(), w, since)
+	return err
+}
+
+// runRestore implementa `broker restore`: carga en -data, con el broker
+// parado, un backup completo y los incrementales que lo siguen, en orden;
+// para volver a un punto anterior basta con no pasar los posteriores.
+// Todos los archivos se verifican antes de cargar el primero.
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("broker restore", flag.ContinueOnError)
+	dir := fs.String("data", config.Default().Storage.DataDir, "Badger dir de destino (broker parado)")
+	force := fs.Bool("force", false, "carga un backup completo aunque -data ya tenga datos")
+	verify := fs.Bool("verify", false, "sólo verifica los archivos, sin cargar nada")
+	if err := fs.Parse(args); err != nil {
+		return exitFlag(err)
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: broker restore [-data dir] [-force] [-verify] full.bak [incr1.bak …]")
+		return 2
+	}
+
+	// cada incremental debe empezar donde acaba el anterior
+	var last *model.BackupManifest
+	for _, name := range files {
+		m, err := verifyFile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return 1
+		}
+		if last != nil && m.Since != last.Next() {
+			fmt.Fprintf(os.Stderr, "%s: starts at version %d, previous backup ends at %d\n", name, m.Since, last.Version)
+			return 1
+		}
+		last = &m
+		printManifest(name, m)
+	}
+	if *verify {
+		return 0
+	}
+
+	store, err := badgerstore.Open(*dir, badgerstore.DefaultOptions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s (stop the broker first): %v\n", *dir, err)
+		return 1
+	}
+	defer store.Close()
+	for i, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		st, err := f.Stat()
+		if err == nil {
+			_, err = store.Restore(context.Background(), f, st.Size(), *force && i == 0)
+		}
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "restored %s\n", name)
+	}
+	return 0
+}
+
+func verifyFile(name string) (model.BackupManifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return model.BackupManifest{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return model.BackupManifest{}, err
+	}
+	return badgerstore.VerifyBackup(f, st.Size())
+}
+
+// printManifest muestra el manifiesto de name en stdout (una línea JSON).
+func printManifest(name string, m model.BackupManifest) int {
+	js, _ := json.Marshal(struct {
+		File string `json:"file"`
+		model.BackupManifest
+		Next uint64 `json:"next"`
+	}{name, m, m.Next()})
+	fmt.Println(string(js))
+	return 0
+}
+
+// exitFlag traduce un error de parseo de flags al código de salida.
+func exitFlag(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
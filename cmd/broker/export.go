@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	badgermeta "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/meta/badger"
+	badgerstore "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/storage/badger"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/config"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/export"
+)
+
+// runExport implementa `broker export`: con -url lo pide a un nodo en
+// marcha (GET /admin/topics/:topic/export); sin él lee -data directamente,
+// con el broker parado. Sin -out escribe en stdout.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("broker export", flag.ContinueOnError)
+	base := fs.String("url", "", "REST de un nodo en marcha (p. ej. http://localhost:8080)")
+	token := fs.String("token", os.Getenv("MOM_TOKEN"), "X-Token para -url (env MOM_TOKEN)")
+	dir := fs.String("data", config.Default().Storage.DataDir, "Badger dir (sin -url; broker parado)")
+	topic := fs.String("topic", "", "tópico a exportar (obligatorio)")
+	part := fs.Int("partition", -1, "partición (-1 = todas)")
+	from := fs.Uint64("from", 0, "primer offset")
+	to := fs.Uint64("to", 0, "offset final, excluido (0 = hasta el final)")
+	key := fs.String("key", "", "sólo mensajes con esta clave")
+	since := fs.String("since", "", "sólo mensajes publicados desde este instante (RFC 3339)")
+	until := fs.String("until", "", "sólo mensajes publicados antes de este instante (RFC 3339)")
+	format := fs.String("format", "", "jsonl, csv o pb (por defecto, el de la extensión de -out o jsonl)")
+	out := fs.String("out", "", "archivo de salida (por defecto stdout)")
+	if err := fs.Parse(args); err != nil {
+		return exitFlag(err)
+	}
+	if *topic == "" {
+		fmt.Fprintln(os.Stderr, "usage: broker export -topic t [-url http://node:8080 | -data dir] [-partition p] [-from o] [-to o] [-key k] [-since t] [-until t] [-format f] [-out file]")
+		return 2
+	}
+	f := export.FormatOf(*out)
+	if *format != "" || f == "" {
+		var err error
+		if f, err = export.ParseFormat(orDefault(*format, "jsonl")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	q := model.ExportQuery{Topic: *topic, Partition: *part, From: *from, To: *to, Key: *key}
+	for _, t := range []struct {
+		name string
+		val  string
+		dst  *time.Time
+	}{{"since", *since, &q.Since}, {"until", *until, &q.Until}} {
+		if t.val == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, t.val)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-%s: %v\n", t.name, err)
+			return 2
+		}
+		*t.dst = v
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	var n int
+	var err error
+	if *base != "" {
+		n, err = exportURL(w, *base, *token, f, q)
+	} else {
+		n, err = exportDir(w, *dir, f, q)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if *out != "" {
+			_ = os.Remove(*out)
+		}
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "exported %d messages from %q\n", n, q.Topic)
+	return 0
+}
+
+// exportURL copia en w el export que sirve el nodo base; el número de
+// mensajes llega en el trailer X-Exported-Messages.
+func exportURL(w io.Writer, base, token string, f export.Format, q model.ExportQuery) (int, error) {
+	v := url.Values{}
+	v.Set("format", string(f))
+	v.Set("partition", strconv.Itoa(q.Partition))
+	v.Set("from", strconv.FormatUint(q.From, 10))
+	v.Set("to", strconv.FormatUint(q.To, 10))
+	if q.Key != "" {
+		v.Set("key", q.Key)
+	}
+	if !q.Since.IsZero() {
+		v.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		v.Set("until", q.Until.Format(time.RFC3339))
+	}
+	u := strings.TrimRight(base, "/") + "/admin/topics/" + url.PathEscape(q.Topic) + "/export?" + v.Encode()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return 0, fmt.Errorf("%s: %s: %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(resp.Trailer.Get("X-Exported-Messages"))
+	if err != nil {
+		return 0, fmt.Errorf("%s: export did not complete (missing X-Exported-Messages trailer)", u)
+	}
+	return n, nil
+}
+
+// exportDir exporta abriendo el directorio de datos.
+func exportDir(w io.Writer, dir string, f export.Format, q model.ExportQuery) (int, error) {
+	store, err := badgerstore.Open(dir, badgerstore.DefaultOptions())
+	if err != nil {
+		return 0, fmt.Errorf("open %s (is the broker running? use -url): %w", dir, err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	parts, err := badgermeta.New(store.DB()).GetTopic(ctx, q.Topic)
+	if err != nil {
+		return 0, err
+	}
+	ew, err := export.NewWriter(w, f)
+	if err != nil {
+		return 0, err
+	}
+	n, err := export.Export(ctx, store, ew, q, parts)
+	if cerr := ew.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// runImport implementa `broker import`: carga en -data, con el broker
+// parado, uno o más archivos exportados. Con -preserve-offsets cada
+// mensaje vuelve a su partición y offset (sirve para restaurar un tópico);
+// sin él se añaden al final, como si se publicaran de nuevo. Se niega con
+// el directorio de un nodo de clúster: lo escrito ahí no pasaría por el
+// consenso y las demás réplicas no lo tendrían.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("broker import", flag.ContinueOnError)
+	dir := fs.String("data", config.Default().Storage.DataDir, "Badger dir de destino (broker parado)")
+	topic := fs.String("topic", "", "tópico destino (por defecto, el de cada mensaje)")
+	part := fs.Int("partition", -1, "partición destino (-1 = la de cada mensaje)")
+	preserve := fs.Bool("preserve-offsets", false, "conserva offset e ID de cada mensaje")
+	format := fs.String("format", "", "jsonl, csv o pb (por defecto, el de la extensión de cada archivo)")
+	create := fs.Int("create", 0, "crea el tópico con N particiones si no existe")
+	if err := fs.Parse(args); err != nil {
+		return exitFlag(err)
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: broker import [-data dir] [-topic t] [-partition p] [-preserve-offsets] [-format f] [-create N] file …")
+		return 2
+	}
+
+	store, err := badgerstore.Open(*dir, badgerstore.DefaultOptions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s (stop the broker first): %v\n", *dir, err)
+		return 1
+	}
+	defer store.Close()
+	if replicated, err := store.Replicated(); err != nil || replicated {
+		if err == nil {
+			err = fmt.Errorf("%s belongs to a cluster node: an import would bypass replication; publish the messages through a running node instead", *dir)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	catalog := badgermeta.New(store.DB())
+	ctx := context.Background()
+
+	for _, name := range files {
+		f := export.FormatOf(name)
+		if *format != "" || f == "" {
+			if f, err = export.ParseFormat(orDefault(*format, "jsonl")); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+		n, err := importFile(ctx, store, catalog, name, f, *topic, *part, *preserve, *create)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v (imported %d messages)\n", name, err, n)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "imported %d messages from %s\n", n, name)
+	}
+	return 0
+}
+
+// importFile carga un archivo. Si -topic no se da, el tópico es el del
+// primer mensaje y todos los del archivo deben compartirlo.
+func importFile(ctx context.Context, store *badgerstore.Store, catalog *badgermeta.Catalog,
+	name string, f export.Format, topic string, part int, preserve bool, create int) (int, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	r, err := export.NewReader(file, f)
+	if err != nil {
+		return 0, err
+	}
+
+	first, err := r.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	opts := export.ImportOptions{Topic: orDefault(topic, first.Topic), Partition: part, Preserve: preserve}
+	opts.Partitions, err = catalog.GetTopic(ctx, opts.Topic)
+	if err != nil && create > 0 {
+		if err = catalog.CreateTopic(ctx, opts.Topic, create, nil, "import"); err == nil {
+			opts.Partitions = create
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return export.Import(ctx, store, &sameTopic{Reader: r, first: &first, topic: first.Topic, fixed: topic != ""}, opts)
+}
+
+// sameTopic devuelve primero el mensaje ya leído y, cuando el tópico sale
+// del archivo, rechaza los mensajes de otro tópico.
+type sameTopic struct {
+	export.Reader
+	first *model.Message
+	topic string
+	fixed bool
+}
+
+func (s *sameTopic) Read() (model.Message, error) {
+	if m := s.first; m != nil {
+		s.first = nil
+		return *m, nil
+	}
+	m, err := s.Reader.Read()
+	if err == nil && !s.fixed && m.Topic != s.topic {
+		return m, fmt.Errorf("message from topic %q in a %q export (use -topic)", m.Topic, s.topic)
+	}
+	return m, err
+}
+
+// orDefault devuelve s o, si está vacío, def.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -1,10 +1,78 @@
 package main
 
-import "time"
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/config"
+)
 
 func main() {
-	_ = buildServer() // goroutine already serving
-	for {
-		time.Sleep(time.Hour)
-	} // block main
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "config":
+			os.Exit(runConfig(args[1:]))
+		case "backup":
+			os.Exit(runBackup(args[1:]))
+		case "restore":
+			os.Exit(runRestore(args[1:]))
+		case "export":
+			os.Exit(runExport(args[1:]))
+		case "import":
+			os.Exit(runImport(args[1:]))
+		}
+	}
+
+	c, err := config.Load("broker", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	b := buildServer(c) // goroutines already serving
+	<-ctx.Done()
+	stop() // una segunda señal mata el proceso sin esperar
+
+	log.Printf("[broker] apagando (plazo %s)…", b.timeout)
+	sctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	if err := b.Shutdown(sctx); err != nil {
+		log.Printf("[broker] apagado incompleto: %v", err)
+		return
+	}
+	log.Printf("[broker] apagado limpio")
+}
+
+// runConfig implementa `broker config print [flags]`: muestra la
+// configuración efectiva tras aplicar archivo, entorno y flags.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: broker config print [-config file] [flags]")
+		return 2
+	}
+	c, err := config.Load("broker config print", args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := c.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
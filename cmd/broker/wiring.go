@@ -1,70 +1,241 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"errors"
 	"log"
-	"os"
+	"net/http"
+	"sync"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 
 	// adapters
+	auditadapter "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/audit"
 	authadapter "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/auth"
 	badgermeta "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/meta/badger"
+	quotaadapter "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/quota"
+	remoteadapter "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/remote"
 	restadapter "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/rest"
 	badgerstore "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/storage/badger"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/config"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
 
 	// use-cases
 	"github.com/MateoRamirezRubio1/project_MOM/internal/app/usecase"
 )
 
-func buildServer() *gin.Engine {
-	/* ───── flags ───── */
-	dataDir := flag.String("data", "./data", "Badger dir")
-	httpAddr := flag.String("http", ":8080", "REST bind")
-	clusterCF := flag.String("cluster", "cluster.json", "cluster config")
-	flag.Parse()
+// broker agrupa lo que hay que parar al apagar el proceso.
+type broker struct {
+	http    *http.Server
+	pub     inbound.Publisher // publicaciones acks=0 pendientes
+	grpc    *grpc.Server      // nil en single-node
+	fan     *cluster.Fanout
+	cons    *cluster.Consensus // propuestas en curso (nil en single-node)
+	store   *badgerstore.Store
+	stop    context.CancelFunc // detiene los bucles de fondo
+	bg      *sync.WaitGroup    // bucles de fondo en marcha
+	tracing func(context.Context) error
+	timeout time.Duration // plazo para drenar peticiones
+}
+
+// Shutdown apaga en orden: deja de aceptar peticiones REST y drena las que
+// están en curso, espera las publicaciones acks=0 y las propuestas del
+// consenso (con su espera de acks), cierra gRPC, detiene los bucles de
+// fondo y espera a que terminen, vuelca las trazas y cierra Badger. ctx
+// acota el drenado; lo que escribe en Badger se espera siempre, para no
+// cerrarlo debajo.
+func (b *broker) Shutdown(ctx context.Context) error {
+	var errList []error
+	if err := b.http.Shutdown(ctx); err != nil {
+		errList = append(errList, err)
+	}
+	if err := b.pub.Flush(ctx); err != nil {
+		errList = append(errList, err)
+	}
+	if err := b.cons.Drain(ctx); err != nil {
+		errList = append(errList, err)
+	}
+	cluster.StopGRPC(ctx, b.grpc)
+	b.stop()
+	b.bg.Wait()
+	_ = b.pub.Flush(context.Background()) // acks=0 que no cupieron en ctx
+	b.fan.Close()
+	if err := b.tracing(ctx); err != nil {
+		errList = append(errList, err)
+	}
+	if err := b.store.Close(); err != nil {
+		errList = append(errList, err)
+	}
+	return errors.Join(errList...)
+}
+
+func buildServer(c *config.Config) *broker {
+	// bucles de fondo: se cancelan en Shutdown
+	ctx, stop := context.WithCancel(context.Background())
+	bg := &sync.WaitGroup{}
+
+	/* ───── trazas ───── */
+	shutdownTracing, err := tracing.Setup(context.Background(), c.Tracing.Exporter, c.Tracing.Endpoint, "mom-broker")
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	/* ───── Badger ───── */
-	store, err := badgerstore.New(*dataDir)
+	store, err := badgerstore.Open(c.Storage.DataDir, badgerstore.Options{
+		InFlightTTL:       c.Storage.InFlightTTL.D(),
+		RequeueInterval:   c.Storage.RequeueInterval.D(),
+		Retain:            c.Retention.Topics,
+		RetentionInterval: c.Retention.Interval.D(),
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	cluster.RebuildHWM(store.DB()) // mantiene compatibilidad
-	store.StartRequeueLoop()
+	store.StartRetentionLoop(ctx, bg)
 	catalog := badgermeta.New(store.DB())
 
-	/* ───── auth demo ───── */
-	authStore := authadapter.NewInMemory()
+	/* ───── métricas de almacenamiento ───── */
+	metrics.RegisterBadger(store.DB())
+	registerQueueGauges(catalog, store)
+
+	/* ───── auditoría (tópico interno en Badger) ───── */
+	auditLog := auditadapter.NewTopicLog(store)
 
 	/* ───── cluster (opcional) ───── */
 	var fan *cluster.Fanout
-	selfID := os.Getenv("NODE_ID")
-	cfg, _ := cluster.Load(*clusterCF)
-	cluster.GlobalCfg, cluster.GlobalSelfID = cfg, selfID // ★
+	var cons *cluster.Consensus // log replicado por partición
+	var grpcSrv *grpc.Server
+	selfID := c.NodeID
+	cfg := c.ClusterConfig()
+	cluster.SetConfig(cfg)
+	cluster.GlobalSelfID = selfID // ★
 
 	if cfg != nil && selfID != "" {
-		fan = cluster.NewFanout(cfg, selfID)
-		if n := cfg.Self(selfID); n != nil {
-			// ← se pasa también catalog
-			cluster.StartGRPCServer(n.Host, store, catalog)
-			log.Printf("[cluster] node %s activo (%s)", selfID, n.Host)
-		}
+		fan = cluster.NewFanout(cfg, selfID, c.Cluster.Secret)
+	}
+	// en clúster el catálogo y las colas se replican: sus escrituras pasan
+	// por los logs de metadatos y de colas, y las lecturas siguen siendo
+	// locales
+	var meta outbound.MetaStore = catalog
+	var msgs outbound.MessageStore = store
+	var metaLog *cluster.ReplicatedMeta
+	var queueLog *cluster.ReplicatedQueues
+	var antiEntropy *cluster.AntiEntropy
+	det := cluster.NewDetector(fan, c.Cluster.SuspectAfter.D(), c.Cluster.DeadAfter.D())
+	if fan != nil {
+		cons = cluster.NewConsensus(selfID, fan, store, store, c.ConsensusOptions())
+		cluster.RegisterBacklogGauge(cons)
+		metaLog = cluster.NewReplicatedMeta(catalog, catalog, cons, fan, store, store)
+		queueLog = cluster.NewReplicatedQueues(store, store, cons, fan, store,
+			c.Storage.InFlightTTL.D(), c.Storage.RequeueInterval.D())
+		meta, msgs = metaLog, queueLog
+		antiEntropy = cluster.NewAntiEntropy(selfID, fan, cons, store, store, catalog)
+	} else {
+		store.StartRequeueLoop(ctx, bg) // en clúster reencola el líder del log de colas
 	}
 
+	/* ───── cuotas: se guardan en el catálogo (replicado en clúster) ───── */
+	quotaStore := quotaadapter.NewInMemory(c.Quota.Model(), meta, store)
+	if metaLog != nil {
+		metaLog.OnQuota(quotaStore.Apply)
+	}
+	quotaStore.StartUsageLoop(ctx, bg, c.Quota.UsageInterval.D())
+
+	/* ───── auth: tokens de la configuración y cuentas del catálogo ───── */
+	if cfg != nil && c.Auth.Secret == "" {
+		log.Printf("[auth] auth.secret is empty: login tokens only work on the node that issued them")
+	}
+	authStore := authadapter.NewAccounts(authadapter.NewInMemoryWith(c.Auth.Tokens, c.Auth.Admins, c.Auth.Open),
+		meta, c.Auth.Secret, c.Auth.TokenTTL.D())
+
+	// lo que no atiende este nodo va a su dueño (sin efecto en single-node)
+	fwd := cluster.NewForwarder(selfID, fan, cons, c.Cluster.Forward)
+
 	/* ───── use-cases ───── */
-	adminUC := usecase.NewAdmin(catalog)
-	pubUC := usecase.NewPublisher(catalog, store, authStore, fan)
-	consUC := usecase.NewConsumer(catalog, store)
-	queueUC := usecase.NewQueue(catalog, store)
+	adminUC := usecase.NewAdmin(meta, store, quotaStore, auditLog, c.Cluster.ReplicationFactor, store, cons, fwd)
+	localPub := usecase.NewPublisher(meta, store, authStore, quotaStore, cons)
+	consUC := usecase.NewConsumer(meta, store, cons, fwd)
+	queueUC := usecase.NewQueue(meta, msgs, quotaStore)
+	healthUC := usecase.NewHealth(meta, store, fan, cons, antiEntropy, c.Cluster.ReadyMaxLag)
+	clusterUC := usecase.NewCluster(fan, c.MembershipFile())
+	pubUC := usecase.WithForwarding(fwd, localPub)
+
+	if fan != nil {
+		// Start antes de servir: un Append entrante ya usa el ctx y el wg
+		cons.Start(ctx, bg, catalog)
+		grpcSrv = cluster.StartGRPCServer(c.GRPCAddr(), c.Cluster.Secret, store, cons,
+			&cluster.Local{Pub: localPub, Meta: metaLog, Queues: queueLog}, det)
+		metaLog.Start(ctx, bg)
+		queueLog.Start(ctx, bg)
+		antiEntropy.Start(ctx, bg, c.Timeouts.Reconcile.D())
+		cluster.StartHealthLoop(ctx, bg, fan, c.Timeouts.HealthProbe.D())
+		if f := c.MembershipFile(); f != "" && c.Cluster.WatchInterval > 0 {
+			cluster.WatchFile(ctx, bg, f, c.Cluster.WatchInterval.D(), fan)
+		}
+		log.Printf("[cluster] node %s activo (%s, forward=%s)", selfID, c.GRPCAddr(), c.Cluster.Forward)
+	}
+
+	/* ───── mirrors desde otros clústeres ───── */
+	mirrorUC := usecase.NewMirror(meta, store, adminUC, cons, remoteadapter.Dialer(c.Mirror.Timeout.D(), c.Mirror.Sources),
+		usecase.MirrorOptions{
+			Interval:     c.Mirror.Interval.D(),
+			SyncInterval: c.Mirror.SyncInterval.D(),
+			Batch:        c.Mirror.Batch,
+			Sources:      c.Mirror.Sources,
+		})
+	mirrorUC.Start(ctx, bg)
+
+	/* ───── monitor de lag ───── */
+	usecase.NewLagMonitor(consUC, store, c.Lag.Threshold).Start(ctx, bg, c.Lag.Interval.D())
 
 	/* ───── router ───── */
-	r := restadapter.NewRouter(adminUC, pubUC, consUC, queueUC, authStore)
+	r := restadapter.NewRouter(adminUC, pubUC, consUC, queueUC, healthUC, clusterUC, mirrorUC, usecase.NewAccess(meta, authStore),
+		authStore, auditLog)
+	srv := &http.Server{
+		Addr:         c.REST.Addr,
+		Handler:      r,
+		ReadTimeout:  c.REST.ReadTimeout.D(),
+		WriteTimeout: c.REST.WriteTimeout.D(),
+	}
 	go func() {
-		log.Printf("[REST] escuchando en %s", *httpAddr)
-		if err := r.Run(*httpAddr); err != nil {
+		log.Printf("[REST] escuchando en %s", c.REST.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal(err)
 		}
 	}()
-	return r
+	return &broker{
+		http: srv, pub: localPub, grpc: grpcSrv, fan: fan, cons: cons, store: store,
+		stop: stop, bg: bg, tracing: shutdownTracing, timeout: c.Timeouts.Shutdown.D(),
+	}
+}
+
+// registerQueueGauges publica profundidad y mensajes en vuelo de cada cola;
+// se calculan en cada scrape a partir del catálogo y el store.
+func registerQueueGauges(meta outbound.MetaStore, msg outbound.MessageStore) {
+	gauge := func(pick func(model.QueueStats) int) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			ctx := context.Background()
+			queues, err := meta.ListQueues(ctx)
+			if err != nil {
+				return nil
+			}
+			out := make([]metrics.Sample, 0, len(queues))
+			for _, q := range queues {
+				if st, err := msg.QueueStats(ctx, q); err == nil {
+					out = append(out, metrics.Sample{Labels: []string{q}, Value: float64(pick(st))})
+				}
+			}
+			return out
+		}
+	}
+	metrics.RegisterGaugeFunc("mom_queue_in_flight", "Messages delivered and not yet acked.",
+		[]string{"queue"}, gauge(func(st model.QueueStats) int { return st.InFlight }))
+	metrics.RegisterGaugeFunc("mom_queue_depth", "Messages waiting to be dequeued.",
+		[]string{"queue"}, gauge(func(st model.QueueStats) int { return st.Depth }))
 }
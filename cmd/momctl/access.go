@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+/*──────────  cuentas  ──────────*/
+
+// runUsers gestiona las cuentas (/admin/users); hace falta ser
+// administrador. Sin -pass la contraseña se lee de stdin.
+func runUsers(c *cli, args []string) error {
+	return verb(args, "users list|create|passwd|delete", map[string]func([]string) error{
+		"list": func(args []string) error {
+			if _, err := parse(flag.NewFlagSet("users list", flag.ContinueOnError), args, 0, "users list"); err != nil {
+				return err
+			}
+			var list []model.User
+			if err := c.api.Do(c.ctx, http.MethodGet, "/admin/users", nil, nil, &list); err != nil {
+				return err
+			}
+			return c.show(list, func(w *tabwriter.Writer) {
+				row(w, "NAME", "ADMIN", "CREATOR", "CREATED")
+				for _, u := range list {
+					row(w, u.Name, u.Admin, u.Creator, when(u.CreatedAt))
+				}
+			})
+		},
+		"create": func(args []string) error {
+			fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+			pass := fs.String("pass", os.Getenv("MOM_PASSWORD"), "contraseña (env MOM_PASSWORD; si falta, se lee de stdin)")
+			admin := fs.Bool("admin", false, "puede usar las rutas /admin")
+			pos, err := parse(fs, args, 1, "users create name [-admin] [-pass p]")
+			if err != nil {
+				return err
+			}
+			if *pass == "" {
+				if *pass, err = readPassword(); err != nil {
+					return err
+				}
+			}
+			body := map[string]any{"name": pos[0], "pass": *pass, "admin": *admin}
+			return c.api.Do(c.ctx, http.MethodPost, "/admin/users", nil, body, nil)
+		},
+		"passwd": func(args []string) error {
+			fs := flag.NewFlagSet("users passwd", flag.ContinueOnError)
+			pass := fs.String("pass", os.Getenv("MOM_PASSWORD"), "contraseña nueva (env MOM_PASSWORD; si falta, se lee de stdin)")
+			pos, err := parse(fs, args, 1, "users passwd name [-pass p]")
+			if err != nil {
+				return err
+			}
+			if *pass == "" {
+				if *pass, err = readPassword(); err != nil {
+					return err
+				}
+			}
+			return c.api.Do(c.ctx, http.MethodPatch, "/admin/users/"+url.PathEscape(pos[0]), nil, map[string]string{"pass": *pass}, nil)
+		},
+		"delete": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("users delete", flag.ContinueOnError), args, 1, "users delete name")
+			if err != nil {
+				return err
+			}
+			return c.api.Do(c.ctx, http.MethodDelete, "/admin/users/"+url.PathEscape(pos[0]), nil, nil, nil)
+		},
+	})
+}
+
+/*──────────  ACLs  ──────────*/
+
+// runACL gestiona las ACLs (/admin/acls); ver model.ACL.
+func runACL(c *cli, args []string) error {
+	return verb(args, "acl list|add|delete", map[string]func([]string) error{
+		"list": func(args []string) error {
+			if _, err := parse(flag.NewFlagSet("acl list", flag.ContinueOnError), args, 0, "acl list"); err != nil {
+				return err
+			}
+			var list []model.ACL
+			if err := c.api.Do(c.ctx, http.MethodGet, "/admin/acls", nil, nil, &list); err != nil {
+				return err
+			}
+			return c.show(list, func(w *tabwriter.Writer) {
+				row(w, "ID", "PRINCIPAL", "RESOURCE", "OPS", "CREATOR", "CREATED")
+				for _, a := range list {
+					row(w, a.ID, a.Principal, a.Resource, strings.Join(a.Ops, ","), a.Creator, when(a.CreatedAt))
+				}
+			})
+		},
+		"add": func(args []string) error {
+			fs := flag.NewFlagSet("acl add", flag.ContinueOnError)
+			principal := fs.String("principal", "", "usuario o * (todos)")
+			resource := fs.String("resource", "", "topic:<patrón> o queue:<patrón> (p. ej. topic:logs-*)")
+			ops := fs.String("ops", "", "operaciones separadas por comas: read, write, manage")
+			use := "acl add -principal u -resource topic:name -ops read,write"
+			if _, err := parse(fs, args, 0, use); err != nil {
+				return err
+			}
+			if *principal == "" || *resource == "" || *ops == "" {
+				fs.Usage()
+				return errUsage
+			}
+			req := model.ACL{Principal: *principal, Resource: *resource, Ops: strings.Split(*ops, ",")}
+			var acl model.ACL
+			if err := c.api.Do(c.ctx, http.MethodPost, "/admin/acls", nil, req, &acl); err != nil {
+				return err
+			}
+			return c.show(acl, func(w *tabwriter.Writer) { row(w, acl.ID) })
+		},
+		"delete": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("acl delete", flag.ContinueOnError), args, 1, "acl delete id")
+			if err != nil {
+				return err
+			}
+			return c.api.Do(c.ctx, http.MethodDelete, "/admin/acls/"+url.PathEscape(pos[0]), nil, nil, nil)
+		},
+	})
+}
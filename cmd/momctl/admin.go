@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+/*──────────  salida  ──────────*/
+
+// show imprime v como JSON con -o json; si no, llama a table.
+func (c *cli) show(v any, table func(w *tabwriter.Writer)) error {
+	if c.output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+func row(w *tabwriter.Writer, cols ...any) {
+	s := make([]string, len(cols))
+	for i, c := range cols {
+		s[i] = fmt.Sprint(c)
+	}
+	fmt.Fprintln(w, strings.Join(s, "\t"))
+}
+
+func when(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+/*──────────  tópicos y colas  ──────────*/
+
+func runTopics(c *cli, args []string) error {
+	return verb(args, "topics list|describe|create|delete", map[string]func([]string) error{
+		"list": func(args []string) error {
+			if _, err := parse(flag.NewFlagSet("topics list", flag.ContinueOnError), args, 0, "topics list"); err != nil {
+				return err
+			}
+			var list []string
+			if err := c.api.Do(c.ctx, http.MethodGet, "/topics", nil, nil, &list); err != nil {
+				return err
+			}
+			return c.show(list, func(w *tabwriter.Writer) {
+				for _, name := range list {
+					row(w, name)
+				}
+			})
+		},
+		"describe": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("topics describe", flag.ContinueOnError), args, 1, "topics describe name")
+			if err != nil {
+				return err
+			}
+			d, err := c.describeTopic(pos[0])
+			if err != nil {
+				return err
+			}
+			return c.show(d, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "topic %s: %d partitions, rf %s, created by %s at %s\n\n",
+					d.Name, d.Partitions, rf(d.ReplicationFactor()), d.Creator, when(d.CreatedAt))
+				row(w, "PARTITION", "START", "END", "MESSAGES", "BYTES", "REPLICAS")
+				for _, p := range d.PartitionStats {
+					reps := "all"
+					if p.Partition < len(d.Replicas) {
+						reps = strings.Join(d.Replicas[p.Partition], ",")
+					}
+					row(w, p.Partition, p.StartOffset, p.EndOffset, p.Messages, p.Bytes, reps)
+				}
+			})
+		},
+		"create": func(args []string) error {
+			fs := flag.NewFlagSet("topics create", flag.ContinueOnError)
+			parts := fs.Int("partitions", 3, "particiones")
+			factor := fs.Int("rf", 0, "réplicas por partición (0 = la del clúster)")
+			pos, err := parse(fs, args, 1, "topics create name [-partitions N] [-rf N]")
+			if err != nil {
+				return err
+			}
+			body := map[string]any{"name": pos[0], "partitions": *parts, "replication_factor": *factor}
+			return c.api.Do(c.ctx, http.MethodPost, "/topics", nil, body, nil)
+		},
+		"delete": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("topics delete", flag.ContinueOnError), args, 1, "topics delete name")
+			if err != nil {
+				return err
+			}
+			return c.api.Do(c.ctx, http.MethodDelete, "/topics/"+url.PathEscape(pos[0]), nil, nil, nil)
+		},
+	})
+}
+
+func (c *cli) describeTopic(name string) (model.TopicDescription, error) {
+	var d model.TopicDescription
+	err := c.api.Do(c.ctx, http.MethodGet, "/topics/"+url.PathEscape(name), nil, nil, &d)
+	return d, err
+}
+
+func rf(n int) string {
+	if n == 0 {
+		return "all"
+	}
+	return fmt.Sprint(n)
+}
+
+func runQueues(c *cli, args []string) error {
+	return verb(args, "queues list|describe|create|delete", map[string]func([]string) error{
+		"list": func(args []string) error {
+			if _, err := parse(flag.NewFlagSet("queues list", flag.ContinueOnError), args, 0, "queues list"); err != nil {
+				return err
+			}
+			var list []string
+			if err := c.api.Do(c.ctx, http.MethodGet, "/queues", nil, nil, &list); err != nil {
+				return err
+			}
+			return c.show(list, func(w *tabwriter.Writer) {
+				for _, name := range list {
+					row(w, name)
+				}
+			})
+		},
+		"describe": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("queues describe", flag.ContinueOnError), args, 1, "queues describe name")
+			if err != nil {
+				return err
+			}
+			var d model.QueueDescription
+			if err := c.api.Do(c.ctx, http.MethodGet, "/queues/"+url.PathEscape(pos[0]), nil, nil, &d); err != nil {
+				return err
+			}
+			return c.show(d, func(w *tabwriter.Writer) {
+				row(w, "NAME", "DEPTH", "IN FLIGHT", "BYTES", "CREATOR", "CREATED")
+				row(w, d.Name, d.Depth, d.InFlight, d.Bytes, d.Creator, when(d.CreatedAt))
+			})
+		},
+		"create": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("queues create", flag.ContinueOnError), args, 1, "queues create name")
+			if err != nil {
+				return err
+			}
+			return c.api.Do(c.ctx, http.MethodPost, "/queues", nil, map[string]string{"name": pos[0]}, nil)
+		},
+		"delete": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("queues delete", flag.ContinueOnError), args, 1, "queues delete name")
+			if err != nil {
+				return err
+			}
+			return c.api.Do(c.ctx, http.MethodDelete, "/queues/"+url.PathEscape(pos[0]), nil, nil, nil)
+		},
+	})
+}
+
+/*──────────  cuotas  ──────────*/
+
+type quotaInfo struct {
+	Scope string           `json:"scope"`
+	Name  string           `json:"name"`
+	Quota model.Quota      `json:"quota"`
+	Usage model.QuotaUsage `json:"usage"`
+}
+
+// runQuotas consulta y cambia las cuotas de un usuario, tópico o cola.
+// set sólo modifica los límites que se pasan; los demás se conservan.
+func runQuotas(c *cli, args []string) error {
+	get := func(scope, name string) (quotaInfo, error) {
+		var q quotaInfo
+		err := c.api.Do(c.ctx, http.MethodGet, "/admin/quotas/"+url.PathEscape(scope)+"/"+url.PathEscape(name), nil, nil, &q)
+		return q, err
+	}
+	show := func(q quotaInfo) error {
+		return c.show(q, func(w *tabwriter.Writer) {
+			row(w, "LIMIT", "VALUE", "USAGE")
+			row(w, "msgs_per_sec", q.Quota.MsgsPerSec, "")
+			row(w, "bytes_per_sec", q.Quota.BytesPerSec, "")
+			row(w, "max_topics", q.Quota.MaxTopics, q.Usage.Topics)
+			row(w, "max_queues", q.Quota.MaxQueues, q.Usage.Queues)
+			row(w, "max_storage_bytes", q.Quota.MaxStorage, q.Usage.StoredBytes)
+		})
+	}
+	return verb(args, "quotas get|set user|topic|queue name", map[string]func([]string) error{
+		"get": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("quotas get", flag.ContinueOnError), args, 2, "quotas get user|topic|queue name")
+			if err != nil {
+				return err
+			}
+			q, err := get(pos[0], pos[1])
+			if err != nil {
+				return err
+			}
+			return show(q)
+		},
+		"set": func(args []string) error {
+			fs := flag.NewFlagSet("quotas set", flag.ContinueOnError)
+			msgs := fs.Float64("msgs-per-sec", 0, "mensajes/s (0 = sin límite)")
+			bytes := fs.Float64("bytes-per-sec", 0, "bytes/s (0 = sin límite)")
+			topics := fs.Int("max-topics", 0, "tópicos que puede crear (sólo user)")
+			queues := fs.Int("max-queues", 0, "colas que puede crear (sólo user)")
+			storage := fs.Int64("max-storage", 0, "bytes almacenados")
+			pos, err := parse(fs, args, 2, "quotas set user|topic|queue name [-msgs-per-sec N] [-bytes-per-sec N] [-max-topics N] [-max-queues N] [-max-storage N]")
+			if err != nil {
+				return err
+			}
+			q, err := get(pos[0], pos[1])
+			if err != nil {
+				return err
+			}
+			fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "msgs-per-sec":
+					q.Quota.MsgsPerSec = *msgs
+				case "bytes-per-sec":
+					q.Quota.BytesPerSec = *bytes
+				case "max-topics":
+					q.Quota.MaxTopics = *topics
+				case "max-queues":
+					q.Quota.MaxQueues = *queues
+				case "max-storage":
+					q.Quota.MaxStorage = *storage
+				}
+			})
+			path := "/admin/quotas/" + url.PathEscape(pos[0]) + "/" + url.PathEscape(pos[1])
+			if err := c.api.Do(c.ctx, http.MethodPut, path, nil, q.Quota, nil); err != nil {
+				return err
+			}
+			return show(q)
+		},
+	})
+}
+
+/*──────────  clúster  ──────────*/
+
+func runCluster(c *cli, args []string) error {
+	return verb(args, "cluster status|nodes", map[string]func([]string) error{
+		"status": func(args []string) error {
+			if _, err := parse(flag.NewFlagSet("cluster status", flag.ContinueOnError), args, 0, "cluster status"); err != nil {
+				return err
+			}
+			var st model.ClusterStatus
+			if err := c.api.Do(c.ctx, http.MethodGet, "/cluster/status", nil, nil, &st); err != nil {
+				return err
+			}
+			return c.show(st, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "node %s, leader %s, %d partition leaders\n\n", st.Self, orDefault(st.Leader, "-"), len(st.PartitionLeaders))
+				row(w, "PEER", "HOST", "STATE", "SINCE", "LAST PING", "ERROR")
+				for _, p := range st.Peers {
+					since, ping := "-", "-"
+					if p.Since != nil {
+						since = when(*p.Since)
+					}
+					if p.LastPing != nil {
+						ping = when(*p.LastPing)
+					}
+					row(w, p.ID, p.Host, p.State, since, ping, orDefault(p.LastError, "-"))
+				}
+			})
+		},
+		"nodes": func(args []string) error {
+			if _, err := parse(flag.NewFlagSet("cluster nodes", flag.ContinueOnError), args, 0, "cluster nodes"); err != nil {
+				return err
+			}
+			var nodes []model.Node
+			if err := c.api.Do(c.ctx, http.MethodGet, "/admin/cluster/nodes", nil, nil, &nodes); err != nil {
+				return err
+			}
+			return c.show(nodes, func(w *tabwriter.Writer) {
+				row(w, "ID", "GRPC", "REST")
+				for _, n := range nodes {
+					row(w, n.ID, n.Host, orDefault(n.REST, "-"))
+				}
+			})
+		},
+	})
+}
@@ -0,0 +1,167 @@
+// momctl es el cliente de línea de comandos del broker: habla con la API
+// REST de cualquier nodo (el nodo reenvía al líder lo que no le toca).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/remote"
+)
+
+const usage = `usage: momctl [-url http://node:8080] [-token t] [-o table|json] <command> …
+
+  login -user u [-pass p]           inicia sesión y guarda el token
+  logout                            borra la sesión guardada
+  topics list|describe|create|delete
+  queues list|describe|create|delete
+  produce [-queue] [-key k] [-key-sep s] [-acks a] name [file …]
+  consume [-queue] [-group g] [-partition p] [-n N] [-follow] name
+  groups describe group
+  lag [-group g] [-topic t]
+  offsets reset -group g -topic t (-to-earliest|-to-latest|-to-offset N|-shift-by N)
+  quotas get|set scope name
+  users list|create|passwd|delete
+  acl list|add|delete
+  cluster status|nodes
+
+"momctl <command> -h" muestra las opciones de cada comando.`
+
+// cli es lo que comparten los comandos: el cliente REST ya autenticado,
+// la sesión guardada y el formato de salida.
+type cli struct {
+	ctx    context.Context
+	api    *remote.Client
+	base   string
+	sess   session
+	output string
+}
+
+type command func(c *cli, args []string) error
+
+var commands = map[string]command{
+	"login":   runLogin,
+	"logout":  runLogout,
+	"topics":  runTopics,
+	"queues":  runQueues,
+	"produce": runProduce,
+	"consume": runConsume,
+	"groups":  runGroups,
+	"lag":     runLag,
+	"offsets": runOffsets,
+	"quotas":  runQuotas,
+	"users":   runUsers,
+	"acl":     runACL,
+	"cluster": runCluster,
+}
+
+// errUsage indica argumentos incorrectos; el comando ya mostró su uso.
+var errUsage = errors.New("usage")
+
+func main() { os.Exit(run(os.Args[1:])) }
+
+func run(args []string) int {
+	sess, err := loadSession()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "momctl: %v\n", err)
+		return 1
+	}
+	fs := flag.NewFlagSet("momctl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), usage) }
+	base := fs.String("url", envOr("MOM_URL", orDefault(sess.URL, "http://localhost:8080")), "REST de un nodo (env MOM_URL)")
+	token := fs.String("token", envOr("MOM_TOKEN", sess.Token), "X-Token (env MOM_TOKEN; por defecto el del último login)")
+	output := fs.String("o", "table", "salida: table | json")
+	timeout := fs.Duration("timeout", 30*time.Second, "plazo por petición")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "momctl: -o must be table or json, not %q\n", *output)
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		if fs.Arg(0) != "" {
+			fmt.Fprintf(os.Stderr, "momctl: unknown command %q\n", fs.Arg(0))
+		}
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	c := &cli{ctx: ctx, api: remote.New(*base, *token, *timeout), base: *base, sess: sess, output: *output}
+	switch err := cmd(c, fs.Args()[1:]); {
+	case err == nil || errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case ctx.Err() != nil:
+		return 130
+	default:
+		fmt.Fprintf(os.Stderr, "momctl: %v\n", err)
+		return 1
+	}
+}
+
+// parse acepta las opciones antes o después de los argumentos
+// (`topics create t -partitions 6`) y exige exactamente n argumentos
+// (n < 0: cualquier número).
+func parse(fs *flag.FlagSet, args []string, n int, use string) ([]string, error) {
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: momctl %s\n", use)
+		fs.PrintDefaults()
+	}
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		pos, args = append(pos, args[0]), args[1:]
+	}
+	if n >= 0 && len(pos) != n {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return pos, nil
+}
+
+// verb separa el subcomando de `momctl topics list` y similares.
+func verb(args []string, use string, verbs map[string]func([]string) error) error {
+	if len(args) > 0 {
+		if f, ok := verbs[args[0]]; ok {
+			return f(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "usage: momctl %s\n", use)
+	return errUsage
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
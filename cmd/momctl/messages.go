@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/export"
+	"github.com/google/uuid"
+)
+
+// maxLine es el tamaño máximo de un mensaje leído línea a línea.
+const maxLine = 16 << 20
+
+/*──────────  produce  ──────────*/
+
+// runProduce publica cada línea de stdin (o de los archivos) como un
+// mensaje; con -whole cada archivo es un único mensaje.
+func runProduce(c *cli, args []string) error {
+	fs := flag.NewFlagSet("produce", flag.ContinueOnError)
+	queue := fs.Bool("queue", false, "name es una cola, no un tópico")
+	key := fs.String("key", "", "clave de todos los mensajes")
+	sep := fs.String("key-sep", "", "separa clave y payload en cada línea (p. ej. \":\")")
+	acks := fs.String("acks", "", "confirmación: 0, 1 o all (por defecto, la del broker)")
+	whole := fs.Bool("whole", false, "cada archivo es un solo mensaje")
+	pos, err := parse(fs, args, -1, "produce [-queue] [-key k] [-key-sep s] [-acks a] [-whole] name [file …]  (sin archivos: stdin)")
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	name, files := pos[0], pos[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	path := "/topics/" + url.PathEscape(name) + "/messages"
+	var q url.Values
+	if *queue {
+		path = "/queues/" + url.PathEscape(name) + "/messages"
+	} else if *acks != "" {
+		q = url.Values{"acks": {*acks}}
+	}
+	send := func(payload string) error {
+		k := *key
+		if *sep != "" {
+			if before, after, ok := strings.Cut(payload, *sep); ok {
+				k, payload = before, after
+			}
+		}
+		body := map[string]string{"payload": payload}
+		if !*queue {
+			body["key"] = k
+		}
+		return c.api.Do(c.ctx, http.MethodPost, path, q, body, nil)
+	}
+
+	n := 0
+	for _, file := range files {
+		var r io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		if *whole {
+			raw, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			if err := send(string(raw)); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			n++
+			continue
+		}
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64<<10), maxLine)
+		for sc.Scan() {
+			if err := send(sc.Text()); err != nil {
+				return fmt.Errorf("%s: message %d: %w", file, n+1, err)
+			}
+			n++
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "produced %d messages to %s\n", n, name)
+	return nil
+}
+
+/*──────────  consume  ──────────*/
+
+// printer escribe los mensajes consumidos: en texto, uno por línea, o en
+// un formato de export (jsonl, csv, pb) que `broker import` acepta.
+type printer struct {
+	w     export.Writer
+	text  *tabwriter.Writer
+	queue bool
+}
+
+func newPrinter(format string, queue bool) (*printer, error) {
+	if format == "text" {
+		return &printer{text: tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', 0), queue: queue}, nil
+	}
+	f, err := export.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	w, err := export.NewWriter(os.Stdout, f)
+	return &printer{w: w}, err
+}
+
+func (p *printer) print(m model.Message) error {
+	if p.w != nil {
+		return p.w.Write(m)
+	}
+	if p.queue {
+		row(p.text, m.ID, string(m.Payload))
+	} else {
+		row(p.text, m.PartID, m.Offset, orDefault(m.Key, "-"), string(m.Payload))
+	}
+	return p.text.Flush()
+}
+
+func (p *printer) close() error {
+	if p.w != nil {
+		return p.w.Close()
+	}
+	return p.text.Flush()
+}
+
+// runConsume lee un tópico como miembro de -group y confirma lo leído, o
+// desencola de una cola y hace ack. Sin -follow termina cuando no quedan
+// mensajes.
+func runConsume(c *cli, args []string) error {
+	fs := flag.NewFlagSet("consume", flag.ContinueOnError)
+	queue := fs.Bool("queue", false, "name es una cola, no un tópico")
+	group := fs.String("group", "default", "grupo de consumo")
+	part := fs.Int("partition", -1, "partición (-1 = todas)")
+	batch := fs.Int("max", 100, "mensajes por petición")
+	limit := fs.Int("n", 0, "termina tras N mensajes (0 = sin límite)")
+	follow := fs.Bool("follow", false, "sigue esperando mensajes nuevos")
+	interval := fs.Duration("interval", time.Second, "espera entre sondeos con -follow")
+	noCommit := fs.Bool("no-commit", false, "no confirma offsets ni hace ack (lee una sola vez)")
+	format := fs.String("format", "text", "salida: text, jsonl, csv o pb")
+	pos, err := parse(fs, args, 1, "consume [-queue] [-group g] [-partition p] [-max N] [-n N] [-follow] [-no-commit] [-format f] name")
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(*format, *queue)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	next := func() ([]model.Message, error) { return c.dequeue(pos[0], !*noCommit) }
+	if !*queue {
+		parts := []int{*part}
+		if *part < 0 {
+			d, err := c.describeTopic(pos[0])
+			if err != nil {
+				return err
+			}
+			parts = parts[:0]
+			for i := range d.Partitions {
+				parts = append(parts, i)
+			}
+		}
+		next = func() ([]model.Message, error) { return c.pull(pos[0], *group, parts, *batch, !*noCommit) }
+	}
+
+	n := 0
+	for {
+		msgs, err := next()
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if err := p.print(m); err != nil {
+				return err
+			}
+			if n++; *limit > 0 && n >= *limit {
+				return nil
+			}
+		}
+		if len(msgs) > 0 && !*noCommit {
+			continue
+		}
+		if !*follow || *noCommit {
+			return nil
+		}
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// pull lee una tanda de cada partición y, con commit, confirma el offset
+// siguiente al último mensaje recibido.
+func (c *cli) pull(topic, group string, parts []int, max int, commit bool) ([]model.Message, error) {
+	var out []model.Message
+	for _, p := range parts {
+		q := url.Values{"group": {group}, "partition": {strconv.Itoa(p)}, "max": {strconv.Itoa(max)}}
+		var msgs []model.Message
+		if err := c.api.Do(c.ctx, http.MethodGet, "/topics/"+url.PathEscape(topic)+"/messages", q, nil, &msgs); err != nil {
+			return out, err
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+		if commit {
+			if err := c.commit(topic, group, p, msgs[len(msgs)-1].Offset+1); err != nil {
+				return out, err
+			}
+		}
+		out = append(out, msgs...)
+	}
+	return out, nil
+}
+
+// dequeue saca un mensaje de la cola y, con ack, lo confirma.
+func (c *cli) dequeue(queue string, ack bool) ([]model.Message, error) {
+	path := "/queues/" + url.PathEscape(queue)
+	var m model.Message
+	if err := c.api.Do(c.ctx, http.MethodGet, path+"/messages", nil, nil, &m); err != nil {
+		return nil, err
+	}
+	if m.ID == uuid.Nil { // cola vacía
+		return nil, nil
+	}
+	if ack {
+		if err := c.api.Do(c.ctx, http.MethodPost, path+"/ack", nil, map[string]string{"id": m.ID.String()}, nil); err != nil {
+			return nil, err
+		}
+	}
+	return []model.Message{m}, nil
+}
+
+func (c *cli) commit(topic, group string, part int, offset uint64) error {
+	body := map[string]any{"group": group, "partition": part, "offset": offset}
+	return c.api.Do(c.ctx, http.MethodPost, "/topics/"+url.PathEscape(topic)+"/offsets", nil, body, nil)
+}
+
+/*──────────  grupos y offsets  ──────────*/
+
+func runGroups(c *cli, args []string) error {
+	return verb(args, "groups describe group", map[string]func([]string) error{
+		"describe": func(args []string) error {
+			pos, err := parse(flag.NewFlagSet("groups describe", flag.ContinueOnError), args, 1, "groups describe group")
+			if err != nil {
+				return err
+			}
+			var d model.GroupDescription
+			if err := c.api.Do(c.ctx, http.MethodGet, "/groups/"+url.PathEscape(pos[0]), nil, nil, &d); err != nil {
+				return err
+			}
+			return c.show(d, func(w *tabwriter.Writer) {
+				row(w, "TOPIC", "PARTITION", "COMMITTED", "END", "LAG")
+				for _, o := range d.Offsets {
+					row(w, o.Topic, o.Partition, o.Committed, o.EndOffset, o.Lag)
+				}
+			})
+		},
+	})
+}
+
+func runLag(c *cli, args []string) error {
+	fs := flag.NewFlagSet("lag", flag.ContinueOnError)
+	group := fs.String("group", "", "sólo este grupo")
+	topic := fs.String("topic", "", "sólo este tópico")
+	if _, err := parse(fs, args, 0, "lag [-group g] [-topic t]"); err != nil {
+		return err
+	}
+	q := url.Values{}
+	if *group != "" {
+		q.Set("group", *group)
+	}
+	if *topic != "" {
+		q.Set("topic", *topic)
+	}
+	var lags []model.ConsumerLag
+	if err := c.api.Do(c.ctx, http.MethodGet, "/lag", q, nil, &lags); err != nil {
+		return err
+	}
+	return c.show(lags, func(w *tabwriter.Writer) {
+		row(w, "GROUP", "TOPIC", "PARTITION", "COMMITTED", "END", "LAG")
+		for _, l := range lags {
+			row(w, l.Group, l.Topic, l.Partition, l.Committed, l.EndOffset, l.Lag)
+		}
+	})
+}
+
+type offsetReset struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Old       uint64 `json:"old"`
+	New       uint64 `json:"new"`
+}
+
+// runOffsets mueve los offsets confirmados de un grupo; el destino se
+// acota al rango que aún guarda cada partición.
+func runOffsets(c *cli, args []string) error {
+	return verb(args, "offsets reset -group g -topic t …", map[string]func([]string) error{
+		"reset": func(args []string) error {
+			fs := flag.NewFlagSet("offsets reset", flag.ContinueOnError)
+			group := fs.String("group", "", "grupo de consumo (obligatorio)")
+			topic := fs.String("topic", "", "tópico (obligatorio)")
+			part := fs.Int("partition", -1, "partición (-1 = todas)")
+			earliest := fs.Bool("to-earliest", false, "al primer mensaje que queda")
+			latest := fs.Bool("to-latest", false, "al final: se salta lo pendiente")
+			to := fs.Int64("to-offset", -1, "a este offset")
+			shift := fs.Int64("shift-by", 0, "suma N (o resta, si es negativo) al offset actual")
+			dry := fs.Bool("dry-run", false, "sólo muestra los cambios")
+			use := "offsets reset -group g -topic t [-partition p] (-to-earliest | -to-latest | -to-offset N | -shift-by N) [-dry-run]"
+			if _, err := parse(fs, args, 0, use); err != nil {
+				return err
+			}
+			modes := 0
+			for _, set := range []bool{*earliest, *latest, *to >= 0, *shift != 0} {
+				if set {
+					modes++
+				}
+			}
+			if *group == "" || *topic == "" || modes != 1 {
+				fs.Usage()
+				return errUsage
+			}
+
+			d, err := c.describeTopic(*topic)
+			if err != nil {
+				return err
+			}
+			current, err := c.groupOffsets(*group, *topic)
+			if err != nil {
+				return err
+			}
+			var out []offsetReset
+			for _, st := range d.PartitionStats {
+				if *part >= 0 && st.Partition != *part {
+					continue
+				}
+				r := offsetReset{Topic: *topic, Partition: st.Partition, Old: current[st.Partition]}
+				switch {
+				case *earliest:
+					r.New = st.StartOffset
+				case *latest:
+					r.New = st.EndOffset
+				case *to >= 0:
+					r.New = uint64(*to)
+				default:
+					r.New = uint64(max(int64(r.Old)+*shift, 0))
+				}
+				r.New = min(max(r.New, st.StartOffset), st.EndOffset)
+				out = append(out, r)
+			}
+			if len(out) == 0 {
+				return errs.OutOfRange("partition %d of %q", *part, *topic)
+			}
+			if !*dry {
+				for _, r := range out {
+					if err := c.commit(r.Topic, *group, r.Partition, r.New); err != nil {
+						return err
+					}
+				}
+			}
+			return c.show(out, func(w *tabwriter.Writer) {
+				row(w, "TOPIC", "PARTITION", "OLD", "NEW")
+				for _, r := range out {
+					row(w, r.Topic, r.Partition, r.Old, r.New)
+				}
+			})
+		},
+	})
+}
+
+// groupOffsets devuelve los offsets confirmados del grupo en topic
+// (partición → offset); un grupo sin commits aún no existe.
+func (c *cli) groupOffsets(group, topic string) (map[int]uint64, error) {
+	var d model.GroupDescription
+	err := c.api.Do(c.ctx, http.MethodGet, "/groups/"+url.PathEscape(group), nil, nil, &d)
+	if errors.Is(err, errs.ErrNotFound) {
+		return map[int]uint64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int]uint64)
+	for _, o := range d.Offsets {
+		if o.Topic == topic {
+			out[o.Partition] = o.Committed
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// session es lo que momctl recuerda entre ejecuciones: el nodo y el token
+// del último login.
+type session struct {
+	URL   string `json:"url"`
+	User  string `json:"user"`
+	Token string `json:"token"`
+}
+
+// sessionPath es $MOMCTL_SESSION o <config del usuario>/momctl/session.json.
+func sessionPath() (string, error) {
+	if p := os.Getenv("MOMCTL_SESSION"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "momctl", "session.json"), nil
+}
+
+// loadSession devuelve la sesión guardada; vacía si no hay ninguna.
+func loadSession() (session, error) {
+	var s session
+	p, err := sessionPath()
+	if err != nil {
+		return s, nil // sin directorio de configuración: sin caché
+	}
+	raw, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", p, err)
+	}
+	return s, nil
+}
+
+// save guarda la sesión sólo para el usuario: contiene el token.
+func (s session) save() (string, error) {
+	p, err := sessionPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return "", err
+	}
+	js, _ := json.MarshalIndent(s, "", "  ")
+	return p, os.WriteFile(p, append(js, '\n'), 0o600)
+}
+
+func runLogin(c *cli, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	user := fs.String("user", "", "usuario")
+	pass := fs.String("pass", os.Getenv("MOM_PASSWORD"), "contraseña (env MOM_PASSWORD; si falta, se lee de stdin)")
+	if _, err := parse(fs, args, 0, "login -user u [-pass p]"); err != nil {
+		return err
+	}
+	if *user == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *pass == "" {
+		var err error
+		if *pass, err = readPassword(); err != nil {
+			return err
+		}
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+		User  string `json:"user"`
+	}
+	body := map[string]string{"user": *user, "pass": *pass}
+	if err := c.api.Do(c.ctx, http.MethodPost, "/login", nil, body, &resp); err != nil {
+		return err
+	}
+	p, err := session{URL: c.base, User: resp.User, Token: resp.Token}.save()
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "logged in to %s as %s (session in %s)\n", c.base, resp.User, p)
+	return nil
+}
+
+// readPassword lee una línea de stdin.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func runLogout(c *cli, args []string) error {
+	if _, err := parse(flag.NewFlagSet("logout", flag.ContinueOnError), args, 0, "logout"); err != nil {
+		return err
+	}
+	p, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+const (
+	// pageSize es cuántos eventos se leen por vuelta al recorrer el tópico.
+	pageSize = 512
+	// maxResults acota la respuesta cuando la consulta no trae Limit.
+	maxResults = 10_000
+)
+
+// topicLog guarda cada evento como un mensaje del tópico interno
+// model.AuditTopic (partición 0) en el MessageStore.
+type topicLog struct{ msg outbound.MessageStore }
+
+func NewTopicLog(msg outbound.MessageStore) *topicLog { return &topicLog{msg: msg} }
+
+var _ outbound.AuditLog = (*topicLog)(nil)
+
+func (l *topicLog) Record(ctx context.Context, ev model.AuditEvent) error {
+	js, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = l.msg.Append(ctx, model.Message{
+		Topic:    model.AuditTopic,
+		PartID:   0,
+		Key:      ev.Actor,
+		Payload:  js,
+		Producer: ev.Actor,
+	})
+	return err
+}
+
+// Query recorre el tópico hacia atrás, desde el final, de pageSize en
+// pageSize eventos, y para en cuanto tiene Limit coincidencias (maxResults
+// sin Limit) o llega a eventos anteriores a Since. Devuelve los más
+// recientes en orden temporal.
+func (l *topicLog) Query(ctx context.Context, f model.AuditFilter) ([]model.AuditEvent, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > maxResults {
+		limit = maxResults
+	}
+	end, err := l.msg.HighWatermark(ctx, model.AuditTopic, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.AuditEvent, 0)
+	for end > 0 && len(out) < limit {
+		from := end - min(end, pageSize)
+		msgs, err := l.msg.Read(ctx, model.AuditTopic, 0, from, int(end-from))
+		if err != nil {
+			return nil, err
+		}
+		page := make([]model.AuditEvent, 0, len(msgs))
+		older := false
+		for _, m := range msgs {
+			var ev model.AuditEvent
+			if err := json.Unmarshal(m.Payload, &ev); err != nil {
+				continue // entrada corrupta
+			}
+			older = older || (!f.Since.IsZero() && ev.Time.Before(f.Since))
+			if f.Match(ev) {
+				page = append(page, ev)
+			}
+		}
+		out = append(page, out...)
+		if older {
+			break
+		}
+		end = from
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	if len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out, nil
+}
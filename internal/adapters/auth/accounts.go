@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+const (
+	tokenPrefix = "mom." // mom.<claims base64>.<hmac base64>
+	hashScheme  = "pbkdf2-sha256"
+	hashIter    = 100_000
+)
+
+// Accounts autentica las cuentas del catálogo (contraseña en /login y
+// token firmado con HMAC) y, por debajo, los tokens de la configuración.
+// También decide las ACLs.
+type Accounts struct {
+	static *memoryAuth
+	meta   outbound.MetaStore
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewAccounts usa secret para firmar los tokens; vacío, se genera uno al
+// azar y los tokens sólo valen en este proceso (en un clúster todos los
+// nodos deben compartir secret).
+func NewAccounts(static *memoryAuth, meta outbound.MetaStore, secret string, ttl time.Duration) *Accounts {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &Accounts{static: static, meta: meta, secret: key, ttl: ttl}
+}
+
+var _ outbound.AuthStore = (*Accounts)(nil)
+
+// claims es lo que firma un token.
+type claims struct {
+	User    string `json:"u"`
+	Expires int64  `json:"exp"`
+	Hash    string `json:"h"` // huella del hash: cambiar la contraseña revoca
+}
+
+func (a *Accounts) Validate(ctx context.Context, token string) (string, bool) {
+	if strings.HasPrefix(token, tokenPrefix) {
+		return a.verify(ctx, token)
+	}
+	user, ok := a.static.Validate(ctx, token)
+	if !ok {
+		return "", false
+	}
+	// en modo abierto el nombre lo elige el cliente: no el de una cuenta
+	if !a.static.users[user] {
+		if _, err := a.meta.GetUser(ctx, user); err == nil {
+			return "", false
+		}
+	}
+	return user, true
+}
+
+func (a *Accounts) IsAdmin(ctx context.Context, user string) bool {
+	if a.static.users[user] {
+		return a.static.IsAdmin(ctx, user)
+	}
+	u, err := a.meta.GetUser(ctx, user)
+	return err == nil && u.Admin
+}
+
+// Login comprueba la contraseña de una cuenta. En modo abierto, un usuario
+// que no es cuenta ni viene de la configuración recibe su propio nombre
+// como token, como hasta ahora.
+func (a *Accounts) Login(ctx context.Context, user, pass string) (string, error) {
+	u, err := a.meta.GetUser(ctx, user)
+	switch {
+	case errs.Kind(err) == errs.ErrNotFound:
+		if a.static.open && user != "" && !a.static.users[user] {
+			return user, nil
+		}
+		return "", errs.New(errs.ErrUnauthenticated, "invalid user or password")
+	case err != nil:
+		return "", err
+	case !checkHash(u.Hash, pass):
+		return "", errs.New(errs.ErrUnauthenticated, "invalid user or password")
+	}
+	return a.sign(claims{User: u.Name, Expires: time.Now().Add(a.ttl).Unix(), Hash: fingerprint(u.Hash)}), nil
+}
+
+// Hash deriva con PBKDF2 lo que se guarda de una contraseña.
+func (a *Accounts) Hash(pass string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, pass, salt, hashIter, 32)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, hashIter, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// Authorize aplica las ACLs (ver model.ACL); los administradores pasan
+// siempre.
+func (a *Accounts) Authorize(ctx context.Context, user, op, kind, name string) error {
+	if a.IsAdmin(ctx, user) {
+		return nil
+	}
+	acls, err := a.meta.ListACLs(ctx)
+	if err != nil {
+		return err
+	}
+	covered := false
+	for _, acl := range acls {
+		if acl.Grants(user, op, kind, name) {
+			return nil
+		}
+		covered = covered || acl.Covers(kind, name)
+	}
+	if !covered {
+		return nil
+	}
+	return errs.Forbidden("user %q may not %s %s %q", user, op, kind, name)
+}
+
+/*──────────  tokens  ──────────*/
+
+func (a *Accounts) sign(c claims) string {
+	js, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(js)
+	return tokenPrefix + body + "." + base64.RawURLEncoding.EncodeToString(a.mac(body))
+}
+
+func (a *Accounts) mac(body string) []byte {
+	h := hmac.New(sha256.New, a.secret)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
+
+// verify exige firma válida, token sin caducar y que la cuenta siga
+// existiendo con la misma contraseña.
+func (a *Accounts) verify(ctx context.Context, token string) (string, bool) {
+	body, sig, ok := strings.Cut(strings.TrimPrefix(token, tokenPrefix), ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, a.mac(body)) {
+		return "", false
+	}
+	js, err := base64.RawURLEncoding.DecodeString(body)
+	var c claims
+	if err != nil || json.Unmarshal(js, &c) != nil || time.Now().Unix() >= c.Expires {
+		return "", false
+	}
+	u, err := a.meta.GetUser(ctx, c.User)
+	if err != nil || fingerprint(u.Hash) != c.Hash {
+		return "", false
+	}
+	return u.Name, true
+}
+
+func fingerprint(hash string) string {
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkHash compara pass con un hash de Hash.
+func checkHash(hash, pass string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err1 := base64.RawStdEncoding.DecodeString(parts[2])
+	want, err2 := base64.RawStdEncoding.DecodeString(parts[3])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, pass, salt, iter, len(want))
+	return err == nil && subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/meta"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+// newAccounts crea un Accounts con el token estático adm-tok (admin) y la
+// cuenta bob (contraseña "s3cret-pass").
+func newAccounts(t *testing.T, open bool) (*Accounts, outbound.MetaStore) {
+	t.Helper()
+	ctx := context.Background()
+	cat := meta.NewMemoryCatalog()
+	a := NewAccounts(NewInMemoryWith(map[string]string{"adm-tok": "admin"}, []string{"admin"}, open),
+		cat, "secret", time.Hour)
+	hash, err := a.Hash("s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cat.PutUser(ctx, model.User{Name: "bob", Hash: hash}); err != nil {
+		t.Fatal(err)
+	}
+	return a, cat
+}
+
+func TestLoginIssuesSignedTokens(t *testing.T) {
+	ctx := context.Background()
+	a, cat := newAccounts(t, false)
+
+	if _, err := a.Login(ctx, "bob", "wrong"); errs.Kind(err) != errs.ErrUnauthenticated {
+		t.Fatalf("wrong password: err = %v, want unauthenticated", err)
+	}
+	tok, err := a.Login(ctx, "bob", "s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := a.Validate(ctx, tok); !ok || user != "bob" {
+		t.Fatalf("Validate(login token) = %q, %v", user, ok)
+	}
+
+	// otra clave, o un token retocado, no valen
+	other := NewAccounts(NewInMemoryWith(nil, nil, false), cat, "other", time.Hour)
+	if _, ok := other.Validate(ctx, tok); ok {
+		t.Fatal("token accepted with another secret")
+	}
+	body, sig, _ := strings.Cut(tok, ".")
+	if _, ok := a.Validate(ctx, body+"x."+sig); ok {
+		t.Fatal("tampered token accepted")
+	}
+
+	// cambiar la contraseña revoca los tokens emitidos
+	u, _ := cat.GetUser(ctx, "bob")
+	if u.Hash, err = a.Hash("new-pass-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cat.PutUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.Validate(ctx, tok); ok {
+		t.Fatal("token still valid after a password change")
+	}
+}
+
+func TestExpiredTokenIsRejected(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newAccounts(t, false)
+	a.ttl = -time.Second
+	tok, err := a.Login(ctx, "bob", "s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.Validate(ctx, tok); ok {
+		t.Fatal("expired token accepted")
+	}
+}
+
+func TestOpenModeCannotImpersonateAccounts(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newAccounts(t, true)
+
+	if user, ok := a.Validate(ctx, "carol"); !ok || user != "carol" {
+		t.Fatalf("open token: %q, %v", user, ok)
+	}
+	if _, ok := a.Validate(ctx, "bob"); ok {
+		t.Fatal("open mode accepted an account name as token")
+	}
+	if tok, err := a.Login(ctx, "carol", ""); err != nil || tok != "carol" {
+		t.Fatalf("open login = %q, %v", tok, err)
+	}
+	if _, err := a.Login(ctx, "bob", ""); errs.Kind(err) != errs.ErrUnauthenticated {
+		t.Fatalf("account login without password: err = %v", err)
+	}
+}
+
+func TestAuthorizeAppliesACLs(t *testing.T) {
+	ctx := context.Background()
+	a, cat := newAccounts(t, false)
+	if err := cat.PutACL(ctx, model.ACL{ID: "1", Principal: "bob", Resource: "topic:logs-*",
+		Ops: []string{model.OpRead}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		user, op, name string
+		ok             bool
+	}{
+		{"bob", model.OpRead, "logs-app", true},
+		{"bob", model.OpWrite, "logs-app", false},
+		{"carol", model.OpRead, "logs-app", false},
+		{"carol", model.OpWrite, "orders", true}, // sin ACL que lo cubra
+		{"admin", model.OpManage, "logs-app", true},
+	}
+	for _, c := range cases {
+		err := a.Authorize(ctx, c.user, c.op, model.ScopeTopic, c.name)
+		if c.ok && err != nil || !c.ok && errs.Kind(err) != errs.ErrForbidden {
+			t.Errorf("Authorize(%s, %s, %s) = %v, want ok=%v", c.user, c.op, c.name, err, c.ok)
+		}
+	}
+	// las ACLs de tópicos no cubren colas del mismo nombre
+	if err := a.Authorize(ctx, "carol", model.OpRead, model.ScopeQueue, "logs-app"); err != nil {
+		t.Errorf("queue covered by a topic ACL: %v", err)
+	}
+}
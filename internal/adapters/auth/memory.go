@@ -6,18 +6,36 @@ import (
 )
 
 type memoryAuth struct {
-	mu    sync.RWMutex
-	store map[string]string // token -> username
+	mu     sync.RWMutex
+	store  map[string]string // token -> username
+	users  map[string]bool   // usuarios con token precargado
+	admins map[string]bool
+	open   bool // acepta tokens desconocidos
 }
 
 func NewInMemory() *memoryAuth {
-	m := &memoryAuth{store: make(map[string]string)}
-	m.store["alice"] = "alice" // usuario por defecto
+	return NewInMemoryWith(map[string]string{"alice": "alice"}, nil, true) // usuario por defecto
+}
+
+// NewInMemoryWith precarga tokens (token → usuario). Con open=false sólo
+// se aceptan esos tokens. admins son los usuarios que pueden usar /admin;
+// sólo cuentan si tienen token precargado.
+func NewInMemoryWith(tokens map[string]string, admins []string, open bool) *memoryAuth {
+	m := &memoryAuth{store: make(map[string]string, len(tokens)), users: map[string]bool{},
+		admins: map[string]bool{}, open: open}
+	for tok, user := range tokens {
+		m.store[tok] = user
+		m.users[user] = true
+	}
+	for _, u := range admins {
+		m.admins[u] = true
+	}
 	return m
 }
 
-// Validate acepta cualquier token no vacío. Si es la 1.ª vez que lo ve,
-// lo agrega al mapa para que futuros usos pasen más rápido.
+// Validate acepta cualquier token no vacío si el store es abierto. Si es
+// la 1.ª vez que lo ve, lo agrega al mapa para que futuros usos pasen más
+// rápido.
 func (a *memoryAuth) Validate(_ context.Context, token string) (string, bool) {
 	if token == "" {
 		return "", false
@@ -28,6 +46,11 @@ func (a *memoryAuth) Validate(_ context.Context, token string) (string, bool) {
 	if ok {
 		return user, true
 	}
+	// abierto, el usuario es el propio token: no puede ser el nombre de un
+	// usuario precargado, o bastaría con enviarlo para suplantarlo
+	if !a.open || a.users[token] {
+		return "", false
+	}
 
 	// token nuevo: lo registramos on-the-fly
 	a.mu.Lock()
@@ -35,3 +58,9 @@ func (a *memoryAuth) Validate(_ context.Context, token string) (string, bool) {
 	a.mu.Unlock()
 	return token, true
 }
+
+// IsAdmin exige que el usuario venga de un token precargado: en modo
+// abierto cualquiera elige su nombre.
+func (a *memoryAuth) IsAdmin(_ context.Context, user string) bool {
+	return a.admins[user] && a.users[user]
+}
@@ -0,0 +1,28 @@
+// Package badgererr traduce los errores de Badger a errores de dominio; lo
+// comparten el almacén de mensajes y el catálogo de metadatos.
+package badgererr
+
+import (
+	"errors"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/dgraph-io/badger/v4"
+)
+
+// Wrap traduce los errores propios de Badger a errores de dominio. Los que
+// ya son de dominio, o que Badger no conoce, se devuelven tal cual.
+func Wrap(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errs.Kind(err) != nil:
+		return err
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return errs.New(errs.ErrNotFound, "%v", err)
+	case errors.Is(err, badger.ErrConflict):
+		return errs.New(errs.ErrConflict, "%v", err)
+	case errors.Is(err, badger.ErrDBClosed):
+		return errs.New(errs.ErrUnavailable, "%v", err)
+	}
+	return err
+}
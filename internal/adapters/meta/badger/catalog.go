@@ -4,24 +4,47 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
-	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/badgererr"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
 	"github.com/dgraph-io/badger/v4"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	topicPrefix   = "t:" // t:<topic>           -> partitions(uint32)
 	creatorPrefix = "c:" // c:<kind>:<name>     -> json {creator}
 	offsetPrefix  = "o:" // o:<group>:<topic>:<part> -> offset(uint64)
+	replicaPrefix = "r:" // r:<topic>           -> json [][]string (réplicas por partición)
 
 	queuePrefix = "q:" // q:<queue> (valor vacío)
+
+	mirrorPrefix     = "y:" // y:<mirror>                -> json model.Mirror
+	checkpointPrefix = "k:" // k:<mirror>:<topic>:<part> -> próximo offset de origen(uint64)
+
+	userPrefix = "u:" // u:<user> -> json model.User
+	aclPrefix  = "l:" // l:<id>   -> json model.ACL
+
+	quotaPrefix = "s:" // s:<scope>:<name> -> json model.QuotaSetting
 )
 
+// prefixes son todas las claves del catálogo: lo que vuelca Snapshot.
+var prefixes = []string{topicPrefix, creatorPrefix, offsetPrefix, replicaPrefix,
+	queuePrefix, mirrorPrefix, checkpointPrefix, userPrefix, aclPrefix, quotaPrefix}
+
+// ours descarta las claves del store bajo los mismos prefijos (q::<queue>:<seq>,
+// o::<group>:…), que comparten la DB.
+func ours(k []byte) bool { return len(k) < 3 || k[2] != ':' }
+
 type creatorRec struct {
-	User string `json:"user"`
+	User    string    `json:"user"`
+	Created time.Time `json:"created,omitempty"`
 }
 
 // Catalog implementa MetaStore sobre BadgerDB.
@@ -35,34 +58,44 @@ var _ outbound.MetaStore = (*Catalog)(nil)
 // TOPICS
 // ------------------------------------------------------------------
 
-func (c *Catalog) CreateTopic(_ context.Context, name string, parts int, user string) error {
-	return c.db.Update(func(txn *badger.Txn) error {
+func (c *Catalog) CreateTopic(_ context.Context, name string, parts int, replicas [][]string, user string) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
 		k := []byte(topicPrefix + name)
 		if _, err := txn.Get(k); err == nil {
-			return fmt.Errorf("topic %q already exists", name)
+			return errs.AlreadyExists("topic %q", name)
 		} else if err != badger.ErrKeyNotFound {
 			return err
 		}
 		if err := txn.Set(k, u32(parts)); err != nil {
 			return err
 		}
-		meta, _ := json.Marshal(creatorRec{User: user})
+		if len(replicas) > 0 {
+			js, _ := json.Marshal(replicas)
+			if err := txn.Set([]byte(replicaPrefix+name), js); err != nil {
+				return err
+			}
+		}
+		meta, _ := json.Marshal(creatorRec{User: user, Created: time.Now().UTC()})
 		return txn.Set([]byte(creatorPrefix+"topic:"+name), meta)
-	})
+	}))
 }
 
-func (c *Catalog) GetTopic(_ context.Context, name string) (int, error) {
+func (c *Catalog) GetTopic(ctx context.Context, name string) (int, error) {
+	_, span := tracing.Start(ctx, "MetaStore.GetTopic", attribute.String("mom.topic", name))
+	defer span.End()
 	var p int
 	err := c.db.View(func(txn *badger.Txn) error {
 		it, err := txn.Get([]byte(topicPrefix + name))
-		if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return errs.NotFound("topic %q", name)
+		} else if err != nil {
 			return err
 		}
 		val, _ := it.ValueCopy(nil)
 		p = int(b2u32(val))
 		return nil
 	})
-	return p, err
+	return p, badgererr.Wrap(err)
 }
 
 func (c *Catalog) ListTopics(_ context.Context) ([]string, error) {
@@ -76,27 +109,66 @@ func (c *Catalog) ListTopics(_ context.Context) ([]string, error) {
 		}
 		return nil
 	})
-	return out, err
+	return out, badgererr.Wrap(err)
 }
 
 func (c *Catalog) DeleteTopic(_ context.Context, name, user string) error {
-	return c.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
 		ck := []byte(creatorPrefix + "topic:" + name)
 		item, err := txn.Get(ck)
-		if err != nil {
-			return fmt.Errorf("topic not found")
+		if err == badger.ErrKeyNotFound {
+			return errs.NotFound("topic %q", name)
+		} else if err != nil {
+			return err
 		}
 		var rec creatorRec
 		val, _ := item.ValueCopy(nil)
 		_ = json.Unmarshal(val, &rec)
 		if rec.User != user {
-			return fmt.Errorf("only creator can delete")
+			return errs.Forbidden("only creator can delete topic %q", name)
 		}
 		if err := txn.Delete([]byte(topicPrefix + name)); err != nil {
 			return err
 		}
+		if err := txn.Delete([]byte(replicaPrefix + name)); err != nil {
+			return err
+		}
 		return txn.Delete(ck)
+	}))
+}
+
+func (c *Catalog) DescribeTopic(ctx context.Context, name string) (model.Topic, error) {
+	parts, err := c.GetTopic(ctx, name)
+	if err != nil {
+		return model.Topic{}, err
+	}
+	rec, err := c.creator("topic", name)
+	if err != nil {
+		return model.Topic{}, err
+	}
+	t := model.Topic{Name: name, Partitions: parts, Creator: rec.User, CreatedAt: rec.Created}
+	err = c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(replicaPrefix + name))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error { return json.Unmarshal(v, &t.Replicas) })
 	})
+	return t, badgererr.Wrap(err)
+}
+
+func (c *Catalog) SetReplicas(_ context.Context, name string, replicas [][]string) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(topicPrefix + name)); err == badger.ErrKeyNotFound {
+			return errs.NotFound("topic %q", name)
+		} else if err != nil {
+			return err
+		}
+		js, _ := json.Marshal(replicas)
+		return txn.Set([]byte(replicaPrefix+name), js)
+	}))
 }
 
 // ------------------------------------------------------------------
@@ -104,19 +176,19 @@ func (c *Catalog) DeleteTopic(_ context.Context, name, user string) error {
 // ------------------------------------------------------------------
 
 func (c *Catalog) CreateQueue(_ context.Context, name, user string) error {
-	return c.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
 		k := []byte(queuePrefix + name)
 		if _, err := txn.Get(k); err == nil {
-			return fmt.Errorf("queue exists")
+			return errs.AlreadyExists("queue %q", name)
 		} else if err != badger.ErrKeyNotFound {
 			return err
 		}
 		if err := txn.Set(k, []byte{}); err != nil {
 			return err
 		}
-		meta, _ := json.Marshal(creatorRec{User: user})
+		meta, _ := json.Marshal(creatorRec{User: user, Created: time.Now().UTC()})
 		return txn.Set([]byte(creatorPrefix+"queue:"+name), meta)
-	})
+	}))
 }
 
 func (c *Catalog) ListQueues(_ context.Context) ([]string, error) {
@@ -125,63 +197,411 @@ func (c *Catalog) ListQueues(_ context.Context) ([]string, error) {
 		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(queuePrefix)})
 		defer it.Close()
 		for it.Rewind(); it.Valid(); it.Next() {
-			out = append(out, strings.TrimPrefix(string(it.Item().Key()), queuePrefix))
+			name := strings.TrimPrefix(string(it.Item().Key()), queuePrefix)
+			if strings.HasPrefix(name, ":") {
+				continue // q::<queue>:<seq> son los mensajes del store, en la misma DB
+			}
+			out = append(out, name)
 		}
 		return nil
 	})
-	return out, err
+	return out, badgererr.Wrap(err)
 }
 
 func (c *Catalog) DeleteQueue(_ context.Context, name, user string) error {
-	return c.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
 		ck := []byte(creatorPrefix + "queue:" + name)
 		item, err := txn.Get(ck)
-		if err != nil {
-			return fmt.Errorf("queue not found")
+		if err == badger.ErrKeyNotFound {
+			return errs.NotFound("queue %q", name)
+		} else if err != nil {
+			return err
 		}
 		var rec creatorRec
 		val, _ := item.ValueCopy(nil)
 		_ = json.Unmarshal(val, &rec)
 		if rec.User != user {
-			return fmt.Errorf("only creator can delete")
+			return errs.Forbidden("only creator can delete queue %q", name)
 		}
 		if err := txn.Delete([]byte(queuePrefix + name)); err != nil {
 			return err
 		}
 		return txn.Delete(ck)
+	}))
+}
+
+func (c *Catalog) DescribeQueue(_ context.Context, name string) (model.Queue, error) {
+	rec, err := c.creator("queue", name)
+	if err != nil {
+		return model.Queue{}, err
+	}
+	return model.Queue{Name: name, Creator: rec.User, CreatedAt: rec.Created}, nil
+}
+
+// creator lee el registro c:<kind>:<name>.
+func (c *Catalog) creator(kind, name string) (creatorRec, error) {
+	var rec creatorRec
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(creatorPrefix + kind + ":" + name))
+		if err == badger.ErrKeyNotFound {
+			return errs.NotFound("%s %q", kind, name)
+		} else if err != nil {
+			return err
+		}
+		val, _ := item.ValueCopy(nil)
+		return json.Unmarshal(val, &rec)
 	})
+	return rec, badgererr.Wrap(err)
 }
 
 // ------------------------------------------------------------------
 // OFFSETS (consumer groups)
 // ------------------------------------------------------------------
 
+// GetOffset devuelve 0 si el grupo aún no ha confirmado nada (igual que el
+// catálogo en memoria).
 func (c *Catalog) GetOffset(_ context.Context, grp, topic string, part int) (uint64, error) {
 	k := offsetPrefix + grp + ":" + topic + ":" + strconv.Itoa(part)
 	var off uint64
 	err := c.db.View(func(txn *badger.Txn) error {
 		it, err := txn.Get([]byte(k))
-		if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
 			return err
 		}
 		val, _ := it.ValueCopy(nil)
 		off = b2u64(val)
 		return nil
 	})
-	return off, err
+	return off, badgererr.Wrap(err)
 }
 
 func (c *Catalog) CommitOffset(_ context.Context, grp, topic string, part int, off uint64) error {
-	return c.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
 		k := offsetPrefix + grp + ":" + topic + ":" + strconv.Itoa(part)
 		return txn.Set([]byte(k), u64(off))
+	}))
+}
+
+// GroupOffsets recorre o:<group>:* ; el último segmento es la partición.
+func (c *Catalog) GroupOffsets(_ context.Context, grp string) ([]model.GroupOffset, error) {
+	prefix := []byte(offsetPrefix + grp + ":")
+	out := []model.GroupOffset{}
+	err := c.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			rest := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+			i := strings.LastIndexByte(rest, ':')
+			if i < 0 {
+				continue
+			}
+			part, err := strconv.Atoi(rest[i+1:])
+			if err != nil {
+				continue
+			}
+			val, _ := it.Item().ValueCopy(nil)
+			out = append(out, model.GroupOffset{Topic: rest[:i], Partition: part, Committed: b2u64(val)})
+		}
+		return nil
 	})
+	return out, badgererr.Wrap(err)
+}
+
+// ListGroups devuelve los grupos con algún offset confirmado.
+func (c *Catalog) ListGroups(_ context.Context) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	err := c.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(offsetPrefix)})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			rest := strings.TrimPrefix(string(it.Item().Key()), offsetPrefix)
+			grp, _, _ := strings.Cut(rest, ":")
+			if !seen[grp] {
+				seen[grp] = true
+				out = append(out, grp)
+			}
+		}
+		return nil
+	})
+	return out, badgererr.Wrap(err)
+}
+
+// ------------------------------------------------------------------
+// MIRRORS
+// ------------------------------------------------------------------
+
+func (c *Catalog) PutMirror(_ context.Context, m model.Mirror) error {
+	js, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(mirrorPrefix+m.Name), js)
+	}))
+}
+
+func (c *Catalog) GetMirror(_ context.Context, name string) (model.Mirror, error) {
+	var m model.Mirror
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(mirrorPrefix + name))
+		if err == badger.ErrKeyNotFound {
+			return errs.NotFound("mirror %q", name)
+		} else if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error { return json.Unmarshal(v, &m) })
+	})
+	return m, badgererr.Wrap(err)
+}
+
+func (c *Catalog) ListMirrors(_ context.Context) ([]model.Mirror, error) {
+	out := []model.Mirror{}
+	err := c.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(mirrorPrefix), PrefetchValues: true})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			var m model.Mirror
+			if err := it.Item().Value(func(v []byte) error { return json.Unmarshal(v, &m) }); err != nil {
+				return err
+			}
+			out = append(out, m)
+		}
+		return nil
+	})
+	return out, badgererr.Wrap(err)
+}
+
+// DeleteMirror borra también k:<mirror>:*.
+func (c *Catalog) DeleteMirror(_ context.Context, name string) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
+		k := []byte(mirrorPrefix + name)
+		if _, err := txn.Get(k); err == badger.ErrKeyNotFound {
+			return errs.NotFound("mirror %q", name)
+		} else if err != nil {
+			return err
+		}
+		if err := txn.Delete(k); err != nil {
+			return err
+		}
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(checkpointPrefix + name + ":")})
+		var keys [][]byte
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+		it.Close()
+		for _, k := range keys {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func (c *Catalog) CommitMirror(_ context.Context, name, topic string, part int, next uint64) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
+		k := checkpointPrefix + name + ":" + topic + ":" + strconv.Itoa(part)
+		return txn.Set([]byte(k), u64(next))
+	}))
+}
+
+// MirrorCheckpoints recorre k:<mirror>:* ; el último segmento es la
+// partición.
+func (c *Catalog) MirrorCheckpoints(_ context.Context, name string) ([]model.MirrorCheckpoint, error) {
+	prefix := []byte(checkpointPrefix + name + ":")
+	out := []model.MirrorCheckpoint{}
+	err := c.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			rest := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+			i := strings.LastIndexByte(rest, ':')
+			if i < 0 {
+				continue
+			}
+			part, err := strconv.Atoi(rest[i+1:])
+			if err != nil {
+				continue
+			}
+			val, _ := it.Item().ValueCopy(nil)
+			out = append(out, model.MirrorCheckpoint{Topic: rest[:i], Partition: part, Next: b2u64(val)})
+		}
+		return nil
+	})
+	return out, badgererr.Wrap(err)
+}
+
+// ------------------------------------------------------------------
+// USERS & ACLs
+// ------------------------------------------------------------------
+
+func (c *Catalog) PutUser(_ context.Context, u model.User) error {
+	return c.putJSON(userPrefix+u.Name, u)
+}
+
+func (c *Catalog) GetUser(_ context.Context, name string) (model.User, error) {
+	var u model.User
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(userPrefix + name))
+		if err == badger.ErrKeyNotFound {
+			return errs.NotFound("user %q", name)
+		} else if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error { return json.Unmarshal(v, &u) })
+	})
+	return u, badgererr.Wrap(err)
+}
+
+func (c *Catalog) ListUsers(_ context.Context) ([]model.User, error) {
+	return listJSON[model.User](c.db, userPrefix)
+}
+
+func (c *Catalog) DeleteUser(_ context.Context, name string) error {
+	return c.deleteKey(userPrefix+name, "user")
+}
+
+func (c *Catalog) PutACL(_ context.Context, a model.ACL) error {
+	return c.putJSON(aclPrefix+a.ID, a)
+}
+
+func (c *Catalog) ListACLs(_ context.Context) ([]model.ACL, error) {
+	return listJSON[model.ACL](c.db, aclPrefix)
+}
+
+func (c *Catalog) DeleteACL(_ context.Context, id string) error {
+	return c.deleteKey(aclPrefix+id, "acl")
+}
+
+// ------------------------------------------------------------------
+// QUOTAS
+// ------------------------------------------------------------------
+
+func (c *Catalog) PutQuota(_ context.Context, s model.QuotaSetting) error {
+	return c.putJSON(quotaPrefix+s.Scope+":"+s.Name, s)
+}
+
+func (c *Catalog) ListQuotas(_ context.Context) ([]model.QuotaSetting, error) {
+	return listJSON[model.QuotaSetting](c.db, quotaPrefix)
+}
+
+// ------------------------------------------------------------------
+// SNAPSHOT (log de metadatos replicado)
+// ------------------------------------------------------------------
+
+// Snapshot vuelca, en una sola transacción de lectura, todas las claves
+// del catálogo: tópicos, colas, offsets, mirrors, usuarios, ACLs y cuotas.
+func (c *Catalog) Snapshot() ([]byte, error) {
+	kv := map[string][]byte{}
+	err := c.db.View(func(txn *badger.Txn) error {
+		for _, p := range prefixes {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(p), PrefetchValues: true})
+			for it.Rewind(); it.Valid(); it.Next() {
+				if !ours(it.Item().Key()) {
+					continue
+				}
+				v, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					it.Close()
+					return err
+				}
+				kv[string(it.Item().Key())] = v
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, badgererr.Wrap(err)
+	}
+	return json.Marshal(kv)
+}
+
+// Restore sustituye el catálogo por el que volcó Snapshot. No es atómico:
+// si se interrumpe, se vuelve a restaurar desde el mismo snapshot.
+func (c *Catalog) Restore(raw []byte) error {
+	var kv map[string][]byte
+	if err := json.Unmarshal(raw, &kv); err != nil {
+		return errs.Invalid("malformed catalog snapshot: %v", err)
+	}
+	var stale [][]byte
+	err := c.db.View(func(txn *badger.Txn) error {
+		for _, p := range prefixes {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(p)})
+			for it.Rewind(); it.Valid(); it.Next() {
+				if _, keep := kv[string(it.Item().Key())]; !keep && ours(it.Item().Key()) {
+					stale = append(stale, it.Item().KeyCopy(nil))
+				}
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return badgererr.Wrap(err)
+	}
+	wb := c.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, k := range stale {
+		if err := wb.Delete(k); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	for k, v := range kv {
+		if err := wb.Set([]byte(k), v); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	return badgererr.Wrap(wb.Flush())
 }
 
 // ------------------------------------------------------------------
 // Helpers
 // ------------------------------------------------------------------
 
+func (c *Catalog) putJSON(k string, v any) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(k), js)
+	}))
+}
+
+// deleteKey borra k o devuelve NotFound nombrando el recurso con what.
+func (c *Catalog) deleteKey(k, what string) error {
+	return badgererr.Wrap(c.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(k)); err == badger.ErrKeyNotFound {
+			return errs.NotFound("%s %q", what, k[strings.IndexByte(k, ':')+1:])
+		} else if err != nil {
+			return err
+		}
+		return txn.Delete([]byte(k))
+	}))
+}
+
+// listJSON decodifica todos los valores bajo prefix, en orden de clave.
+func listJSON[T any](db *badger.DB, prefix string) ([]T, error) {
+	out := []T{}
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(prefix), PrefetchValues: true})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			var v T
+			if err := it.Item().Value(func(b []byte) error { return json.Unmarshal(b, &v) }); err != nil {
+				return err
+			}
+			out = append(out, v)
+		}
+		return nil
+	})
+	return out, badgererr.Wrap(err)
+}
+
 func u32(i int) []byte {
 	b := make([]byte, 4)
 	binary.BigEndian.PutUint32(b, uint32(i))
@@ -2,55 +2,59 @@ package meta
 
 import (
 	"context"
-	"errors"
 	"strconv"
+	"strings"
 	"sync"
-)
+	"time"
 
-var (
-	ErrExists     = errors.New("already exists")
-	ErrNotFound   = errors.New("not found")
-	ErrNotCreator = errors.New("only creator can delete")
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
 )
 
 type memoryCatalog struct {
 	mu sync.RWMutex
 
-	// topic -> {partitions, creator}
-	topics map[string]struct {
-		parts   int
-		creator string
-	}
-
-	// queue -> creator
-	queues map[string]string
+	topics map[string]model.Topic
+	queues map[string]model.Queue
 
 	// group -> topic:part -> offset
 	offsets map[string]map[string]uint64
+
+	mirrors map[string]model.Mirror
+	// mirror -> topic:part -> próximo offset de origen
+	checkpoints map[string]map[string]uint64
+
+	users map[string]model.User
+	acls  map[string]model.ACL
+
+	quotas map[string]model.QuotaSetting // scope:name -> cuota
 }
 
 func NewMemoryCatalog() *memoryCatalog {
 	return &memoryCatalog{
-		topics: make(map[string]struct {
-			parts   int
-			creator string
-		}),
-		queues:  make(map[string]string),
+		topics:  make(map[string]model.Topic),
+		queues:  make(map[string]model.Queue),
 		offsets: make(map[string]map[string]uint64),
+
+		mirrors:     make(map[string]model.Mirror),
+		checkpoints: make(map[string]map[string]uint64),
+
+		users: make(map[string]model.User),
+		acls:  make(map[string]model.ACL),
+
+		quotas: make(map[string]model.QuotaSetting),
 	}
 }
 
 // -------- TOPICS --------
-func (m *memoryCatalog) CreateTopic(_ context.Context, name string, p int, user string) error {
+func (m *memoryCatalog) CreateTopic(_ context.Context, name string, p int, replicas [][]string, user string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if _, ok := m.topics[name]; ok {
-		return ErrExists
+		return errs.AlreadyExists("topic %q", name)
 	}
-	m.topics[name] = struct {
-		parts   int
-		creator string
-	}{p, user}
+	m.topics[name] = model.Topic{Name: name, Partitions: p, Creator: user, CreatedAt: time.Now().UTC(),
+		Replicas: replicas}
 	return nil
 }
 
@@ -59,9 +63,9 @@ func (m *memoryCatalog) GetTopic(_ context.Context, name string) (int, error) {
 	defer m.mu.RUnlock()
 	t, ok := m.topics[name]
 	if !ok {
-		return 0, ErrNotFound
+		return 0, errs.NotFound("topic %q", name)
 	}
-	return t.parts, nil
+	return t.Partitions, nil
 }
 
 func (m *memoryCatalog) ListTopics(_ context.Context) ([]string, error) {
@@ -79,23 +83,45 @@ func (m *memoryCatalog) DeleteTopic(_ context.Context, name, user string) error
 	defer m.mu.Unlock()
 	t, ok := m.topics[name]
 	if !ok {
-		return ErrNotFound
+		return errs.NotFound("topic %q", name)
 	}
-	if t.creator != user {
-		return ErrNotCreator
+	if t.Creator != user {
+		return errs.Forbidden("only creator can delete topic %q", name)
 	}
 	delete(m.topics, name)
 	return nil
 }
 
+func (m *memoryCatalog) DescribeTopic(_ context.Context, name string) (model.Topic, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.topics[name]
+	if !ok {
+		return model.Topic{}, errs.NotFound("topic %q", name)
+	}
+	return t, nil
+}
+
+func (m *memoryCatalog) SetReplicas(_ context.Context, name string, replicas [][]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.topics[name]
+	if !ok {
+		return errs.NotFound("topic %q", name)
+	}
+	t.Replicas = replicas
+	m.topics[name] = t
+	return nil
+}
+
 // -------- QUEUES --------
 func (m *memoryCatalog) CreateQueue(_ context.Context, name, user string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if _, ok := m.queues[name]; ok {
-		return ErrExists
+		return errs.AlreadyExists("queue %q", name)
 	}
-	m.queues[name] = user
+	m.queues[name] = model.Queue{Name: name, Creator: user, CreatedAt: time.Now().UTC()}
 	return nil
 }
 
@@ -112,17 +138,27 @@ func (m *memoryCatalog) ListQueues(_ context.Context) ([]string, error) {
 func (m *memoryCatalog) DeleteQueue(_ context.Context, name, user string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	creator, ok := m.queues[name]
+	q, ok := m.queues[name]
 	if !ok {
-		return ErrNotFound
+		return errs.NotFound("queue %q", name)
 	}
-	if creator != user {
-		return ErrNotCreator
+	if q.Creator != user {
+		return errs.Forbidden("only creator can delete queue %q", name)
 	}
 	delete(m.queues, name)
 	return nil
 }
 
+func (m *memoryCatalog) DescribeQueue(_ context.Context, name string) (model.Queue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	q, ok := m.queues[name]
+	if !ok {
+		return model.Queue{}, errs.NotFound("queue %q", name)
+	}
+	return q, nil
+}
+
 // -------- OFFSETS (consumer groups) --------
 func key(topic string, part int) string { return topic + ":" + strconv.Itoa(part) }
 
@@ -144,3 +180,169 @@ func (m *memoryCatalog) CommitOffset(_ context.Context, grp, topic string, part
 	m.offsets[grp][key(topic, part)] = off
 	return nil
 }
+
+func (m *memoryCatalog) GroupOffsets(_ context.Context, grp string) ([]model.GroupOffset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]model.GroupOffset, 0, len(m.offsets[grp]))
+	for k, off := range m.offsets[grp] {
+		i := strings.LastIndexByte(k, ':')
+		part, _ := strconv.Atoi(k[i+1:])
+		out = append(out, model.GroupOffset{Topic: k[:i], Partition: part, Committed: off})
+	}
+	return out, nil
+}
+
+func (m *memoryCatalog) ListGroups(_ context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]string, 0, len(m.offsets))
+	for g := range m.offsets {
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// -------- MIRRORS --------
+func (m *memoryCatalog) PutMirror(_ context.Context, mi model.Mirror) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mirrors[mi.Name] = mi
+	return nil
+}
+
+func (m *memoryCatalog) GetMirror(_ context.Context, name string) (model.Mirror, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mi, ok := m.mirrors[name]
+	if !ok {
+		return model.Mirror{}, errs.NotFound("mirror %q", name)
+	}
+	return mi, nil
+}
+
+func (m *memoryCatalog) ListMirrors(_ context.Context) ([]model.Mirror, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]model.Mirror, 0, len(m.mirrors))
+	for _, mi := range m.mirrors {
+		out = append(out, mi)
+	}
+	return out, nil
+}
+
+func (m *memoryCatalog) DeleteMirror(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.mirrors[name]; !ok {
+		return errs.NotFound("mirror %q", name)
+	}
+	delete(m.mirrors, name)
+	delete(m.checkpoints, name)
+	return nil
+}
+
+func (m *memoryCatalog) CommitMirror(_ context.Context, name, topic string, part int, next uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checkpoints[name] == nil {
+		m.checkpoints[name] = make(map[string]uint64)
+	}
+	m.checkpoints[name][key(topic, part)] = next
+	return nil
+}
+
+func (m *memoryCatalog) MirrorCheckpoints(_ context.Context, name string) ([]model.MirrorCheckpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]model.MirrorCheckpoint, 0, len(m.checkpoints[name]))
+	for k, next := range m.checkpoints[name] {
+		i := strings.LastIndexByte(k, ':')
+		part, _ := strconv.Atoi(k[i+1:])
+		out = append(out, model.MirrorCheckpoint{Topic: k[:i], Partition: part, Next: next})
+	}
+	return out, nil
+}
+
+// -------- USERS & ACLs --------
+func (m *memoryCatalog) PutUser(_ context.Context, u model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[u.Name] = u
+	return nil
+}
+
+func (m *memoryCatalog) GetUser(_ context.Context, name string) (model.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[name]
+	if !ok {
+		return model.User{}, errs.NotFound("user %q", name)
+	}
+	return u, nil
+}
+
+func (m *memoryCatalog) ListUsers(_ context.Context) ([]model.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]model.User, 0, len(m.users))
+	for _, u := range m.users {
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func (m *memoryCatalog) DeleteUser(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[name]; !ok {
+		return errs.NotFound("user %q", name)
+	}
+	delete(m.users, name)
+	return nil
+}
+
+func (m *memoryCatalog) PutACL(_ context.Context, a model.ACL) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acls[a.ID] = a
+	return nil
+}
+
+func (m *memoryCatalog) ListACLs(_ context.Context) ([]model.ACL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]model.ACL, 0, len(m.acls))
+	for _, a := range m.acls {
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (m *memoryCatalog) DeleteACL(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.acls[id]; !ok {
+		return errs.NotFound("acl %q", id)
+	}
+	delete(m.acls, id)
+	return nil
+}
+
+// -------- QUOTAS --------
+func (m *memoryCatalog) PutQuota(_ context.Context, s model.QuotaSetting) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas[s.Scope+":"+s.Name] = s
+	return nil
+}
+
+func (m *memoryCatalog) ListQuotas(_ context.Context) ([]model.QuotaSetting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]model.QuotaSetting, 0, len(m.quotas))
+	for _, s := range m.quotas {
+		out = append(out, s)
+	}
+	return out, nil
+}
@@ -0,0 +1,406 @@
+package quota
+
+import (
+	"context"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+// ---------- token bucket -----------------------------------------
+
+// bucket se rellena a `rate` tokens/seg con ráfaga máxima de 1 s.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(rate float64, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = rate
+	} else {
+		b.tokens = math.Min(rate, b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+}
+
+// wait devuelve cuánto hay que esperar para poder tomar n tokens.
+// Una petición mayor que la ráfaga pasa con el bucket lleno y lo deja en negativo.
+func (b *bucket) wait(rate, n float64) time.Duration {
+	need := math.Min(n, rate)
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration((need - b.tokens) / rate * float64(time.Second))
+}
+
+// ---------- memoryQuotas -----------------------------------------
+
+type entry struct {
+	quota *model.Quota // nil → se usa la cuota por defecto del ámbito
+	msgs  bucket
+	bytes bucket
+	usage model.QuotaUsage
+	// delta es lo que Take, Reserve y Release han sumado a usage desde que
+	// empezó la medida en curso: Measure lo añade a lo medido.
+	delta model.QuotaUsage
+}
+
+// add suma d al uso y lo anota en delta.
+func (e *entry) add(d model.QuotaUsage) {
+	e.usage.Topics += d.Topics
+	e.usage.Queues += d.Queues
+	e.usage.StoredBytes += d.StoredBytes
+	e.delta.Topics += d.Topics
+	e.delta.Queues += d.Queues
+	e.delta.StoredBytes += d.StoredBytes
+}
+
+// charge es lo que Take cobra a una entrada: rate los buckets de mensajes
+// y bytes por segundo, store el almacenamiento.
+type charge struct {
+	scope, name string
+	rate, store bool
+}
+
+// check comprueba, sin consumir nada, que e admite msgs mensajes y bytes bytes.
+func (e *entry) check(c charge, q model.Quota, msgs, bytes int, now time.Time) error {
+	if c.store && q.MaxStorage > 0 && e.usage.StoredBytes+int64(bytes) > q.MaxStorage {
+		return &model.QuotaError{Scope: c.scope, Name: c.name, Reason: "storage limit reached"}
+	}
+	if !c.rate {
+		return nil
+	}
+	var wait time.Duration
+	reason := ""
+	if q.MsgsPerSec > 0 {
+		e.msgs.refill(q.MsgsPerSec, now)
+		if w := e.msgs.wait(q.MsgsPerSec, float64(msgs)); w > wait {
+			wait, reason = w, "messages per second"
+		}
+	}
+	if q.BytesPerSec > 0 {
+		e.bytes.refill(q.BytesPerSec, now)
+		if w := e.bytes.wait(q.BytesPerSec, float64(bytes)); w > wait {
+			wait, reason = w, "bytes per second"
+		}
+	}
+	if wait > 0 {
+		return &model.QuotaError{Scope: c.scope, Name: c.name, Reason: reason, RetryAfter: wait}
+	}
+	return nil
+}
+
+// consume cobra lo que check dio por bueno.
+func (e *entry) consume(c charge, q model.Quota, msgs, bytes int) {
+	if c.rate && q.MsgsPerSec > 0 {
+		e.msgs.tokens -= float64(msgs)
+	}
+	if c.rate && q.BytesPerSec > 0 {
+		e.bytes.tokens -= float64(bytes)
+	}
+	if c.store {
+		e.add(model.QuotaUsage{StoredBytes: int64(bytes)})
+	}
+}
+
+type memoryQuotas struct {
+	mu       sync.Mutex
+	defaults map[string]model.Quota // scope -> cuota por defecto
+	entries  map[string]*entry      // scope:name -> estado
+	owners   map[string]string      // scope:name de tópico o cola -> usuario que lo creó
+	meta     outbound.MetaStore     // guarda las cuotas; de aquí y de msg se mide el uso
+	msg      outbound.MessageStore
+}
+
+// NewInMemory crea el almacén de cuotas; userDefault se aplica a todo
+// usuario sin cuota explícita. Tópicos y colas no tienen límite por defecto.
+// Las cuotas explícitas se guardan en meta y aquí se tiene una copia (ver
+// Apply); el uso no se guarda aparte: se mide en meta y msg (ver Measure).
+func NewInMemory(userDefault model.Quota, meta outbound.MetaStore, msg outbound.MessageStore) *memoryQuotas {
+	return &memoryQuotas{
+		defaults: map[string]model.Quota{model.ScopeUser: userDefault},
+		entries:  make(map[string]*entry),
+		owners:   make(map[string]string),
+		meta:     meta,
+		msg:      msg,
+	}
+}
+
+var _ outbound.QuotaStore = (*memoryQuotas)(nil)
+
+// get devuelve (creando si hace falta) la entrada; requiere m.mu tomado.
+func (m *memoryQuotas) get(scope, name string) (*entry, model.Quota) {
+	k := scope + ":" + name
+	e, ok := m.entries[k]
+	if !ok {
+		e = &entry{}
+		m.entries[k] = e
+	}
+	if e.quota != nil {
+		return e, *e.quota
+	}
+	return e, m.defaults[scope]
+}
+
+func (m *memoryQuotas) Take(ctx context.Context, msgs, bytes int, refs ...model.QuotaRef) error {
+	charges := make([]charge, 0, 2*len(refs))
+	for _, r := range refs {
+		charges = append(charges, charge{scope: r.Scope, name: r.Name, rate: true, store: r.Scope != model.ScopeUser})
+	}
+	// lo guardado en un tópico o cola ocupa también el almacenamiento de su dueño
+	for _, r := range refs {
+		if r.Scope == model.ScopeUser {
+			continue
+		}
+		o := m.owner(ctx, r.Scope, r.Name)
+		if o == "" {
+			continue
+		}
+		i := slices.IndexFunc(charges, func(c charge) bool { return c.scope == model.ScopeUser && c.name == o })
+		if i < 0 {
+			charges = append(charges, charge{scope: model.ScopeUser, name: o})
+			i = len(charges) - 1
+		}
+		charges[i].store = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	entries := make([]*entry, len(charges))
+	quotas := make([]model.Quota, len(charges))
+	// se comprueba todo antes de consumir nada
+	for i, c := range charges {
+		entries[i], quotas[i] = m.get(c.scope, c.name)
+		if err := entries[i].check(c, quotas[i], msgs, bytes, now); err != nil {
+			return err
+		}
+	}
+	for i, c := range charges {
+		entries[i].consume(c, quotas[i], msgs, bytes)
+	}
+	return nil
+}
+
+// owner devuelve el usuario que creó el tópico o la cola ("" si no existe).
+// Lo pregunta a meta la primera vez; Measure renueva la caché.
+func (m *memoryQuotas) owner(ctx context.Context, scope, name string) string {
+	k := scope + ":" + name
+	m.mu.Lock()
+	o, ok := m.owners[k]
+	m.mu.Unlock()
+	if ok {
+		return o
+	}
+	switch scope {
+	case model.ScopeTopic:
+		t, err := m.meta.DescribeTopic(ctx, name)
+		if err != nil {
+			return ""
+		}
+		o = t.Creator
+	case model.ScopeQueue:
+		q, err := m.meta.DescribeQueue(ctx, name)
+		if err != nil {
+			return ""
+		}
+		o = q.Creator
+	}
+	m.mu.Lock()
+	m.owners[k] = o
+	m.mu.Unlock()
+	return o
+}
+
+func (m *memoryQuotas) Reserve(_ context.Context, user, kind string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, q := m.get(model.ScopeUser, user)
+	switch kind {
+	case model.ScopeTopic:
+		if q.MaxTopics > 0 && e.usage.Topics >= q.MaxTopics {
+			return &model.QuotaError{Scope: model.ScopeUser, Name: user, Reason: "max topics owned"}
+		}
+		e.add(model.QuotaUsage{Topics: 1})
+	case model.ScopeQueue:
+		if q.MaxQueues > 0 && e.usage.Queues >= q.MaxQueues {
+			return &model.QuotaError{Scope: model.ScopeUser, Name: user, Reason: "max queues owned"}
+		}
+		e.add(model.QuotaUsage{Queues: 1})
+	}
+	return nil
+}
+
+func (m *memoryQuotas) Release(_ context.Context, user, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, _ := m.get(model.ScopeUser, user)
+	switch kind {
+	case model.ScopeTopic:
+		if e.usage.Topics > 0 {
+			e.add(model.QuotaUsage{Topics: -1})
+		}
+	case model.ScopeQueue:
+		if e.usage.Queues > 0 {
+			e.add(model.QuotaUsage{Queues: -1})
+		}
+	}
+}
+
+func (m *memoryQuotas) GetQuota(_ context.Context, scope, name string) (model.Quota, model.QuotaUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, q := m.get(scope, name)
+	return q, e.usage, nil
+}
+
+func (m *memoryQuotas) SetQuota(ctx context.Context, scope, name string, q model.Quota) error {
+	s := model.QuotaSetting{Scope: scope, Name: name, Quota: q}
+	if err := m.meta.PutQuota(ctx, s); err != nil {
+		return err
+	}
+	m.Apply(s)
+	return nil
+}
+
+// Apply fija en memoria una cuota guardada en meta. En clúster la llama
+// el log de metadatos en cada nodo al aplicar put_quota.
+func (m *memoryQuotas) Apply(s model.QuotaSetting) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, _ := m.get(s.Scope, s.Name)
+	q := s.Quota
+	e.quota = &q
+}
+
+// LoadQuotas copia a memoria las cuotas que guarda meta.
+func (m *memoryQuotas) LoadQuotas(ctx context.Context) error {
+	quotas, err := m.meta.ListQuotas(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range quotas {
+		m.Apply(s)
+	}
+	return nil
+}
+
+// ---------- uso guardado -----------------------------------------
+
+// StartUsageLoop carga las cuotas guardadas, mide el uso al arrancar y
+// luego cada every, hasta que se cancele ctx; wg cuenta el bucle. Entre
+// medidas Take y Reserve suman lo nuevo; borrados, recortes de retención y
+// ACKs se notan en la siguiente.
+func (m *memoryQuotas) StartUsageLoop(ctx context.Context, wg *sync.WaitGroup, every time.Duration) {
+	if err := m.LoadQuotas(ctx); err != nil {
+		log.Printf("[quota] load quotas: %v", err)
+	}
+	if err := m.Measure(ctx); err != nil {
+		log.Printf("[quota] measure usage: %v", err)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(every)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				if err := m.Measure(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("[quota] measure usage: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Measure recalcula el uso con lo que guarda este nodo: los bytes de cada
+// tópico y cola y, por usuario, cuántos tópicos y colas creó y lo que
+// ocupan. Así el uso sobrevive a reinicios y baja al borrar o confirmar.
+// Lo que Take, Reserve y Release suman durante la medida se añade a lo
+// medido; si ya estaba en disco se cuenta dos veces hasta la siguiente.
+func (m *memoryQuotas) Measure(ctx context.Context) error {
+	m.mu.Lock()
+	for _, e := range m.entries {
+		e.delta = model.QuotaUsage{}
+	}
+	m.mu.Unlock()
+
+	usage := map[string]model.QuotaUsage{}
+	owners := map[string]string{}
+	add := func(scope, name string, f func(u *model.QuotaUsage)) {
+		u := usage[scope+":"+name]
+		f(&u)
+		usage[scope+":"+name] = u
+	}
+
+	topics, err := m.meta.ListTopics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range topics {
+		if strings.HasPrefix(name, model.InternalTopicPrefix) {
+			continue
+		}
+		t, err := m.meta.DescribeTopic(ctx, name)
+		if err != nil {
+			continue // borrado mientras se medía
+		}
+		var bytes int64
+		for p := range t.Partitions {
+			st, err := m.msg.PartitionStats(ctx, name, p)
+			if err != nil {
+				return err
+			}
+			bytes += st.Bytes
+		}
+		owners[model.ScopeTopic+":"+name] = t.Creator
+		add(model.ScopeTopic, name, func(u *model.QuotaUsage) { u.StoredBytes = bytes })
+		add(model.ScopeUser, t.Creator, func(u *model.QuotaUsage) { u.Topics++; u.StoredBytes += bytes })
+	}
+
+	queues, err := m.meta.ListQueues(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range queues {
+		q, err := m.meta.DescribeQueue(ctx, name)
+		if err != nil {
+			continue
+		}
+		st, err := m.msg.QueueStats(ctx, name)
+		if err != nil {
+			return err
+		}
+		owners[model.ScopeQueue+":"+name] = q.Creator
+		add(model.ScopeQueue, name, func(u *model.QuotaUsage) { u.StoredBytes = st.Bytes })
+		add(model.ScopeUser, q.Creator, func(u *model.QuotaUsage) { u.Queues++; u.StoredBytes += st.Bytes })
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, e := range m.entries {
+		u := usage[k] // lo que ya no existe queda a cero
+		e.usage = model.QuotaUsage{
+			Topics:      max(u.Topics+e.delta.Topics, 0),
+			Queues:      max(u.Queues+e.delta.Queues, 0),
+			StoredBytes: max(u.StoredBytes+e.delta.StoredBytes, 0),
+		}
+		e.delta = model.QuotaUsage{}
+		delete(usage, k)
+	}
+	for k, u := range usage {
+		m.entries[k] = &entry{usage: u}
+	}
+	m.owners = owners
+	return nil
+}
@@ -0,0 +1,168 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/meta"
+	badgerstore "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/storage/badger"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+var (
+	alice  = model.QuotaRef{Scope: model.ScopeUser, Name: "alice"}
+	bob    = model.QuotaRef{Scope: model.ScopeUser, Name: "bob"}
+	orders = model.QuotaRef{Scope: model.ScopeTopic, Name: "orders"}
+)
+
+// newQuotas crea el almacén con el tópico orders, de alice.
+func newQuotas(t *testing.T, userDefault model.Quota, msg outbound.MessageStore) *memoryQuotas {
+	t.Helper()
+	cat := meta.NewMemoryCatalog()
+	if err := cat.CreateTopic(context.Background(), "orders", 1, nil, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	return NewInMemory(userDefault, cat, msg)
+}
+
+func openStore(t *testing.T) *badgerstore.Store {
+	t.Helper()
+	s, err := badgerstore.Open(t.TempDir(), badgerstore.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func quotaErr(t *testing.T, err error) *model.QuotaError {
+	t.Helper()
+	var qe *model.QuotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("err = %v, want *model.QuotaError", err)
+	}
+	return qe
+}
+
+func usage(t *testing.T, m *memoryQuotas, r model.QuotaRef) model.QuotaUsage {
+	t.Helper()
+	_, u, err := m.GetQuota(context.Background(), r.Scope, r.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestTakeChargesNothingWhenAScopeRejects(t *testing.T) {
+	ctx := context.Background()
+	m := newQuotas(t, model.Quota{MsgsPerSec: 2}, openStore(t))
+	if err := m.SetQuota(ctx, model.ScopeTopic, "orders", model.Quota{MsgsPerSec: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Take(ctx, 1, 10, bob, orders); err != nil {
+		t.Fatal(err)
+	}
+	// el tópico ya no admite más: bob no debe pagar el intento
+	if qe := quotaErr(t, m.Take(ctx, 1, 10, bob, orders)); qe.Scope != model.ScopeTopic {
+		t.Fatalf("rejected by %s %q, want the topic", qe.Scope, qe.Name)
+	}
+	if got := usage(t, m, orders).StoredBytes; got != 10 {
+		t.Fatalf("topic stored %d bytes, want 10", got)
+	}
+
+	// a bob le queda justo un mensaje del segundo
+	if err := m.Take(ctx, 1, 0, bob); err != nil {
+		t.Fatalf("bob was charged for the rejected message: %v", err)
+	}
+	if qe := quotaErr(t, m.Take(ctx, 1, 0, bob)); qe.Scope != model.ScopeUser {
+		t.Fatalf("rejected by %s %q, want the user", qe.Scope, qe.Name)
+	}
+}
+
+func TestTakeChargesStorageToTheOwner(t *testing.T) {
+	ctx := context.Background()
+	m := newQuotas(t, model.Quota{}, openStore(t))
+	if err := m.SetQuota(ctx, model.ScopeUser, "alice", model.Quota{MaxStorage: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Take(ctx, 1, 100, bob, orders); err != nil {
+		t.Fatal(err)
+	}
+	if got := usage(t, m, alice).StoredBytes; got != 100 {
+		t.Fatalf("alice stores %d bytes, want 100", got)
+	}
+	if got := usage(t, m, bob).StoredBytes; got != 0 {
+		t.Fatalf("bob stores %d bytes, want 0: the topic is alice's", got)
+	}
+
+	qe := quotaErr(t, m.Take(ctx, 1, 100, bob, orders))
+	if qe.Scope != model.ScopeUser || qe.Name != "alice" {
+		t.Fatalf("rejected by %s %q, want user alice", qe.Scope, qe.Name)
+	}
+	if got := usage(t, m, orders).StoredBytes; got != 100 {
+		t.Fatalf("topic stored %d bytes after the rejection, want 100", got)
+	}
+}
+
+// during llama a f en cada PartitionStats, en mitad de Measure.
+type during struct {
+	outbound.MessageStore
+	f func()
+}
+
+func (d during) PartitionStats(ctx context.Context, topic string, part int) (model.PartitionStats, error) {
+	d.f()
+	return d.MessageStore.PartitionStats(ctx, topic, part)
+}
+
+func TestMeasureKeepsWhatTakeAddsMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	d := &during{MessageStore: openStore(t), f: func() {}}
+	m := newQuotas(t, model.Quota{}, d)
+
+	d.f = func() {
+		if err := m.Take(ctx, 1, 10, bob, orders); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := m.Measure(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := usage(t, m, orders).StoredBytes; got != 10 {
+		t.Fatalf("topic stores %d bytes, want the 10 taken during the scan", got)
+	}
+	if u := usage(t, m, alice); u.StoredBytes != 10 || u.Topics != 1 {
+		t.Fatalf("alice usage = %+v, want 1 topic and 10 bytes", u)
+	}
+
+	// la siguiente medida ya no arrastra lo de la anterior
+	d.f = func() {}
+	if err := m.Measure(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := usage(t, m, orders).StoredBytes; got != 0 {
+		t.Fatalf("topic stores %d bytes, want the 0 on disk", got)
+	}
+}
+
+func TestSetQuotaIsStoredInMeta(t *testing.T) {
+	ctx := context.Background()
+	m := newQuotas(t, model.Quota{}, openStore(t))
+	want := model.Quota{MsgsPerSec: 5, MaxStorage: 1 << 20}
+	if err := m.SetQuota(ctx, model.ScopeTopic, "orders", want); err != nil {
+		t.Fatal(err)
+	}
+
+	// un nodo que arranca sobre el mismo catálogo la recupera
+	again := NewInMemory(model.Quota{}, m.meta, m.msg)
+	if err := again.LoadQuotas(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := again.GetQuota(ctx, model.ScopeTopic, "orders"); got != want {
+		t.Fatalf("reloaded quota = %+v, want %+v", got, want)
+	}
+}
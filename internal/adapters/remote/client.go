@@ -0,0 +1,161 @@
+// Package remote es el cliente de la API REST del broker; lo usan el
+// mirror, para leer del clúster de origen, y momctl.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Client habla con un nodo del clúster remoto; si éste reenvía con 307 al
+// dueño de la partición, net/http sigue la redirección, pero el token sólo
+// viaja al host de base y a los de trusted.
+type Client struct {
+	base    string
+	token   string
+	trusted map[string]bool // hosts que pueden recibir el token
+	http    *http.Client
+}
+
+// New crea un cliente de base; trusted son URLs de otros nodos a los que
+// se puede seguir una redirección con el token.
+func New(base, token string, timeout time.Duration, trusted ...string) *Client {
+	c := &Client{base: strings.TrimRight(base, "/"), token: token, trusted: map[string]bool{}}
+	for _, s := range append(trusted, base) {
+		if u, err := url.Parse(s); err == nil && u.Host != "" {
+			c.trusted[strings.ToLower(u.Host)] = true
+		}
+	}
+	c.http = &http.Client{Timeout: timeout, CheckRedirect: c.redirect}
+	return c
+}
+
+// Dialer crea clientes con el plazo timeout por petición que confían en
+// los hosts de trusted.
+func Dialer(timeout time.Duration, trusted []string) outbound.RemoteDialer {
+	return func(base, token string) outbound.RemoteCluster { return New(base, token, timeout, trusted...) }
+}
+
+// redirect sigue hasta 10 redirecciones, como net/http, y quita el token
+// si la redirección lleva a un host que no es de confianza.
+func (c *Client) redirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !c.trusted[strings.ToLower(req.URL.Host)] {
+		req.Header.Del("X-Token")
+	}
+	return nil
+}
+
+var _ outbound.RemoteCluster = (*Client)(nil)
+
+func (c *Client) DescribeTopic(ctx context.Context, topic string) (model.TopicDescription, error) {
+	var d model.TopicDescription
+	err := c.Do(ctx, http.MethodGet, "/topics/"+url.PathEscape(topic), nil, nil, &d)
+	return d, err
+}
+
+func (c *Client) Pull(ctx context.Context, topic, group string, part, max int) ([]model.Message, error) {
+	q := url.Values{}
+	q.Set("group", group)
+	q.Set("partition", strconv.Itoa(part))
+	q.Set("max", strconv.Itoa(max))
+	var msgs []model.Message
+	err := c.Do(ctx, http.MethodGet, "/topics/"+url.PathEscape(topic)+"/messages", q, nil, &msgs)
+	return msgs, err
+}
+
+func (c *Client) Commit(ctx context.Context, topic, group string, part int, offset uint64) error {
+	body := struct {
+		Group     string `json:"group"`
+		Partition int    `json:"partition"`
+		Offset    uint64 `json:"offset"`
+	}{group, part, offset}
+	return c.Do(ctx, http.MethodPost, "/topics/"+url.PathEscape(topic)+"/offsets", nil, body, nil)
+}
+
+// Do envía una petición a la API REST (q y body son opcionales) y
+// decodifica la respuesta JSON en out (si no es nil). Los Problem del
+// remoto se traducen a errores de dominio por su status.
+func (c *Client) Do(ctx context.Context, method, path string, q url.Values, body, out any) error {
+	u := c.base + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(js)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return errs.Invalid("remote %s: %v", c.base, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("X-Token", c.token)
+	}
+	tracing.InjectHTTP(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errs.Unavailable("remote %s: %v", c.base, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return problem(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("remote %s: decode %s: %w", c.base, path, err)
+	}
+	return nil
+}
+
+// problem traduce una respuesta de error del remoto.
+func problem(resp *http.Response) error {
+	var p struct {
+		Detail string `json:"detail"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(raw, &p) != nil || p.Detail == "" {
+		p.Detail = strings.TrimSpace(string(raw))
+	}
+	detail := fmt.Sprintf("remote %s %s: %s", resp.Request.Method, resp.Request.URL.Path, p.Detail)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errs.New(errs.ErrNotFound, "%s", detail)
+	case http.StatusConflict:
+		return errs.New(errs.ErrConflict, "%s", detail)
+	case http.StatusBadRequest:
+		return errs.New(errs.ErrInvalid, "%s", detail)
+	case http.StatusUnauthorized:
+		return errs.New(errs.ErrUnauthenticated, "%s", detail)
+	case http.StatusForbidden:
+		return errs.New(errs.ErrForbidden, "%s", detail)
+	}
+	return errs.New(errs.ErrUnavailable, "%s (%s)", detail, resp.Status)
+}
@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+// AccessHandlers gestionan el login, las cuentas (/admin/users) y las
+// ACLs (/admin/acls).
+type AccessHandlers struct{ access inbound.Access }
+
+func (h *AccessHandlers) Login(c *gin.Context) {
+	var u struct {
+		User string `json:"user"`
+		Pass string `json:"pass"`
+	}
+	if err := c.ShouldBindJSON(&u); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	c.Set("user", u.User)
+	c.Set("resource", "user:"+u.User)
+	token, err := h.access.Login(c, u.User, u.Pass)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token": token,
+		"user":  u.User,
+	})
+}
+
+// ---- USERS ------------------------------------------------------
+
+func (h *AccessHandlers) ListUsers(c *gin.Context) {
+	list, err := h.access.ListUsers(c)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *AccessHandlers) CreateUser(c *gin.Context) {
+	var req struct {
+		Name  string `json:"name"`
+		Pass  string `json:"pass"`
+		Admin bool   `json:"admin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	c.Set("resource", "user:"+req.Name)
+	if err := h.access.CreateUser(c, req.Name, req.Pass, req.Admin, c.GetString("user")); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+// UpdateUser cambia "pass" y/o "admin"; lo que no viene se conserva.
+func (h *AccessHandlers) UpdateUser(c *gin.Context) {
+	var req struct {
+		Pass  *string `json:"pass"`
+		Admin *bool   `json:"admin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	name := c.Param("user")
+	c.Set("resource", "user:"+name)
+	if err := h.access.UpdateUser(c, name, req.Pass, req.Admin); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AccessHandlers) DeleteUser(c *gin.Context) {
+	name := c.Param("user")
+	c.Set("resource", "user:"+name)
+	if err := h.access.DeleteUser(c, name); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ---- ACLs -------------------------------------------------------
+
+func (h *AccessHandlers) ListACLs(c *gin.Context) {
+	list, err := h.access.ListACLs(c)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddACL recibe principal, resource y ops; responde la ACL con su id.
+func (h *AccessHandlers) AddACL(c *gin.Context) {
+	var req model.ACL
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	c.Set("resource", req.Resource)
+	acl, err := h.access.AddACL(c, req, c.GetString("user"))
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, acl)
+}
+
+func (h *AccessHandlers) DeleteACL(c *gin.Context) {
+	id := c.Param("id")
+	c.Set("resource", "acl:"+id)
+	if err := h.access.DeleteACL(c, id); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+// ClusterHandlers gestionan la membresía (/admin/cluster/nodes).
+type ClusterHandlers struct{ cluster inbound.Cluster }
+
+func (h *ClusterHandlers) ListNodes(c *gin.Context) {
+	nodes, err := h.cluster.Nodes(c)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, nodes)
+}
+
+func (h *ClusterHandlers) AddNode(c *gin.Context) {
+	var n model.Node
+	if err := c.ShouldBindJSON(&n); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	c.Set("resource", "node:"+n.ID)
+	if err := h.cluster.AddNode(c, n); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ClusterHandlers) RemoveNode(c *gin.Context) {
+	id := c.Param("id")
+	c.Set("resource", "node:"+id)
+	if err := h.cluster.RemoveNode(c, id); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
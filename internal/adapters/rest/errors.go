@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Problem es el cuerpo de error común (RFC 7807, application/problem+json).
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// problemOf traduce un error de dominio a (status, type).
+func problemOf(err error) (int, string) {
+	var qe *model.QuotaError
+	if errors.As(err, &qe) {
+		return http.StatusTooManyRequests, "quota-exceeded"
+	}
+	var re *model.ReplicationError
+	if errors.As(err, &re) {
+		return http.StatusGatewayTimeout, "not-enough-replicas"
+	}
+	var rd *model.RedirectError
+	if errors.As(err, &rd) {
+		return http.StatusTemporaryRedirect, "redirect"
+	}
+	switch errs.Kind(err) {
+	case errs.ErrNotFound:
+		return http.StatusNotFound, "not-found"
+	case errs.ErrAlreadyExists:
+		return http.StatusConflict, "already-exists"
+	case errs.ErrConflict:
+		return http.StatusConflict, "conflict"
+	case errs.ErrForbidden:
+		return http.StatusForbidden, "forbidden"
+	case errs.ErrOutOfRange:
+		return http.StatusBadRequest, "out-of-range"
+	case errs.ErrInvalid:
+		return http.StatusBadRequest, "invalid-argument"
+	case errs.ErrUnauthenticated:
+		return http.StatusUnauthorized, "unauthenticated"
+	case errs.ErrUnavailable:
+		return http.StatusServiceUnavailable, "unavailable"
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+// abortError responde con el Problem correspondiente a err.
+// Las cuotas añaden Retry-After cuando esperar sirve de algo y las
+// redirecciones, Location hacia el mismo recurso en el nodo dueño (307
+// conserva método y cuerpo).
+func abortError(c *gin.Context, err error) {
+	status, typ := problemOf(err)
+	var qe *model.QuotaError
+	if errors.As(err, &qe) && qe.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(qe.RetryAfter.Seconds()))))
+	}
+	var rd *model.RedirectError
+	if errors.As(err, &rd) {
+		c.Header("Location", "http://"+rd.Addr+c.Request.URL.RequestURI())
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(status, Problem{
+		Type:     typ,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: c.Request.URL.Path,
+	})
+}
+
+// abortInvalid es el atajo para errores de binding / parámetros.
+func abortInvalid(c *gin.Context, err error) {
+	abortError(c, errs.Invalid("%s", err.Error()))
+}
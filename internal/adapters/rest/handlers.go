@@ -1,10 +1,16 @@
 package rest
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/export"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -14,46 +20,122 @@ type Handlers struct {
 	pub      inbound.Publisher
 	consumer inbound.Consumer
 	queue    inbound.Queue
+	auth     outbound.AuthStore // ACL de las creaciones (el nombre va en el cuerpo)
 }
 
-func NewHandlers(a inbound.Admin, p inbound.Publisher, c inbound.Consumer, q inbound.Queue) *Handlers {
-	return &Handlers{admin: a, pub: p, consumer: c, queue: q}
-}
-
-// ---- AUTH -------------------------------------------------------
-
-func (h *Handlers) Login(c *gin.Context) {
-	var u struct {
-		User string `json:"user"`
-		Pass string `json:"pass"`
-	}
-	_ = c.BindJSON(&u)
-	c.JSON(http.StatusOK, gin.H{
-		"token": u.User,
-		"user":  u.User,
-	})
+func NewHandlers(a inbound.Admin, p inbound.Publisher, c inbound.Consumer, q inbound.Queue, auth outbound.AuthStore) *Handlers {
+	return &Handlers{admin: a, pub: p, consumer: c, queue: q, auth: auth}
 }
 
 // ---- TOPICS -----------------------------------------------------
 
 func (h *Handlers) CreateTopic(c *gin.Context) {
 	var req struct {
-		Name string `json:"name"`
+		Name              string `json:"name"`
+		Partitions        int    `json:"partitions"`         // 0 → 3
+		ReplicationFactor int    `json:"replication_factor"` // 0 → cluster.replication_factor
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
 		return
 	}
+	if req.Partitions == 0 {
+		req.Partitions = 3
+	}
+	c.Set("resource", "topic:"+req.Name)
 	user := c.GetString("user")
-	if err := h.admin.CreateTopic(c, req.Name, 3, user); err != nil {
-		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+	if err := h.auth.Authorize(c, user, model.OpManage, model.ScopeTopic, req.Name); err != nil {
+		abortError(c, err)
+		return
+	}
+	if err := h.admin.CreateTopic(c, req.Name, req.Partitions, req.ReplicationFactor, user); err != nil {
+		abortError(c, err)
 		return
 	}
 	c.Status(http.StatusCreated)
 }
 
+// ReassignTopic cambia las réplicas de un tópico; sin "replicas" las
+// reparte de nuevo sobre los nodos actuales.
+func (h *Handlers) ReassignTopic(c *gin.Context) {
+	var req struct {
+		Replicas          [][]string `json:"replicas"`
+		ReplicationFactor int        `json:"replication_factor"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortInvalid(c, err)
+			return
+		}
+	}
+	name := c.Param("topic")
+	c.Set("resource", "topic:"+name)
+	t, err := h.admin.ReassignTopic(c, name, req.Replicas, req.ReplicationFactor)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// ExportTopic descarga los mensajes comprometidos de un tópico.
+// Acepta ?format= (jsonl, csv o pb), ?partition= (-1 = todas), ?from= y
+// ?to= (offsets, to excluido), ?key= y ?since=/?until= (RFC 3339).
+func (h *Handlers) ExportTopic(c *gin.Context) {
+	q := model.ExportQuery{Topic: c.Param("topic"), Key: c.Query("key")}
+	var err error
+	if q.Partition, err = strconv.Atoi(c.DefaultQuery("partition", "-1")); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	if q.From, err = strconv.ParseUint(c.DefaultQuery("from", "0"), 10, 64); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	if q.To, err = strconv.ParseUint(c.DefaultQuery("to", "0"), 10, 64); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	if v := c.Query("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			abortInvalid(c, err)
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if q.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			abortInvalid(c, err)
+			return
+		}
+	}
+	f, err := export.ParseFormat(c.DefaultQuery("format", "jsonl"))
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", export.ContentType(f))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, q.Topic, f))
+	c.Header("Trailer", "X-Exported-Messages")
+	n, err := h.admin.ExportTopic(c, c.Writer, string(f), q)
+	if err != nil {
+		if !c.Writer.Written() {
+			abortError(c, err)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+	c.Header("X-Exported-Messages", strconv.Itoa(n))
+}
+
 func (h *Handlers) ListTopics(c *gin.Context) {
-	list, _ := h.admin.ListTopics(c)
+	list, err := h.admin.ListTopics(c)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, list)
 }
 
@@ -63,14 +145,23 @@ func (h *Handlers) Publish(c *gin.Context) {
 		Key     string `json:"key"`
 		Payload string `json:"payload"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	acks, err := model.ParseAcks(c.Query("acks"))
+	if err != nil {
+		abortInvalid(c, err)
 		return
 	}
 	user := c.GetString("user")
-	part, off, err := h.pub.Publish(c, topic, req.Key, req.Payload, user)
+	part, off, err := h.pub.Publish(c, topic, req.Key, req.Payload, user, acks)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortError(c, err)
+		return
+	}
+	if acks == model.AcksNone { // aún sin offset
+		c.JSON(http.StatusAccepted, gin.H{"partition": part})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"partition": part, "offset": off})
@@ -79,13 +170,21 @@ func (h *Handlers) Publish(c *gin.Context) {
 func (h *Handlers) Pull(c *gin.Context) {
 	topic := c.Param("topic")
 	group := c.DefaultQuery("group", "default") // Obtenemos el grupo de consumidores
-	part, _ := strconv.Atoi(c.DefaultQuery("partition", "0"))
-	max, _ := strconv.Atoi(c.DefaultQuery("max", "100"))
+	part, err := strconv.Atoi(c.DefaultQuery("partition", "0"))
+	if err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	max, err := strconv.Atoi(c.DefaultQuery("max", "100"))
+	if err != nil {
+		abortInvalid(c, err)
+		return
+	}
 
 	// Leer los mensajes del grupo
 	msgs, err := h.consumer.Pull(c, topic, group, part, max)
 	if err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.JSON(200, msgs)
@@ -98,24 +197,68 @@ func (h *Handlers) CommitOffset(c *gin.Context) {
 		Partition int    `json:"partition"`
 		Offset    uint64 `json:"offset"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
 		return
 	}
 
 	// Confirmar el commit del offset
 	if err := h.consumer.Commit(c, topic, req.Group, req.Partition, req.Offset); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.Status(204)
 }
 
+func (h *Handlers) DescribeTopic(c *gin.Context) {
+	d, err := h.admin.DescribeTopic(c, c.Param("topic"))
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// DescribeGroup sólo muestra los offsets de los tópicos que el usuario
+// puede leer.
+func (h *Handlers) DescribeGroup(c *gin.Context) {
+	d, err := h.consumer.DescribeGroup(c, c.Param("group"))
+	if err == nil {
+		d.Offsets, err = readable(c, h.auth, d.Offsets, func(o model.GroupOffset) string { return o.Topic })
+	}
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// Lag acepta ?group= y ?topic= como filtros opcionales.
+// Lag, como DescribeGroup, se limita a los tópicos que el usuario puede
+// leer; pedir uno concreto sin permiso da 403.
+func (h *Handlers) Lag(c *gin.Context) {
+	if t := c.Query("topic"); t != "" {
+		if err := h.auth.Authorize(c, c.GetString("user"), model.OpRead, model.ScopeTopic, t); err != nil {
+			abortError(c, err)
+			return
+		}
+	}
+	lags, err := h.consumer.Lag(c, c.Query("group"), c.Query("topic"))
+	if err == nil {
+		lags, err = readable(c, h.auth, lags, func(l model.ConsumerLag) string { return l.Topic })
+	}
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, lags)
+}
+
 func (h *Handlers) DeleteTopic(c *gin.Context) {
 	name := c.Param("topic")
 	user := c.GetString("user")
 	if err := h.admin.DeleteTopic(c, name, user); err != nil {
-		c.AbortWithStatusJSON(403, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.Status(204)
@@ -127,13 +270,18 @@ func (h *Handlers) CreateQueue(c *gin.Context) {
 	var req struct {
 		Name string `json:"name"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
 		return
 	}
+	c.Set("resource", "queue:"+req.Name)
 	user := c.GetString("user")
+	if err := h.auth.Authorize(c, user, model.OpManage, model.ScopeQueue, req.Name); err != nil {
+		abortError(c, err)
+		return
+	}
 	if err := h.queue.CreateQueue(c, req.Name, user); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.Status(201)
@@ -144,13 +292,13 @@ func (h *Handlers) Enqueue(c *gin.Context) {
 	var req struct {
 		Payload string `json:"payload"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
 		return
 	}
 	user := c.GetString("user")
 	if err := h.queue.Enqueue(c, queue, req.Payload, user); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.Status(201)
@@ -160,7 +308,7 @@ func (h *Handlers) Dequeue(c *gin.Context) {
 	queue := c.Param("queue")
 	msg, err := h.queue.Dequeue(c, queue)
 	if err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	if msg == nil {
@@ -175,29 +323,173 @@ func (h *Handlers) Ack(c *gin.Context) {
 	var req struct {
 		ID string `json:"id"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	uid, err := uuid.Parse(req.ID)
+	if err != nil {
+		abortInvalid(c, err)
 		return
 	}
-	uid, _ := uuid.Parse(req.ID)
 	if err := h.queue.Ack(c, queue, uid); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.Status(204)
 }
 
 func (h *Handlers) ListQueues(c *gin.Context) {
-	list, _ := h.admin.ListQueues(c)
+	list, err := h.admin.ListQueues(c)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, list)
 }
 
+func (h *Handlers) DescribeQueue(c *gin.Context) {
+	d, err := h.admin.DescribeQueue(c, c.Param("queue"))
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
 func (h *Handlers) DeleteQueue(c *gin.Context) {
 	name := c.Param("queue")
 	user := c.GetString("user")
 	if err := h.admin.DeleteQueue(c, name, user); err != nil {
-		c.AbortWithStatusJSON(403, gin.H{"error": err.Error()})
+		abortError(c, err)
 		return
 	}
 	c.Status(204)
 }
+
+// ---- QUOTAS -----------------------------------------------------
+
+func validScope(s string) bool {
+	return s == model.ScopeUser || s == model.ScopeTopic || s == model.ScopeQueue
+}
+
+func (h *Handlers) GetQuota(c *gin.Context) {
+	scope, name := c.Param("scope"), c.Param("name")
+	if !validScope(scope) {
+		abortError(c, errs.Invalid("scope must be user, topic or queue"))
+		return
+	}
+	q, usage, err := h.admin.GetQuota(c, scope, name)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"scope": scope, "name": name, "quota": q, "usage": usage})
+}
+
+func (h *Handlers) SetQuota(c *gin.Context) {
+	scope, name := c.Param("scope"), c.Param("name")
+	if !validScope(scope) {
+		abortError(c, errs.Invalid("scope must be user, topic or queue"))
+		return
+	}
+	c.Set("resource", scope+":"+name)
+	var q model.Quota
+	if err := c.ShouldBindJSON(&q); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	if err := h.admin.SetQuota(c, scope, name, q); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(204)
+}
+
+// ---- AUDIT ------------------------------------------------------
+
+// QueryAudit acepta since/until (RFC 3339), user, resource y limit.
+func (h *Handlers) QueryAudit(c *gin.Context) {
+	var f model.AuditFilter
+	var err error
+	if v := c.Query("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			abortInvalid(c, err)
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if f.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			abortInvalid(c, err)
+			return
+		}
+	}
+	f.Actor = c.Query("user")
+	f.Resource = c.Query("resource")
+	if f.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "100")); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+
+	events, err := h.admin.QueryAudit(c, f)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(200, events)
+}
+
+// ---- BACKUP -----------------------------------------------------
+
+// Backup descarga un backup del almacenamiento de este nodo; ?since= (el
+// next del backup anterior) lo hace incremental. La versión alcanzada
+// viaja en el trailer X-Backup-Version; si algo falla a mitad, el archivo
+// queda sin marca final y restore lo rechaza.
+func (h *Handlers) Backup(c *gin.Context) {
+	since, err := strconv.ParseUint(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	// un backup grande tarda más que rest.write_timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="mom-%s.bak"`,
+		time.Now().UTC().Format("20060102T150405Z")))
+	c.Header("Trailer", "X-Backup-Version")
+	m, err := h.admin.Backup(c, c.Writer, since)
+	if err != nil {
+		if !c.Writer.Written() {
+			abortError(c, err)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+	c.Header("X-Backup-Version", strconv.FormatUint(m.Version, 10))
+}
+
+// readable devuelve los items cuyo tópico puede leer el usuario, con la
+// misma ACL (read) que las rutas de consumo.
+func readable[T any](c *gin.Context, auth outbound.AuthStore, items []T, topic func(T) string) ([]T, error) {
+	user := c.GetString("user")
+	allowed := map[string]bool{}
+	out := items[:0]
+	for _, it := range items {
+		t := topic(it)
+		ok, seen := allowed[t]
+		if !seen {
+			err := auth.Authorize(c, user, model.OpRead, model.ScopeTopic, t)
+			if err != nil && errs.Kind(err) != errs.ErrForbidden {
+				return nil, err
+			}
+			ok = err == nil
+			allowed[t] = ok
+		}
+		if ok {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandlers sirve las sondas del orquestador (sin auth).
+type HealthHandlers struct{ health inbound.Health }
+
+// Healthz sólo indica que el proceso responde.
+func (h *HealthHandlers) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz responde 503 si algún check falla.
+func (h *HealthHandlers) Readyz(c *gin.Context) {
+	r := h.health.Ready(c)
+	status := http.StatusOK
+	if !r.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, r)
+}
+
+func (h *HealthHandlers) ClusterStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.health.ClusterStatus(c))
+}
@@ -2,24 +2,125 @@ package rest
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AuthMiddleware(a outbound.AuthStore) gin.HandlerFunc {
+// AuthMiddleware valida X-Token; los rechazos quedan en la auditoría
+// como auth.failed (l puede ser nil).
+func AuthMiddleware(a outbound.AuthStore, l outbound.AuditLog) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("X-Token")
 		if token == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			metrics.AuthFailures.WithLabelValues("missing_token").Inc()
+			abortError(c, errs.New(errs.ErrUnauthenticated, "missing token"))
+			record(c, l, "auth.failed")
 			return
 		}
 		user, ok := a.Validate(c, token)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			metrics.AuthFailures.WithLabelValues("invalid_token").Inc()
+			abortError(c, errs.New(errs.ErrUnauthenticated, "invalid token"))
+			record(c, l, "auth.failed")
 			return
 		}
 		c.Set("user", user)
 		c.Next()
 	}
 }
+
+// AdminMiddleware deja pasar sólo a los administradores; va después de
+// AuthMiddleware y el rechazo (403) queda en la auditoría de la ruta.
+func AdminMiddleware(a outbound.AuthStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := c.GetString("user"); !a.IsAdmin(c, user) {
+			abortError(c, errs.Forbidden("user %q is not an administrator", user))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ACLMiddleware aplica las ACLs a las rutas con :topic o :queue (kind);
+// va después de AuthMiddleware.
+func ACLMiddleware(a outbound.AuthStore, op, kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := a.Authorize(c, c.GetString("user"), op, kind, c.Param(kind)); err != nil {
+			abortError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuditMiddleware registra la operación una vez atendida. El recurso lo
+// fija el handler con c.Set("resource", ...); si no, se deduce de la ruta.
+// Los 401 no se registran aquí porque ya los anota AuthMiddleware.
+func AuditMiddleware(l outbound.AuditLog, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Status() == http.StatusUnauthorized {
+			return
+		}
+		record(c, l, action)
+	}
+}
+
+func record(c *gin.Context, l outbound.AuditLog, action string) {
+	if l == nil {
+		return
+	}
+	res := c.GetString("resource")
+	if res == "" {
+		if t := c.Param("topic"); t != "" {
+			res = "topic:" + t
+		} else if q := c.Param("queue"); q != "" {
+			res = "queue:" + q
+		}
+	}
+	result := "success"
+	if c.Writer.Status() >= 400 {
+		result = "failure"
+	}
+	_ = l.Record(c, model.AuditEvent{
+		Time:     time.Now().UTC(),
+		Actor:    c.GetString("user"),
+		Action:   action,
+		Resource: res,
+		Result:   result,
+		Status:   c.Writer.Status(),
+		SourceIP: c.ClientIP(),
+	})
+}
+
+// TracingMiddleware abre el span de servidor de cada petición continuando
+// el traceparent que mande el cliente. El contexto se deja en c.Request;
+// el router activa ContextWithFallback para que c lo exponga.
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := tracing.ExtractHTTP(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.StartKind(ctx, c.Request.Method+" "+c.FullPath(), trace.SpanKindServer,
+			attribute.String("http.method", c.Request.Method),
+			attribute.String("http.route", c.FullPath()))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+// MirrorHandlers gestionan los mirrors entre clústeres (/admin/mirrors).
+type MirrorHandlers struct{ mirror inbound.Mirror }
+
+func (h *MirrorHandlers) ListMirrors(c *gin.Context) {
+	list, err := h.mirror.ListMirrors(c)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateMirror recibe la definición (name, source, token, group, topics,
+// prefix, rename, paused); creator y created_at los pone el servidor.
+func (h *MirrorHandlers) CreateMirror(c *gin.Context) {
+	var m model.Mirror
+	if err := c.ShouldBindJSON(&m); err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	c.Set("resource", "mirror:"+m.Name)
+	if err := h.mirror.CreateMirror(c, m, c.GetString("user")); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+func (h *MirrorHandlers) MirrorStatus(c *gin.Context) {
+	st, err := h.mirror.MirrorStatus(c, c.Param("mirror"))
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// PauseMirror y ResumeMirror detienen y reanudan la copia; el checkpoint
+// se conserva.
+func (h *MirrorHandlers) PauseMirror(c *gin.Context)  { h.pause(c, true) }
+func (h *MirrorHandlers) ResumeMirror(c *gin.Context) { h.pause(c, false) }
+
+func (h *MirrorHandlers) pause(c *gin.Context, paused bool) {
+	name := c.Param("mirror")
+	c.Set("resource", "mirror:"+name)
+	if err := h.mirror.PauseMirror(c, name, paused); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *MirrorHandlers) DeleteMirror(c *gin.Context) {
+	name := c.Param("mirror")
+	c.Set("resource", "mirror:"+name)
+	if err := h.mirror.DeleteMirror(c, name); err != nil {
+		abortError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TranslateOffset acepta ?topic= (de origen), ?partition= y ?offset=.
+func (h *MirrorHandlers) TranslateOffset(c *gin.Context) {
+	part, err := strconv.Atoi(c.DefaultQuery("partition", "0"))
+	if err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	off, err := strconv.ParseUint(c.Query("offset"), 10, 64)
+	if err != nil {
+		abortInvalid(c, err)
+		return
+	}
+	tr, err := h.mirror.TranslateOffset(c, c.Param("mirror"), c.Query("topic"), part, off)
+	if err != nil {
+		abortError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tr)
+}
@@ -1,36 +1,103 @@
 package rest
 
 import (
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
 	"github.com/gin-gonic/gin"
 )
 
 func NewRouter(admin inbound.Admin, pub inbound.Publisher, cons inbound.Consumer,
-	queue inbound.Queue, auth outbound.AuthStore) *gin.Engine {
+	queue inbound.Queue, health inbound.Health, members inbound.Cluster, mirrors inbound.Mirror,
+	access inbound.Access, auth outbound.AuthStore, audit outbound.AuditLog) *gin.Engine {
 
 	r := gin.Default()
-	h := NewHandlers(admin, pub, cons, queue)
+	r.ContextWithFallback = true // c.Value/Done delegan en c.Request.Context()
+	r.Use(TracingMiddleware())
+	h := NewHandlers(admin, pub, cons, queue, auth)
+	ah := &AccessHandlers{access: access}
 
-	r.POST("/login", h.Login)
+	audited := func(action string) gin.HandlerFunc { return AuditMiddleware(audit, action) }
 
-	authMw := AuthMiddleware(auth)
+	r.POST("/login", audited("auth.login"), ah.Login)
+
+	authMw := AuthMiddleware(auth, audit)
+	adminMw := AdminMiddleware(auth)
+	topicMw := func(op string) gin.HandlerFunc { return ACLMiddleware(auth, op, model.ScopeTopic) }
+	queueMw := func(op string) gin.HandlerFunc { return ACLMiddleware(auth, op, model.ScopeQueue) }
 
 	// tópicos
-	r.POST("/topics", authMw, h.CreateTopic)
+	r.POST("/topics", audited("topic.create"), authMw, h.CreateTopic)
 	r.GET("/topics", authMw, h.ListTopics)
-	r.DELETE("/topics/:topic", authMw, h.DeleteTopic)
-	r.POST("/topics/:topic/messages", authMw, h.Publish)
-	r.GET("/topics/:topic/messages", authMw, h.Pull)
-	r.POST("/topics/:topic/offsets", authMw, h.CommitOffset)
+	r.GET("/topics/:topic", authMw, topicMw(model.OpRead), h.DescribeTopic)
+	r.DELETE("/topics/:topic", audited("topic.delete"), authMw, topicMw(model.OpManage), h.DeleteTopic)
+	r.POST("/topics/:topic/messages", authMw, topicMw(model.OpWrite), h.Publish)
+	r.GET("/topics/:topic/messages", authMw, topicMw(model.OpRead), h.Pull)
+	r.POST("/topics/:topic/offsets", audited("offset.commit"), authMw, topicMw(model.OpRead), h.CommitOffset)
 
 	// colas
-	r.POST("/queues", authMw, h.CreateQueue)
+	r.POST("/queues", audited("queue.create"), authMw, h.CreateQueue)
 	r.GET("/queues", authMw, h.ListQueues)
-	r.DELETE("/queues/:queue", authMw, h.DeleteQueue)
-	r.POST("/queues/:queue/messages", authMw, h.Enqueue)
-	r.GET("/queues/:queue/messages", authMw, h.Dequeue)
-	r.POST("/queues/:queue/ack", authMw, h.Ack)
+	r.GET("/queues/:queue", authMw, queueMw(model.OpRead), h.DescribeQueue)
+	r.DELETE("/queues/:queue", audited("queue.delete"), authMw, queueMw(model.OpManage), h.DeleteQueue)
+	r.POST("/queues/:queue/messages", authMw, queueMw(model.OpWrite), h.Enqueue)
+	r.GET("/queues/:queue/messages", authMw, queueMw(model.OpRead), h.Dequeue)
+	r.POST("/queues/:queue/ack", authMw, queueMw(model.OpRead), h.Ack)
+
+	// grupos de consumo
+	r.GET("/groups/:group", authMw, h.DescribeGroup)
+	r.GET("/lag", authMw, h.Lag)
+
+	// métricas Prometheus (sin auth, para el scraper)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// sondas y estado del clúster (sin auth, para el orquestador)
+	hh := &HealthHandlers{health: health}
+	r.GET("/healthz", hh.Healthz)
+	r.GET("/readyz", hh.Readyz)
+	r.GET("/cluster/status", hh.ClusterStatus)
+
+	// cuotas
+	r.GET("/admin/quotas/:scope/:name", authMw, adminMw, h.GetQuota)
+	r.PUT("/admin/quotas/:scope/:name", audited("quota.set"), authMw, adminMw, h.SetQuota)
+
+	// auditoría
+	r.GET("/admin/audit", authMw, adminMw, h.QueryAudit)
+
+	// backup del almacenamiento de este nodo
+	r.GET("/admin/backup", audited("backup.create"), authMw, adminMw, h.Backup)
+
+	// réplicas de los tópicos
+	r.POST("/admin/topics/:topic/reassign", audited("topic.reassign"), authMw, adminMw, h.ReassignTopic)
+
+	// exportación de tópicos (jsonl, csv o pb)
+	r.GET("/admin/topics/:topic/export", audited("topic.export"), authMw, adminMw, h.ExportTopic)
+
+	// membresía del clúster
+	ch := &ClusterHandlers{cluster: members}
+	r.GET("/admin/cluster/nodes", authMw, adminMw, ch.ListNodes)
+	r.POST("/admin/cluster/nodes", audited("cluster.node.add"), authMw, adminMw, ch.AddNode)
+	r.DELETE("/admin/cluster/nodes/:id", audited("cluster.node.remove"), authMw, adminMw, ch.RemoveNode)
+
+	// mirrors entre clústeres
+	mh := &MirrorHandlers{mirror: mirrors}
+	r.GET("/admin/mirrors", authMw, adminMw, mh.ListMirrors)
+	r.POST("/admin/mirrors", audited("mirror.create"), authMw, adminMw, mh.CreateMirror)
+	r.GET("/admin/mirrors/:mirror", authMw, adminMw, mh.MirrorStatus)
+	r.DELETE("/admin/mirrors/:mirror", audited("mirror.delete"), authMw, adminMw, mh.DeleteMirror)
+	r.POST("/admin/mirrors/:mirror/pause", audited("mirror.pause"), authMw, adminMw, mh.PauseMirror)
+	r.POST("/admin/mirrors/:mirror/resume", audited("mirror.resume"), authMw, adminMw, mh.ResumeMirror)
+	r.GET("/admin/mirrors/:mirror/translate", authMw, adminMw, mh.TranslateOffset)
+
+	// cuentas y ACLs
+	r.GET("/admin/users", authMw, adminMw, ah.ListUsers)
+	r.POST("/admin/users", audited("user.create"), authMw, adminMw, ah.CreateUser)
+	r.PATCH("/admin/users/:user", audited("user.update"), authMw, adminMw, ah.UpdateUser)
+	r.DELETE("/admin/users/:user", audited("user.delete"), authMw, adminMw, ah.DeleteUser)
+	r.GET("/admin/acls", authMw, adminMw, ah.ListACLs)
+	r.POST("/admin/acls", audited("acl.create"), authMw, adminMw, ah.AddACL)
+	r.DELETE("/admin/acls/:id", audited("acl.delete"), authMw, adminMw, ah.DeleteACL)
 
 	return r
 }
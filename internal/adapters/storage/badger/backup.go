@@ -0,0 +1,189 @@
+package badgerstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+Formato de un backup:
+
+	MOMBAK01 | volcado de DB.Backup | manifiesto JSON | len(manifiesto) uint32 BE | MOMBAK01
+
+El manifiesto va al final porque lleva el tamaño y el SHA-256 del volcado,
+que sólo se conocen al terminarlo; así el backup se puede escribir en un
+stream (p. ej. la respuesta HTTP). Un archivo cortado no tiene la marca
+final y se rechaza antes de cargar nada.
+*/
+
+const (
+	backupMagic  = "MOMBAK01"
+	backupFormat = 1
+	// maxManifest acota lo que se lee como manifiesto de un archivo dañado.
+	maxManifest = 64 << 10
+)
+
+var _ outbound.Backup = (*Store)(nil)
+
+// Backup escribe en w lo escrito desde la versión since (0 = todo). Badger
+// lo lee de una instantánea, así que el nodo sigue atendiendo mientras.
+func (s *Store) Backup(ctx context.Context, w io.Writer, since uint64) (m model.BackupManifest, err error) {
+	ctx, span := tracing.Start(ctx, "Store.Backup", attribute.Int64("mom.since", int64(since)))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := io.WriteString(w, backupMagic); err != nil {
+		return m, err
+	}
+	dump := &hashWriter{w: w, ctx: ctx, h: sha256.New()}
+	version, err := s.db.Backup(dump, since)
+	if err != nil {
+		return m, err
+	}
+	if since > 0 && version < since { // nada nuevo: el siguiente sigue en since
+		version = since - 1
+	}
+	m = model.BackupManifest{
+		Format:    backupFormat,
+		CreatedAt: time.Now().UTC(),
+		Since:     since,
+		Version:   version,
+		Bytes:     dump.n,
+		SHA256:    hex.EncodeToString(dump.h.Sum(nil)),
+	}
+	js, _ := json.Marshal(m)
+	trailer := append(js, binary.BigEndian.AppendUint32(nil, uint32(len(js)))...)
+	trailer = append(trailer, backupMagic...)
+	if _, err := w.Write(trailer); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// VerifyBackup lee el manifiesto de un backup y comprueba el volcado.
+func VerifyBackup(r io.ReaderAt, size int64) (model.BackupManifest, error) {
+	m, _, err := openBackup(r, size)
+	return m, err
+}
+
+// Restore carga un backup en el store, que no debe estar sirviendo (el
+// broker parado). Un backup completo va a un directorio vacío salvo con
+// force; un incremental exige que ya estén cargados los anteriores. Nada
+// se carga si el archivo no supera la verificación.
+func (s *Store) Restore(ctx context.Context, r io.ReaderAt, size int64, force bool) (model.BackupManifest, error) {
+	m, dump, err := openBackup(r, size)
+	if err != nil {
+		return m, err
+	}
+	cur := s.db.MaxVersion()
+	switch {
+	case !m.Incremental() && cur > 0 && !force:
+		return m, errs.Conflict("data dir already holds data up to version %d (restore a full backup into an empty dir)", cur)
+	case m.Incremental() && m.Since > cur+1:
+		return m, errs.Conflict("incremental backup starts at version %d but data dir is at version %d (restore the previous backups first)",
+			m.Since, cur)
+	}
+	if err := s.db.Load(&ctxReader{r: dump, ctx: ctx}, 256); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// openBackup valida marcas, manifiesto y SHA-256, y devuelve el volcado.
+func openBackup(r io.ReaderAt, size int64) (model.BackupManifest, *io.SectionReader, error) {
+	var m model.BackupManifest
+	tail := int64(4 + len(backupMagic))
+	if size < int64(len(backupMagic))+tail {
+		return m, nil, errs.Invalid("backup is truncated (%d bytes)", size)
+	}
+	head := make([]byte, len(backupMagic))
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return m, nil, err
+	}
+	end := make([]byte, tail)
+	if _, err := r.ReadAt(end, size-tail); err != nil {
+		return m, nil, err
+	}
+	if string(head) != backupMagic {
+		return m, nil, errs.Invalid("not a broker backup")
+	}
+	if string(end[4:]) != backupMagic {
+		return m, nil, errs.Invalid("backup is truncated (no end marker)")
+	}
+	n := int64(binary.BigEndian.Uint32(end[:4]))
+	dumpEnd := size - tail - n
+	if n > maxManifest || dumpEnd < int64(len(backupMagic)) {
+		return m, nil, errs.Invalid("backup manifest is corrupt")
+	}
+	js := make([]byte, n)
+	if _, err := r.ReadAt(js, dumpEnd); err != nil {
+		return m, nil, err
+	}
+	if err := json.Unmarshal(js, &m); err != nil {
+		return m, nil, errs.Invalid("backup manifest is corrupt: %v", err)
+	}
+	if m.Format != backupFormat {
+		return m, nil, errs.Invalid("unsupported backup format %d", m.Format)
+	}
+
+	dump := io.NewSectionReader(r, int64(len(backupMagic)), dumpEnd-int64(len(backupMagic)))
+	if dump.Size() != m.Bytes {
+		return m, nil, errs.Invalid("backup dump has %d bytes, manifest says %d", dump.Size(), m.Bytes)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, dump); err != nil {
+		return m, nil, err
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, mustHex(m.SHA256)) {
+		return m, nil, errs.Invalid("backup checksum mismatch (sha256 %x, manifest says %s)", sum, m.SHA256)
+	}
+	return m, io.NewSectionReader(r, int64(len(backupMagic)), m.Bytes), nil
+}
+
+func mustHex(s string) []byte {
+	b, _ := hex.DecodeString(s)
+	return b
+}
+
+// hashWriter cuenta y resume lo escrito; corta si se cancela ctx.
+type hashWriter struct {
+	w   io.Writer
+	ctx context.Context
+	h   hash.Hash
+	n   int64
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := w.w.Write(p)
+	w.h.Write(p[:n])
+	w.n += int64(n)
+	return n, err
+}
+
+// ctxReader corta la lectura si se cancela ctx.
+type ctxReader struct {
+	r   io.Reader
+	ctx context.Context
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -1,47 +1,139 @@
 package badgerstore
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/badgererr"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
-	msgPrefix    = "m:" // m:<topic>:<part>:<offset>
+	msgPrefix    = "m:" // m:<topic>:<part>:<offset> (offset con 20 dígitos)
 	hwmPrefix    = "h:" // h:<topic>:<part>
 	qPrefix      = "q:" // q:<queue>:<seq>
 	infPrefix    = "f:" // f:<queue>:<uuid>
 	offsetPrefix = "o:" // o:<group>:<topic>:<part> -> offset(uint64)
+	termPrefix   = "e:" // e:<topic>:<part> -> término/voto del log replicado
+	confPrefix   = "p:" // p:<topic>:<part> -> votantes del log replicado (json)
+	applPrefix   = "a:" // a:<log> -> próximo offset por aplicar (uint64)
 )
 
 // ------------------------------------------------------------------
 // Store
 // ------------------------------------------------------------------
 
-type Store struct{ db *badger.DB }
+// Options ajusta los tiempos de las colas y la retención de tópicos.
+type Options struct {
+	InFlightTTL       time.Duration // tiempo para hacer ack antes de reencolar
+	RequeueInterval   time.Duration
+	Retain            map[string]uint64 // tópico → últimos N mensajes por partición; sin entrada, todos
+	RetentionInterval time.Duration
+}
+
+// DefaultOptions son los valores que el store usaba fijos.
+func DefaultOptions() Options {
+	return Options{
+		InFlightTTL:       30 * time.Second,
+		RequeueInterval:   5 * time.Second,
+		RetentionInterval: time.Minute,
+	}
+}
+
+type Store struct {
+	db   *badger.DB
+	opts Options
+}
+
+func New(dir string) (*Store, error) { return Open(dir, DefaultOptions()) }
 
-func New(dir string) (*Store, error) {
+// Open abre Badger en dir con las opciones dadas.
+func Open(dir string, o Options) (*Store, error) {
 	opts := badger.DefaultOptions(filepath.Clean(dir)).WithLoggingLevel(badger.ERROR)
 	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, err
 	}
-	return &Store{db: db}, nil
+	s := &Store{db: db, opts: o}
+	if err := s.migrateOffsetKeys(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrateOffsetKeys reescribe las claves m: antiguas, con el offset sin
+// relleno, al formato de ancho fijo para que Badger las ordene por offset.
+func (s *Store) migrateOffsetKeys() error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	n := 0
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(msgPrefix), PrefetchValues: true})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			k := string(it.Item().Key())
+			i := strings.LastIndexByte(k, ':')
+			off, err := strconv.ParseUint(k[i+1:], 10, 64)
+			if err != nil || len(k)-i-1 == offWidth {
+				continue
+			}
+			val, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := wb.Set([]byte(k[:i+1]+offStr(off)), val); err != nil {
+				return err
+			}
+			if err := wb.Delete(it.Item().KeyCopy(nil)); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil || n == 0 {
+		return err
+	}
+	return wb.Flush()
 }
 
 // Exponer la instancia para el MetaStore
 func (s *Store) DB() *badger.DB { return s.db }
 
+// Close cierra Badger limpiamente (vuelca memtables y value-log), así el
+// siguiente arranque no tiene que reproducir el vlog.
+func (s *Store) Close() error {
+	if s.db.IsClosed() {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Check implementa outbound.HealthChecker.
+func (s *Store) Check(context.Context) error {
+	if s.db.IsClosed() {
+		return errs.Unavailable("badger is closed")
+	}
+	return nil
+}
+
 // ------------------------------------------------------------------
 // Helpers
 // ------------------------------------------------------------------
@@ -49,6 +141,20 @@ func (s *Store) DB() *badger.DB { return s.db }
 func join(parts ...string) string { return strings.Join(parts, ":") }
 func key(parts ...string) []byte  { return []byte(join(parts...)) }
 
+const offWidth = 20 // dígitos de un uint64
+
+func offStr(off uint64) string { return fmt.Sprintf("%0*d", offWidth, off) }
+
+// msgKey es m:<topic>:<part>:<offset>; partPrefix, lo mismo sin el offset
+// pero con los dos puntos, para no mezclar la partición 1 con la 10.
+func msgKey(topic string, part int, off uint64) []byte {
+	return key(msgPrefix, topic, strconv.Itoa(part), offStr(off))
+}
+
+func partPrefix(topic string, part int) []byte {
+	return append(key(msgPrefix, topic, strconv.Itoa(part)), ':')
+}
+
 func u64(i uint64) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, i)
@@ -61,7 +167,9 @@ func b2u64(b []byte) uint64 { return binary.BigEndian.Uint64(b) }
 // ------------------------------------------------------------------
 
 // Append agrega un mensaje NUEVO generado por este nodo.
-func (s *Store) Append(_ context.Context, msg model.Message) (uint64, error) {
+func (s *Store) Append(ctx context.Context, msg model.Message) (uint64, error) {
+	_, span := tracing.Start(ctx, "MessageStore.Append",
+		attribute.String("mom.topic", msg.Topic), attribute.Int("mom.partition", msg.PartID))
 	var offset uint64
 	err := s.db.Update(func(txn *badger.Txn) error {
 		hwmKey := key(hwmPrefix, msg.Topic, strconv.Itoa(msg.PartID))
@@ -75,8 +183,8 @@ func (s *Store) Append(_ context.Context, msg model.Message) (uint64, error) {
 			return err
 		}
 
-		/* si viene del publicador ya trae UUID,
-		   si viene del reconciliador es uuid.Nil y le ponemos uno */
+		/* si viene del publicador ya trae UUID;
+		   si no (uuid.Nil) le ponemos uno */
 		if msg.ID == uuid.Nil {
 			msg.ID = uuid.New()
 		}
@@ -84,60 +192,61 @@ func (s *Store) Append(_ context.Context, msg model.Message) (uint64, error) {
 
 		js, _ := json.Marshal(msg)
 		if err := txn.Set(
-			key(msgPrefix, msg.Topic, strconv.Itoa(msg.PartID), strconv.FormatUint(offset, 10)), js); err != nil {
+			msgKey(msg.Topic, msg.PartID, offset), js); err != nil {
 			return err
 		}
 		return txn.Set(hwmKey, u64(offset+1))
 	})
 	if err == nil {
-		// refresca hwm en memoria para reconciliación
+		// refresca hwm en memoria (métrica y /cluster/status)
 		cluster.TrackNextOffset(msg.Topic, msg.PartID, offset+1)
 	}
-	return offset, err
+	tracing.End(span, err)
+	return offset, badgererr.Wrap(err)
 }
 
 // AppendWithOffset inserta un mensaje VENIDO DE OTRO NODO conservando offset.
 func (s *Store) AppendWithOffset(_ context.Context, msg model.Message) error {
-	return s.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
 		partStr := strconv.Itoa(msg.PartID)
 		hwmKey := key(hwmPrefix, msg.Topic, partStr)
-		msgKey := key(msgPrefix, msg.Topic, partStr, strconv.FormatUint(msg.Offset, 10))
-
-		// 1) ¿ya lo tenía?
-		if _, err := txn.Get(msgKey); err == nil {
-			// Aun así se puede necesitar subir el HWM si se está rezagado
-			curNext := uint64(0)
-			if item, err := txn.Get(hwmKey); err == nil {
-				val, _ := item.ValueCopy(nil)
-				curNext = b2u64(val)
+		mk := msgKey(msg.Topic, msg.PartID, msg.Offset)
+
+		// 1) insertar el mensaje, si no lo tenía
+		if _, err := txn.Get(mk); err == badger.ErrKeyNotFound {
+			if msg.ID == uuid.Nil {
+				msg.ID = uuid.New()
 			}
-			if next := msg.Offset + 1; next > curNext {
-				_ = txn.Set(hwmKey, u64(next))
-				cluster.TrackNextOffset(msg.Topic, msg.PartID, next) // RAM
+			js, _ := json.Marshal(msg)
+			if err := txn.Set(mk, js); err != nil {
+				return err
 			}
-			return nil
+		} else if err != nil {
+			return err
 		}
 
-		// 2) insertar el mensaje
-		if msg.ID == uuid.Nil {
-			msg.ID = uuid.New()
-		}
-		js, _ := json.Marshal(msg)
-		if err := txn.Set(msgKey, js); err != nil {
+		// 2) subir el HWM si se queda por debajo; nunca bajarlo: un offset
+		// antiguo (importado o reparado) no descarta lo que hay detrás
+		curNext := uint64(0)
+		if item, err := txn.Get(hwmKey); err == nil {
+			val, _ := item.ValueCopy(nil)
+			curNext = b2u64(val)
+		} else if err != badger.ErrKeyNotFound {
 			return err
 		}
-
-		// 3) dejar el HWM persistido al día
-		next := msg.Offset + 1
-		_ = txn.Set(hwmKey, u64(next))
-		cluster.TrackNextOffset(msg.Topic, msg.PartID, next) // RAM
+		if next := msg.Offset + 1; next > curNext {
+			if err := txn.Set(hwmKey, u64(next)); err != nil {
+				return err
+			}
+			cluster.TrackNextOffset(msg.Topic, msg.PartID, next) // RAM
+		}
 		return nil
-	})
+	}))
 }
 
 func (s *Store) Read(_ context.Context, topic string, part int, from uint64, max int) ([]model.Message, error) {
-	prefix := key(msgPrefix, topic, strconv.Itoa(part))
-	start := key(msgPrefix, topic, strconv.Itoa(part), strconv.FormatUint(from, 10))
+	prefix := partPrefix(topic, part)
+	start := msgKey(topic, part, from)
 	out := make([]model.Message, 0, max)
 
 	err := s.db.View(func(txn *badger.Txn) error {
@@ -156,11 +265,132 @@ func (s *Store) Read(_ context.Context, topic string, part int, from uint64, max
 		}
 		return nil
 	})
-	return out, err
+	return out, badgererr.Wrap(err)
 }
 
 func (s *Store) Delete(context.Context, string, int, uint64) error { return nil }
 
+// Truncate borra los mensajes con offset >= from y deja el HWM en from.
+// Lo usa un seguidor para descartar entradas que el líder no tiene.
+func (s *Store) Truncate(_ context.Context, topic string, part int, from uint64) error {
+	prefix := partPrefix(topic, part)
+	var stale [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Seek(msgKey(topic, part, from)); it.Valid(); it.Next() {
+			stale = append(stale, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+	if err != nil {
+		return badgererr.Wrap(err)
+	}
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, k := range stale {
+		if err := wb.Delete(k); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	if err := wb.Set(key(hwmPrefix, topic, strconv.Itoa(part)), u64(from)); err != nil {
+		return badgererr.Wrap(err)
+	}
+	if err := wb.Flush(); err != nil {
+		return badgererr.Wrap(err)
+	}
+	cluster.ResetNextOffset(topic, part, from)
+	return nil
+}
+
+// ReplaceRange deja en [from, to) de la partición exactamente los msgs
+// de ese rango (los de fuera se ignoran): borra lo que había y escribe
+// los nuevos en una sola transacción, así un lector nunca ve el rango a
+// medias. Es la reparación de la anti-entropía (cluster.RangeStore); el
+// HWM no cambia aunque msgs llegue más allá de él.
+func (s *Store) ReplaceRange(_ context.Context, topic string, part int, from, to uint64, msgs []model.Message) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: partPrefix(topic, part)})
+		var stale [][]byte
+		for it.Seek(msgKey(topic, part, from)); it.Valid(); it.Next() {
+			k := it.Item().KeyCopy(nil)
+			if bytes.Compare(k, msgKey(topic, part, to)) >= 0 {
+				break
+			}
+			stale = append(stale, k)
+		}
+		it.Close()
+		for _, k := range stale {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, m := range msgs {
+			if m.Offset < from || m.Offset >= to {
+				continue
+			}
+			m.Topic, m.PartID = topic, part
+			js, _ := json.Marshal(m)
+			if err := txn.Set(msgKey(topic, part, m.Offset), js); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// PartitionStats cuenta mensajes y bytes de la partición; EndOffset sale
+// del HWM persistido (h:) y StartOffset del menor offset presente.
+func (s *Store) PartitionStats(_ context.Context, topic string, part int) (model.PartitionStats, error) {
+	partStr := strconv.Itoa(part)
+	st := model.PartitionStats{Partition: part}
+	err := s.db.View(func(txn *badger.Txn) error {
+		if item, err := txn.Get(key(hwmPrefix, topic, partStr)); err == nil {
+			val, _ := item.ValueCopy(nil)
+			st.EndOffset = b2u64(val)
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		prefix := partPrefix(topic, part)
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		first := true
+		for it.Rewind(); it.Valid(); it.Next() {
+			off, err := strconv.ParseUint(string(it.Item().Key()[len(prefix):]), 10, 64)
+			if err != nil {
+				continue
+			}
+			if first || off < st.StartOffset {
+				st.StartOffset, first = off, false
+			}
+			st.Messages++
+			st.Bytes += it.Item().ValueSize()
+		}
+		if first {
+			st.StartOffset = st.EndOffset // partición vacía
+		}
+		return nil
+	})
+	return st, badgererr.Wrap(err)
+}
+
+func (s *Store) HighWatermark(_ context.Context, topic string, part int) (uint64, error) {
+	var next uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key(hwmPrefix, topic, strconv.Itoa(part)))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		val, _ := item.ValueCopy(nil)
+		next = b2u64(val)
+		return nil
+	})
+	return next, badgererr.Wrap(err)
+}
+
 // ------------------------------------------------------------------
 // Colas
 // ------------------------------------------------------------------
@@ -173,44 +403,111 @@ func (s *Store) Enqueue(_ context.Context, q string, msg model.Message) error {
 	}
 	js, _ := json.Marshal(msg)
 	seq := uuid.New().String()
-	return s.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
 		return txn.Set(key(qPrefix, q, seq), js)
-	})
+	}))
 }
 
 func (s *Store) Dequeue(_ context.Context, q string) (*model.Message, error) {
 	var res *model.Message
-	var firstKey []byte
+	err := s.db.Update(func(txn *badger.Txn) (err error) {
+		res, err = s.takeHead(txn, q, time.Now().Add(s.opts.InFlightTTL))
+		return err
+	})
+	return res, badgererr.Wrap(err)
+}
 
-	err := s.db.Update(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.IteratorOptions{Prefix: key(qPrefix, q)})
-		defer it.Close()
-		it.Rewind()
-		if !it.Valid() {
-			return nil // empty queue
-		}
-		firstKey = it.Item().KeyCopy(nil)
-		val, _ := it.Item().ValueCopy(nil)
-		var m model.Message
-		if err := json.Unmarshal(val, &m); err != nil {
-			return err
-		}
-		res = &m
+// takeHead pasa la cabeza de la cola q a en vuelo hasta deadline; nil si
+// la cola está vacía.
+func (s *Store) takeHead(txn *badger.Txn, q string, deadline time.Time) (*model.Message, error) {
+	// con los dos puntos, para no mezclar la cola jobs con jobs2
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: append(key(qPrefix, q), ':')})
+	defer it.Close()
+	it.Rewind()
+	if !it.Valid() {
+		return nil, nil // empty queue
+	}
+	head := it.Item().KeyCopy(nil)
+	val, _ := it.Item().ValueCopy(nil)
+	var m model.Message
+	if err := json.Unmarshal(val, &m); err != nil {
+		return nil, err
+	}
 
-		// move to in‑flight
-		if err := txn.Delete(firstKey); err != nil {
-			return err
-		}
-		exp := u64(uint64(time.Now().Add(30 * time.Second).Unix()))
-		return txn.Set(key(infPrefix, q, m.ID.String()), exp)
-	})
-	return res, err
+	// move to in‑flight
+	if err := txn.Delete(head); err != nil {
+		return nil, err
+	}
+	rec, _ := json.Marshal(inflightRec{Exp: deadline.Unix(), Msg: m})
+	return &m, txn.Set(key(infPrefix, q, m.ID.String()), rec)
+}
+
+// inflightRec es el valor de f:<queue>:<uuid>: el plazo (Unix) y el
+// mensaje, que vuelve entero a la cola si caduca.
+type inflightRec struct {
+	Exp int64         `json:"exp"`
+	Msg model.Message `json:"msg"`
+}
+
+// splitInflight separa f::<queue>:<uuid> en cola e id.
+func splitInflight(k []byte) (q, id string, ok bool) {
+	rest, ok := strings.CutPrefix(string(k), join(infPrefix, ""))
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndexByte(rest, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// readInflight acepta también el formato antiguo: sólo el plazo (uint64).
+// Ése no guarda el mensaje, así que whole es false: al caducar se descarta
+// en vez de volver a la cola como una entrada sin contenido.
+func readInflight(id string, val []byte) (rec inflightRec, whole bool) {
+	if len(val) == 8 {
+		rec.Exp = int64(b2u64(val))
+		rec.Msg.ID, _ = uuid.Parse(id)
+		return rec, false
+	}
+	_ = json.Unmarshal(val, &rec)
+	return rec, true
 }
 
 func (s *Store) Ack(_ context.Context, q string, id uuid.UUID) error {
-	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete(key(infPrefix, q, id.String()))
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error { return ack(txn, q, id) }))
+}
+
+func ack(txn *badger.Txn, q string, id uuid.UUID) error {
+	k := key(infPrefix, q, id.String())
+	if _, err := txn.Get(k); err == badger.ErrKeyNotFound {
+		return errs.NotFound("message %s not in flight on queue %q", id, q)
+	} else if err != nil {
+		return err
+	}
+	return txn.Delete(k)
+}
+
+// QueueStats cuenta pendientes (q:) y en vuelo (f:) de la cola.
+func (s *Store) QueueStats(_ context.Context, q string) (model.QueueStats, error) {
+	var st model.QueueStats
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: append(key(qPrefix, q), ':')})
+		for it.Rewind(); it.Valid(); it.Next() {
+			st.Depth++
+			st.Bytes += it.Item().ValueSize()
+		}
+		it.Close()
+
+		it = txn.NewIterator(badger.IteratorOptions{Prefix: append(key(infPrefix, q), ':')})
+		for it.Rewind(); it.Valid(); it.Next() {
+			st.InFlight++
+		}
+		it.Close()
+		return nil
 	})
+	return st, badgererr.Wrap(err)
 }
 
 // ------------------------------------------------------------------
@@ -220,44 +517,54 @@ func (s *Store) Ack(_ context.Context, q string, id uuid.UUID) error {
 // CommitOffset guarda el offset para un grupo de consumidores.
 func (s *Store) CommitOffset(ctx context.Context, group, topic string, part int, offset uint64) error {
 	offsetKey := key(offsetPrefix, group, topic, strconv.Itoa(part))
-	return s.db.Update(func(txn *badger.Txn) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
 		return txn.Set(offsetKey, u64(offset))
-	})
+	}))
 }
 
 // ------------------------------------------------------------------
 // Re‑enqueue loop (handles TTL)
 // ------------------------------------------------------------------
 
-func (s *Store) StartRequeueLoop() {
+// StartRequeueLoop devuelve a la cola los mensajes en vuelo caducados
+// hasta que se cancele ctx; wg cuenta el bucle.
+func (s *Store) StartRequeueLoop(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
 	go func() {
-		tick := time.NewTicker(5 * time.Second)
-		for range tick.C {
-			now := uint64(time.Now().Unix())
+		defer wg.Done()
+		tick := time.NewTicker(s.opts.RequeueInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+			now := time.Now().Unix()
 			_ = s.db.Update(func(txn *badger.Txn) error {
 				it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(infPrefix)})
 				defer it.Close()
 				for it.Rewind(); it.Valid(); it.Next() {
-					k := string(it.Item().Key())
-					expBytes, _ := it.Item().ValueCopy(nil)
-					exp := b2u64(expBytes)
-					if now <= exp {
+					q, id, ok := splitInflight(it.Item().Key())
+					if !ok {
+						_ = txn.Delete(it.Item().KeyCopy(nil)) // corrupted
+						continue
+					}
+					val, _ := it.Item().ValueCopy(nil)
+					rec, whole := readInflight(id, val)
+					if now <= rec.Exp {
 						continue
 					}
-					parts := strings.Split(k, ":")
-					if len(parts) != 3 {
-						_ = txn.Delete(it.Item().Key()) // corrupted
+					if !whole {
+						_ = txn.Delete(it.Item().KeyCopy(nil))
 						continue
 					}
-					q, id := parts[1], parts[2]
-					// reinserta stub (en real se movería payload original)
-					stub := model.Message{ID: uuid.MustParse(id), Payload: []byte("expired")}
-					js, _ := json.Marshal(stub)
+					js, _ := json.Marshal(rec.Msg)
 					seq := uuid.New().String()
 					if err := txn.Set(key(qPrefix, q, seq), js); err != nil {
 						return err
 					}
-					_ = txn.Delete(it.Item().Key())
+					_ = txn.Delete(it.Item().KeyCopy(nil))
 				}
 				return nil
 			})
@@ -265,8 +572,392 @@ func (s *Store) StartRequeueLoop() {
 	}()
 }
 
+// ------------------------------------------------------------------
+// Log replicado de colas (cluster.QueueLogStore)
+// ------------------------------------------------------------------
+
+// applyQueue ejecuta fn y guarda at+1 como progreso de model.QueueTopic en
+// la misma transacción; si la entrada ya se aplicó no hace nada.
+func (s *Store) applyQueue(at uint64, fn func(txn *badger.Txn) error) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
+		k := key(applPrefix, model.QueueTopic)
+		if item, err := txn.Get(k); err == nil {
+			val, _ := item.ValueCopy(nil)
+			if at < b2u64(val) {
+				return errApplied
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+		return txn.Set(k, u64(at+1))
+	}))
+}
+
+// errApplied aborta la transacción de una entrada ya aplicada.
+var errApplied = errors.New("entry already applied")
+
+func skipApplied(err error) error {
+	if errors.Is(err, errApplied) {
+		return nil
+	}
+	return err
+}
+
+// ApplyEnqueue usa el offset de la entrada como secuencia: el orden de la
+// cola es el del log en todos los nodos.
+func (s *Store) ApplyEnqueue(at uint64, q string, msg model.Message) error {
+	js, _ := json.Marshal(msg)
+	return skipApplied(s.applyQueue(at, func(txn *badger.Txn) error {
+		return txn.Set(key(qPrefix, q, offStr(at)), js)
+	}))
+}
+
+func (s *Store) ApplyDequeue(at uint64, q string, deadline time.Time) (*model.Message, error) {
+	var res *model.Message
+	err := s.applyQueue(at, func(txn *badger.Txn) (err error) {
+		res, err = s.takeHead(txn, q, deadline)
+		return err
+	})
+	return res, skipApplied(err)
+}
+
+// ApplyAck también avanza el progreso si el mensaje no estaba en vuelo:
+// el NotFound es el resultado de la entrada, no un fallo al aplicarla.
+func (s *Store) ApplyAck(at uint64, q string, id uuid.UUID) error {
+	var res error
+	err := s.applyQueue(at, func(txn *badger.Txn) error {
+		if err := ack(txn, q, id); errors.Is(err, errs.ErrNotFound) {
+			res = err
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err = skipApplied(err); err != nil {
+		return err
+	}
+	return res
+}
+
+func (s *Store) ApplyRequeue(at uint64, q string, id uuid.UUID, now time.Time) error {
+	return skipApplied(s.applyQueue(at, func(txn *badger.Txn) error {
+		k := key(infPrefix, q, id.String())
+		item, err := txn.Get(k)
+		if err == badger.ErrKeyNotFound {
+			return nil // ya confirmado
+		} else if err != nil {
+			return err
+		}
+		val, _ := item.ValueCopy(nil)
+		rec, whole := readInflight(id.String(), val)
+		if rec.Exp >= now.Unix() {
+			return nil // se volvió a entregar después de decidir el reencolado
+		}
+		if !whole {
+			return txn.Delete(k)
+		}
+		js, _ := json.Marshal(rec.Msg)
+		if err := txn.Set(key(qPrefix, q, offStr(at)), js); err != nil {
+			return err
+		}
+		return txn.Delete(k)
+	}))
+}
+
+func (s *Store) Expired(now time.Time) ([]cluster.QueueRef, error) {
+	var out []cluster.QueueRef
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(infPrefix)})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			q, sid, ok := splitInflight(it.Item().Key())
+			id, err := uuid.Parse(sid)
+			if !ok || err != nil {
+				continue
+			}
+			val, _ := it.Item().ValueCopy(nil)
+			if rec, _ := readInflight(sid, val); rec.Exp < now.Unix() {
+				out = append(out, cluster.QueueRef{Queue: q, ID: id})
+			}
+		}
+		return nil
+	})
+	return out, badgererr.Wrap(err)
+}
+
+// queueState son los prefijos del estado de las colas: pendientes y en vuelo.
+var queueState = []string{join(qPrefix, ""), join(infPrefix, "")}
+
+// SnapshotQueues vuelca, en una sola transacción de lectura, los mensajes
+// pendientes y en vuelo de todas las colas.
+func (s *Store) SnapshotQueues() ([]byte, error) {
+	kv := map[string][]byte{}
+	err := s.db.View(func(txn *badger.Txn) error {
+		for _, p := range queueState {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(p), PrefetchValues: true})
+			for it.Rewind(); it.Valid(); it.Next() {
+				v, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					it.Close()
+					return err
+				}
+				kv[string(it.Item().Key())] = v
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, badgererr.Wrap(err)
+	}
+	return json.Marshal(kv)
+}
+
+// RestoreQueues sustituye las colas por las que volcó SnapshotQueues. No
+// es atómico: si se interrumpe, se vuelve a restaurar el mismo volcado.
+func (s *Store) RestoreQueues(raw []byte) error {
+	var kv map[string][]byte
+	if err := json.Unmarshal(raw, &kv); err != nil {
+		return errs.Invalid("malformed queue snapshot: %v", err)
+	}
+	var stale [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		for _, p := range queueState {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(p)})
+			for it.Rewind(); it.Valid(); it.Next() {
+				if _, keep := kv[string(it.Item().Key())]; !keep {
+					stale = append(stale, it.Item().KeyCopy(nil))
+				}
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return badgererr.Wrap(err)
+	}
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, k := range stale {
+		if err := wb.Delete(k); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	for k, v := range kv {
+		if err := wb.Set([]byte(k), v); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	return badgererr.Wrap(wb.Flush())
+}
+
+// ------------------------------------------------------------------
+// Término, voto y votantes del log replicado (cluster.TermStore)
+// ------------------------------------------------------------------
+
+type termRec struct {
+	Epoch uint64 `json:"epoch"`
+	Voted string `json:"voted"`
+}
+
+// Replicated indica si este directorio es de un nodo de clúster: tiene el
+// término de algún log replicado.
+func (s *Store) Replicated() (bool, error) {
+	found := false
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(termPrefix)})
+		defer it.Close()
+		it.Rewind()
+		found = it.Valid()
+		return nil
+	})
+	return found, badgererr.Wrap(err)
+}
+
+func (s *Store) LoadTerm(topic string, part int) (epoch uint64, voted string, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key(termPrefix, topic, strconv.Itoa(part)))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var r termRec
+		if err := item.Value(func(v []byte) error { return json.Unmarshal(v, &r) }); err != nil {
+			return err
+		}
+		epoch, voted = r.Epoch, r.Voted
+		return nil
+	})
+	return epoch, voted, badgererr.Wrap(err)
+}
+
+func (s *Store) SaveTerm(topic string, part int, epoch uint64, voted string) error {
+	js, _ := json.Marshal(termRec{Epoch: epoch, Voted: voted})
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key(termPrefix, topic, strconv.Itoa(part)), js)
+	}))
+}
+
+func (s *Store) LoadConfig(topic string, part int) ([]byte, error) {
+	var raw []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key(confPrefix, topic, strconv.Itoa(part)))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		raw, err = item.ValueCopy(nil)
+		return err
+	})
+	return raw, badgererr.Wrap(err)
+}
+
+func (s *Store) SaveConfig(topic string, part int, raw []byte) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key(confPrefix, topic, strconv.Itoa(part)), raw)
+	}))
+}
+
+// ------------------------------------------------------------------
+// Progreso y compactación de los logs aplicados (cluster.AppliedStore)
+// ------------------------------------------------------------------
+
+func (s *Store) LoadApplied(name string) (uint64, error) {
+	var next uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key(applPrefix, name))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		val, _ := item.ValueCopy(nil)
+		next = b2u64(val)
+		return nil
+	})
+	return next, badgererr.Wrap(err)
+}
+
+func (s *Store) SaveApplied(name string, next uint64) error {
+	return badgererr.Wrap(s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key(applPrefix, name), u64(next))
+	}))
+}
+
+// Compact borra las entradas del log name por debajo de before (ya las
+// recoge un snapshot); el HWM no cambia.
+func (s *Store) Compact(name string, before uint64) error {
+	end := msgKey(name, 0, before)
+	var stale [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: partPrefix(name, 0)})
+		defer it.Close()
+		for it.Rewind(); it.Valid() && bytes.Compare(it.Item().Key(), end) < 0; it.Next() {
+			stale = append(stale, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+	if err != nil || len(stale) == 0 {
+		return badgererr.Wrap(err)
+	}
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, k := range stale {
+		if err := wb.Delete(k); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	return badgererr.Wrap(wb.Flush())
+}
+
+// ------------------------------------------------------------------
+// Retención por número de mensajes
+// ------------------------------------------------------------------
+
+// StartRetentionLoop borra, en cada partición de los tópicos de Retain,
+// los mensajes por debajo de HWM-N. No hace nada si Retain está vacío.
+func (s *Store) StartRetentionLoop(ctx context.Context, wg *sync.WaitGroup) {
+	if len(s.opts.Retain) == 0 {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(s.opts.RetentionInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				_ = s.trim()
+			}
+		}
+	}()
+}
+
+func (s *Store) trim() error {
+	var stale [][]byte
+	hp := join(hwmPrefix, "") // las claves son h::<topic>:<part>
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(hp)})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			tp := strings.TrimPrefix(string(it.Item().Key()), hp) // topic:part
+			i := strings.LastIndexByte(tp, ':')
+			if i < 0 || strings.HasPrefix(tp, model.InternalTopicPrefix) {
+				continue // los internos (auditoría, logs replicados) no se recortan
+			}
+			part, err := strconv.Atoi(tp[i+1:])
+			if err != nil {
+				continue
+			}
+			keep := s.opts.Retain[tp[:i]]
+			val, _ := it.Item().ValueCopy(nil)
+			hwm := b2u64(val)
+			if keep == 0 || hwm <= keep {
+				continue
+			}
+			cut := hwm - keep
+			prefix := partPrefix(tp[:i], part)
+			mit := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+			for mit.Rewind(); mit.Valid(); mit.Next() {
+				off, err := strconv.ParseUint(string(mit.Item().Key()[len(prefix):]), 10, 64)
+				if err == nil && off < cut {
+					stale = append(stale, mit.Item().KeyCopy(nil))
+				}
+			}
+			mit.Close()
+		}
+		return nil
+	})
+	if err != nil || len(stale) == 0 {
+		return badgererr.Wrap(err)
+	}
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, k := range stale {
+		if err := wb.Delete(k); err != nil {
+			return badgererr.Wrap(err)
+		}
+	}
+	return badgererr.Wrap(wb.Flush())
+}
+
 // ------------------------------------------------------------------
 // Interface guard
 // ------------------------------------------------------------------
 
-var _ outbound.MessageStore = (*Store)(nil)
+var (
+	_ outbound.MessageStore  = (*Store)(nil)
+	_ outbound.HealthChecker = (*Store)(nil)
+	_ cluster.TermStore      = (*Store)(nil)
+	_ cluster.AppliedStore   = (*Store)(nil)
+	_ cluster.QueueLogStore  = (*Store)(nil)
+	_ cluster.RangeStore     = (*Store)(nil)
+)
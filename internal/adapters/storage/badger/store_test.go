@@ -0,0 +1,73 @@
+package badgerstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+func openStore(t *testing.T, o Options) *Store {
+	t.Helper()
+	s, err := Open(t.TempDir(), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func appendN(t *testing.T, s *Store, topic string, part, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := s.Append(context.Background(), model.Message{Topic: topic, PartID: part, Payload: []byte("x")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTrimKeepsTheLastNMessages(t *testing.T) {
+	ctx := context.Background()
+	o := DefaultOptions()
+	o.Retain = map[string]uint64{"orders": 2, "__audit": 1}
+	s := openStore(t, o)
+
+	appendN(t, s, "orders", 0, 5)
+	appendN(t, s, "orders", 1, 1)   // por debajo de N: intacta
+	appendN(t, s, "payments", 0, 5) // sin retención
+	appendN(t, s, "__audit", 0, 5)  // interno: nunca se recorta
+
+	if err := s.trim(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		topic             string
+		part              int
+		start, end, count uint64
+	}{
+		{"orders", 0, 3, 5, 2},
+		{"orders", 1, 0, 1, 1},
+		{"payments", 0, 0, 5, 5},
+		{"__audit", 0, 0, 5, 5},
+	}
+	for _, w := range want {
+		st, err := s.PartitionStats(ctx, w.topic, w.part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.StartOffset != w.start || st.EndOffset != w.end || uint64(st.Messages) != w.count {
+			t.Errorf("%s:%d = start %d end %d messages %d, want %d %d %d", w.topic, w.part,
+				st.StartOffset, st.EndOffset, st.Messages, w.start, w.end, w.count)
+		}
+	}
+
+	// lo recortado ya no se lee; lo que queda sí, con su offset
+	msgs, err := s.Read(ctx, "orders", 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Offset != 3 || msgs[1].Offset != 4 {
+		t.Fatalf("read after trim: %+v", msgs)
+	}
+}
@@ -2,12 +2,12 @@ package storage
 
 import (
 	"context"
-	"errors"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
 	"github.com/google/uuid"
 )
@@ -23,7 +23,14 @@ type partLog struct {
 type queue struct {
 	mu       sync.Mutex
 	items    []model.Message
-	inFlight map[uuid.UUID]time.Time
+	inFlight map[uuid.UUID]inflight
+}
+
+// inflight guarda el mensaje entregado entero: si caduca vuelve a la cola
+// tal cual, no como un marcador que QueueStats contaría como pendiente.
+type inflight struct {
+	exp time.Time
+	msg model.Message
 }
 
 // ---------- memoryStore -----------------------------------------
@@ -86,13 +93,43 @@ func (m *memoryStore) Read(_ context.Context, topic string, part int, from uint6
 
 func (m *memoryStore) Delete(_ context.Context, _ string, _ int, _ uint64) error { return nil }
 
+func (m *memoryStore) PartitionStats(_ context.Context, topic string, part int) (model.PartitionStats, error) {
+	st := model.PartitionStats{Partition: part}
+	m.mu.RLock()
+	pl, ok := m.parts[topic+":"+strconv.Itoa(part)]
+	m.mu.RUnlock()
+	if !ok {
+		return st, nil
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	st.EndOffset = pl.next
+	st.Messages = int64(len(pl.events))
+	for _, e := range pl.events {
+		st.Bytes += int64(len(e.Payload))
+	}
+	return st, nil
+}
+
+func (m *memoryStore) HighWatermark(_ context.Context, topic string, part int) (uint64, error) {
+	m.mu.RLock()
+	pl, ok := m.parts[topic+":"+strconv.Itoa(part)]
+	m.mu.RUnlock()
+	if !ok {
+		return 0, nil
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.next, nil
+}
+
 // ---------- COLAS ------------------------------------------------
 
 func (m *memoryStore) queue(q string) *queue {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if _, ok := m.queues[q]; !ok {
-		m.queues[q] = &queue{inFlight: make(map[uuid.UUID]time.Time)}
+		m.queues[q] = &queue{inFlight: make(map[uuid.UUID]inflight)}
 		// rutina de requeue
 		go m.requeueLoop(q, m.queues[q])
 	}
@@ -113,7 +150,7 @@ func (m *memoryStore) Enqueue(_ context.Context, q string, msg model.Message) er
 func (m *memoryStore) Dequeue(_ context.Context, q string) (*model.Message, error) {
 	qu, ok := m.queues[q]
 	if !ok {
-		return nil, errors.New("queue not found")
+		return nil, errs.NotFound("queue %q", q)
 	}
 	qu.mu.Lock()
 	defer qu.mu.Unlock()
@@ -122,14 +159,14 @@ func (m *memoryStore) Dequeue(_ context.Context, q string) (*model.Message, erro
 	}
 	msg := qu.items[0]
 	qu.items = qu.items[1:]
-	qu.inFlight[msg.ID] = time.Now().Add(30 * time.Second)
+	qu.inFlight[msg.ID] = inflight{exp: time.Now().Add(30 * time.Second), msg: msg}
 	return &msg, nil
 }
 
 func (m *memoryStore) Ack(_ context.Context, q string, id uuid.UUID) error {
 	qu, ok := m.queues[q]
 	if !ok {
-		return errors.New("queue not found")
+		return errs.NotFound("queue %q", q)
 	}
 	qu.mu.Lock()
 	defer qu.mu.Unlock()
@@ -137,16 +174,32 @@ func (m *memoryStore) Ack(_ context.Context, q string, id uuid.UUID) error {
 	return nil
 }
 
+func (m *memoryStore) QueueStats(_ context.Context, q string) (model.QueueStats, error) {
+	m.mu.RLock()
+	qu, ok := m.queues[q]
+	m.mu.RUnlock()
+	if !ok {
+		return model.QueueStats{}, errs.NotFound("queue %q", q)
+	}
+	qu.mu.Lock()
+	defer qu.mu.Unlock()
+	st := model.QueueStats{Depth: len(qu.items), InFlight: len(qu.inFlight)}
+	for _, it := range qu.items {
+		st.Bytes += int64(len(it.Payload))
+	}
+	return st, nil
+}
+
 // -- background goroutine re‑enqueues expired in‑flight messages ---
 func (m *memoryStore) requeueLoop(name string, qu *queue) {
 	ticker := time.NewTicker(5 * time.Second)
 	for range ticker.C {
 		qu.mu.Lock()
 		now := time.Now()
-		for id, exp := range qu.inFlight {
-			if now.After(exp) {
+		for id, f := range qu.inFlight {
+			if now.After(f.exp) {
 				// reinserta al final
-				qu.items = append(qu.items, model.Message{ID: id, Payload: []byte("expired")})
+				qu.items = append(qu.items, f.msg)
 				delete(qu.inFlight, id)
 			}
 		}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/google/uuid"
+)
+
+var userName = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// minPassword es la longitud mínima de una contraseña.
+const minPassword = 8
+
+type accessUC struct {
+	meta outbound.MetaStore
+	auth outbound.AuthStore
+}
+
+func NewAccess(meta outbound.MetaStore, auth outbound.AuthStore) inbound.Access {
+	return &accessUC{meta: meta, auth: auth}
+}
+
+func (a *accessUC) Login(ctx context.Context, user, password string) (string, error) {
+	return a.auth.Login(ctx, user, password)
+}
+
+/*──────────  usuarios  ──────────*/
+
+func (a *accessUC) ListUsers(ctx context.Context) ([]model.User, error) {
+	list, err := a.meta.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i] = list[i].Redacted()
+	}
+	slices.SortFunc(list, func(x, y model.User) int { return strings.Compare(x.Name, y.Name) })
+	return list, nil
+}
+
+func (a *accessUC) CreateUser(ctx context.Context, name, password string, admin bool, creator string) error {
+	if !userName.MatchString(name) {
+		return errs.Invalid("user name must match %s", userName)
+	}
+	if _, err := a.meta.GetUser(ctx, name); err == nil {
+		return errs.AlreadyExists("user %q", name)
+	} else if errs.Kind(err) != errs.ErrNotFound {
+		return err
+	}
+	hash, err := a.hash(password)
+	if err != nil {
+		return err
+	}
+	return a.meta.PutUser(ctx, model.User{Name: name, Admin: admin, Hash: hash,
+		Creator: creator, CreatedAt: time.Now().UTC()})
+}
+
+func (a *accessUC) UpdateUser(ctx context.Context, name string, password *string, admin *bool) error {
+	u, err := a.meta.GetUser(ctx, name)
+	if err != nil {
+		return err
+	}
+	if password != nil {
+		if u.Hash, err = a.hash(*password); err != nil {
+			return err
+		}
+	}
+	if admin != nil {
+		u.Admin = *admin
+	}
+	return a.meta.PutUser(ctx, u)
+}
+
+func (a *accessUC) DeleteUser(ctx context.Context, name string) error {
+	return a.meta.DeleteUser(ctx, name)
+}
+
+func (a *accessUC) hash(password string) (string, error) {
+	if len(password) < minPassword {
+		return "", errs.Invalid("password must have at least %d characters", minPassword)
+	}
+	return a.auth.Hash(password)
+}
+
+/*──────────  ACLs  ──────────*/
+
+func (a *accessUC) ListACLs(ctx context.Context) ([]model.ACL, error) {
+	list, err := a.meta.ListACLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(list, func(x, y model.ACL) int { return x.CreatedAt.Compare(y.CreatedAt) })
+	return list, nil
+}
+
+func (a *accessUC) AddACL(ctx context.Context, acl model.ACL, creator string) (model.ACL, error) {
+	if acl.Principal == "" {
+		return model.ACL{}, errs.Invalid("acl needs a principal (user or *)")
+	}
+	kind, pattern, _ := strings.Cut(acl.Resource, ":")
+	if kind != model.ScopeTopic && kind != model.ScopeQueue || pattern == "" {
+		return model.ACL{}, errs.Invalid("acl resource must be topic:<pattern> or queue:<pattern> (got %q)", acl.Resource)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return model.ACL{}, errs.Invalid("resource pattern %q: %v", pattern, err)
+	}
+	if len(acl.Ops) == 0 {
+		return model.ACL{}, errs.Invalid("acl needs at least one operation")
+	}
+	for _, op := range acl.Ops {
+		if op != model.OpRead && op != model.OpWrite && op != model.OpManage {
+			return model.ACL{}, errs.Invalid("unknown operation %q (read, write or manage)", op)
+		}
+	}
+	acl.ID, acl.Creator, acl.CreatedAt = uuid.NewString(), creator, time.Now().UTC()
+	return acl, a.meta.PutACL(ctx, acl)
+}
+
+func (a *accessUC) DeleteACL(ctx context.Context, id string) error {
+	return a.meta.DeleteACL(ctx, id)
+}
@@ -0,0 +1,62 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/auth"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/adapters/meta"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/app/usecase"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+func TestAccessValidatesUsersAndACLs(t *testing.T) {
+	ctx := context.Background()
+	cat := meta.NewMemoryCatalog()
+	acc := usecase.NewAccess(cat, auth.NewAccounts(auth.NewInMemoryWith(nil, nil, false), cat, "k", time.Hour))
+
+	if err := acc.CreateUser(ctx, "bad name", "long-enough", false, "admin"); errs.Kind(err) != errs.ErrInvalid {
+		t.Errorf("bad name: %v", err)
+	}
+	if err := acc.CreateUser(ctx, "bob", "short", false, "admin"); errs.Kind(err) != errs.ErrInvalid {
+		t.Errorf("short password: %v", err)
+	}
+	if err := acc.CreateUser(ctx, "bob", "long-enough", false, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := acc.CreateUser(ctx, "bob", "long-enough", false, "admin"); errs.Kind(err) != errs.ErrAlreadyExists {
+		t.Errorf("duplicate user: %v", err)
+	}
+	users, err := acc.ListUsers(ctx)
+	if err != nil || len(users) != 1 || users[0].Hash != "" {
+		t.Fatalf("ListUsers = %+v, %v (hash must be redacted)", users, err)
+	}
+	if _, err := acc.Login(ctx, "bob", "long-enough"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	bad := []model.ACL{
+		{Resource: "topic:x", Ops: []string{model.OpRead}},                   // sin principal
+		{Principal: "bob", Resource: "group:x", Ops: []string{model.OpRead}}, // tipo desconocido
+		{Principal: "bob", Resource: "topic:[", Ops: []string{model.OpRead}}, // patrón inválido
+		{Principal: "bob", Resource: "topic:x"},                              // sin operaciones
+		{Principal: "bob", Resource: "topic:x", Ops: []string{"delete"}},
+	}
+	for _, a := range bad {
+		if _, err := acc.AddACL(ctx, a, "admin"); errs.Kind(err) != errs.ErrInvalid {
+			t.Errorf("AddACL(%+v) = %v, want invalid", a, err)
+		}
+	}
+	a, err := acc.AddACL(ctx, model.ACL{Principal: "bob", Resource: "topic:logs-*", Ops: []string{model.OpRead}}, "admin")
+	if err != nil || a.ID == "" || a.Creator != "admin" {
+		t.Fatalf("AddACL = %+v, %v", a, err)
+	}
+	if err := acc.DeleteACL(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := acc.ListACLs(ctx); len(list) != 0 {
+		t.Fatalf("ACLs after delete: %+v", list)
+	}
+}
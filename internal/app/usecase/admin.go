@@ -2,33 +2,299 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
 
+	cl "github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/service"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/export"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
 )
 
-type adminUC struct{ meta outbound.MetaStore }
+type adminUC struct {
+	meta  outbound.MetaStore
+	msg   outbound.MessageStore
+	quota outbound.QuotaStore // nil → sin cuotas
+	audit outbound.AuditLog   // nil → sin auditoría
+	rf    int                 // réplicas por partición por defecto (0 → todos los nodos)
+	bak   outbound.Backup
+	cons  *cl.Consensus // nil → se exporta todo el log local
+	fwd   *cl.Forwarder // nil → las particiones de otros nodos dan 503
+}
+
+func NewAdmin(meta outbound.MetaStore, msg outbound.MessageStore, quota outbound.QuotaStore,
+	audit outbound.AuditLog, rf int, bak outbound.Backup, cons *cl.Consensus, fwd *cl.Forwarder) inbound.Admin {
 
-func NewAdmin(meta outbound.MetaStore) inbound.Admin { return &adminUC{meta: meta} }
+	return &adminUC{meta: meta, msg: msg, quota: quota, audit: audit, rf: rf, bak: bak, cons: cons, fwd: fwd}
+}
 
 // TÓPICOS
-func (a *adminUC) CreateTopic(ctx context.Context, n string, p int, u string) error {
-	return a.meta.CreateTopic(ctx, n, p, u)
+func (a *adminUC) CreateTopic(ctx context.Context, n string, p, rf int, u string) error {
+	if !(model.Topic{Name: n, Partitions: p}).IsValid() {
+		return errs.Invalid("topic needs a name and at least one partition")
+	}
+	if strings.HasPrefix(n, model.InternalTopicPrefix) {
+		return errs.Invalid("topic names starting with %q are reserved", model.InternalTopicPrefix)
+	}
+	replicas, err := a.place(n, p, rf)
+	if err != nil {
+		return err
+	}
+	if err := a.reserve(ctx, u, model.ScopeTopic); err != nil {
+		return err
+	}
+	if err := a.meta.CreateTopic(ctx, n, p, replicas, u); err != nil {
+		a.release(ctx, u, model.ScopeTopic)
+		return err
+	}
+	return nil
 }
 func (a *adminUC) ListTopics(ctx context.Context) ([]string, error) {
 	return a.meta.ListTopics(ctx)
 }
 func (a *adminUC) DeleteTopic(ctx context.Context, n, u string) error {
-	return a.meta.DeleteTopic(ctx, n, u)
+	if err := a.meta.DeleteTopic(ctx, n, u); err != nil {
+		return err
+	}
+	a.release(ctx, u, model.ScopeTopic)
+	return nil
+}
+func (a *adminUC) DescribeTopic(ctx context.Context, n string) (model.TopicDescription, error) {
+	t, err := a.meta.DescribeTopic(ctx, n)
+	if err != nil {
+		return model.TopicDescription{}, err
+	}
+	d := model.TopicDescription{Topic: t, PartitionStats: make([]model.PartitionStats, 0, t.Partitions)}
+	for p := 0; p < t.Partitions; p++ {
+		st, err := a.msg.PartitionStats(ctx, n, p)
+		if err != nil {
+			return model.TopicDescription{}, err
+		}
+		d.PartitionStats = append(d.PartitionStats, st)
+	}
+	return d, nil
+}
+
+// ReassignTopic cambia las réplicas de las particiones de n. Con replicas
+// nil reparte de nuevo sobre los nodos actuales moviendo lo mínimo (rf > 0
+// cambia además el factor de réplica). Las réplicas nuevas copian los
+// datos del líder al ritmo de cluster.catchup_rate.
+func (a *adminUC) ReassignTopic(ctx context.Context, n string, replicas [][]string, rf int) (model.Topic, error) {
+	cfg := cl.CurrentConfig()
+	if cfg == nil {
+		return model.Topic{}, errs.Unavailable("node is not running in cluster mode")
+	}
+	t, err := a.meta.DescribeTopic(ctx, n)
+	if err != nil {
+		return model.Topic{}, err
+	}
+	nodes := nodeIDs(cfg)
+	if replicas == nil {
+		if rf == 0 {
+			rf = t.ReplicationFactor()
+		}
+		if rf == 0 {
+			rf = a.rf
+		}
+		if rf <= 0 || rf > len(nodes) {
+			return model.Topic{}, errs.Invalid("replication factor must be between 1 and %d", len(nodes))
+		}
+		cur := t.Replicas
+		if len(cur) != t.Partitions {
+			cur = make([][]string, t.Partitions)
+		}
+		replicas = service.Rebalance(cur, nodes, rf)
+	} else if err := checkReplicas(replicas, t.Partitions, nodes); err != nil {
+		return model.Topic{}, err
+	}
+	if err := a.meta.SetReplicas(ctx, n, replicas); err != nil {
+		return model.Topic{}, err
+	}
+	t.Replicas = replicas
+	return t, nil
+}
+
+// place asigna las réplicas de un tópico nuevo; nil en single-node o si
+// el factor resultante es 0 (todos los nodos).
+func (a *adminUC) place(n string, p, rf int) ([][]string, error) {
+	if rf < 0 {
+		return nil, errs.Invalid("replication factor must be >= 0")
+	}
+	cfg := cl.CurrentConfig()
+	if cfg == nil {
+		return nil, nil
+	}
+	nodes := nodeIDs(cfg)
+	if rf == 0 {
+		rf = min(a.rf, len(nodes))
+	}
+	if rf == 0 {
+		return nil, nil
+	}
+	if rf > len(nodes) {
+		return nil, errs.Invalid("replication factor %d exceeds the %d nodes of the cluster", rf, len(nodes))
+	}
+	return service.Place(nodes, n, p, rf), nil
+}
+
+// checkReplicas valida una asignación manual: una lista no vacía y sin
+// repetidos por partición, sólo con nodos del clúster.
+func checkReplicas(replicas [][]string, parts int, nodes []string) error {
+	if len(replicas) != parts {
+		return errs.Invalid("replicas must list the %d partitions of the topic", parts)
+	}
+	for p, reps := range replicas {
+		if len(reps) == 0 {
+			return errs.Invalid("partition %d has no replicas", p)
+		}
+		for i, id := range reps {
+			if !slices.Contains(nodes, id) {
+				return errs.Invalid("partition %d: unknown node %q", p, id)
+			}
+			if slices.Contains(reps[:i], id) {
+				return errs.Invalid("partition %d: node %q listed twice", p, id)
+			}
+		}
+	}
+	return nil
+}
+
+func nodeIDs(cfg *cl.Config) []string {
+	out := make([]string, 0, len(cfg.Nodes))
+	for _, n := range cfg.Nodes {
+		out = append(out, n.ID)
+	}
+	return out
 }
 
 // COLAS
 func (a *adminUC) CreateQueue(ctx context.Context, n, u string) error {
-	return a.meta.CreateQueue(ctx, n, u)
+	if err := a.reserve(ctx, u, model.ScopeQueue); err != nil {
+		return err
+	}
+	if err := a.meta.CreateQueue(ctx, n, u); err != nil {
+		a.release(ctx, u, model.ScopeQueue)
+		return err
+	}
+	return nil
 }
 func (a *adminUC) ListQueues(ctx context.Context) ([]string, error) {
 	return a.meta.ListQueues(ctx)
 }
 func (a *adminUC) DeleteQueue(ctx context.Context, n, u string) error {
-	return a.meta.DeleteQueue(ctx, n, u)
+	if err := a.meta.DeleteQueue(ctx, n, u); err != nil {
+		return err
+	}
+	a.release(ctx, u, model.ScopeQueue)
+	return nil
+}
+func (a *adminUC) DescribeQueue(ctx context.Context, n string) (model.QueueDescription, error) {
+	q, err := a.meta.DescribeQueue(ctx, n)
+	if err != nil {
+		return model.QueueDescription{}, err
+	}
+	st, err := a.msg.QueueStats(ctx, n)
+	if err != nil {
+		return model.QueueDescription{}, err
+	}
+	return model.QueueDescription{Queue: q, QueueStats: st}, nil
+}
+
+// CUOTAS
+func (a *adminUC) GetQuota(ctx context.Context, scope, name string) (model.Quota, model.QuotaUsage, error) {
+	if a.quota == nil {
+		return model.Quota{}, model.QuotaUsage{}, nil
+	}
+	return a.quota.GetQuota(ctx, scope, name)
+}
+func (a *adminUC) SetQuota(ctx context.Context, scope, name string, q model.Quota) error {
+	if a.quota == nil {
+		return nil
+	}
+	return a.quota.SetQuota(ctx, scope, name, q)
+}
+
+// AUDITORÍA
+func (a *adminUC) QueryAudit(ctx context.Context, f model.AuditFilter) ([]model.AuditEvent, error) {
+	if a.audit == nil {
+		return nil, nil
+	}
+	return a.audit.Query(ctx, f)
+}
+
+// ExportTopic escribe en w, en format, los mensajes de q. En clúster
+// cada partición se lee en una de sus réplicas y sólo hasta lo
+// comprometido, como en Pull.
+func (a *adminUC) ExportTopic(ctx context.Context, w io.Writer, format string, q model.ExportQuery) (int, error) {
+	f, err := export.ParseFormat(format)
+	if err != nil {
+		return 0, err
+	}
+	parts, err := a.meta.GetTopic(ctx, q.Topic)
+	if err != nil {
+		return 0, err
+	}
+	ew, err := export.NewWriter(w, f)
+	if err != nil {
+		return 0, err
+	}
+	var src export.Source = a.msg
+	if a.cons != nil {
+		src = committedSource{msg: a.msg, cons: a.cons, fwd: a.fwd}
+	}
+	n, err := export.Export(ctx, src, ew, q, parts)
+	if cerr := ew.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// committedSource lee una partición en este nodo si es réplica (o en
+// una réplica vía fwd si no) y corta en el offset comprometido. Deja los
+// registros de control: Export los descarta y los necesita para avanzar.
+type committedSource struct {
+	msg  outbound.MessageStore
+	cons *cl.Consensus
+	fwd  *cl.Forwarder
+}
+
+func (s committedSource) Read(ctx context.Context, topic string, part int, from uint64, max int) ([]model.Message, error) {
+	var nr *cl.NotReplicaError
+	if err := s.cons.CheckReplica(topic, part); errors.As(err, &nr) && nr.Replica != "" && s.fwd != nil {
+		return s.fwd.Read(ctx, nr.Replica, topic, part, from, max)
+	} else if err != nil {
+		return nil, err
+	}
+	msgs, err := s.msg.Read(ctx, topic, part, from, max)
+	if err != nil {
+		return nil, err
+	}
+	commit := s.cons.Committed(topic, part)
+	if i := slices.IndexFunc(msgs, func(m model.Message) bool { return m.Offset >= commit }); i >= 0 {
+		msgs = msgs[:i]
+	}
+	return msgs, nil
+}
+
+// BACKUP
+func (a *adminUC) Backup(ctx context.Context, w io.Writer, since uint64) (model.BackupManifest, error) {
+	return a.bak.Backup(ctx, w, since)
+}
+
+// helpers nil-safe
+func (a *adminUC) reserve(ctx context.Context, u, kind string) error {
+	if a.quota == nil {
+		return nil
+	}
+	return a.quota.Reserve(ctx, u, kind)
+}
+func (a *adminUC) release(ctx context.Context, u, kind string) {
+	if a.quota != nil {
+		a.quota.Release(ctx, u, kind)
+	}
 }
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	cl "github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+)
+
+type clusterUC struct {
+	fan  *cl.Fanout // nil si ejecuto single-node
+	file string     // cluster.json a reescribir; "" → sólo en memoria
+	mu   sync.Mutex // serializa los cambios de membresía
+}
+
+func NewCluster(fan *cl.Fanout, file string) inbound.Cluster {
+	return &clusterUC{fan: fan, file: file}
+}
+
+func (u *clusterUC) Nodes(context.Context) ([]model.Node, error) {
+	cfg := cl.CurrentConfig()
+	if cfg == nil {
+		return []model.Node{}, nil
+	}
+	out := make([]model.Node, 0, len(cfg.Nodes))
+	for _, n := range cfg.Nodes {
+		out = append(out, model.Node{ID: n.ID, Host: n.Host, REST: n.REST})
+	}
+	return out, nil
+}
+
+// AddNode agrega un nodo o cambia las direcciones de uno existente.
+func (u *clusterUC) AddNode(_ context.Context, n model.Node) error {
+	return u.change(func(cfg *cl.Config) error {
+		for i := range cfg.Nodes {
+			if cfg.Nodes[i].ID == n.ID {
+				if cfg.Nodes[i].Host == n.Host && cfg.Nodes[i].REST == n.REST {
+					return errs.AlreadyExists("node %q", n.ID)
+				}
+				cfg.Nodes[i].Host, cfg.Nodes[i].REST = n.Host, n.REST
+				return nil
+			}
+		}
+		cfg.Nodes = append(cfg.Nodes, cl.Node{ID: n.ID, Host: n.Host, REST: n.REST})
+		return nil
+	})
+}
+
+func (u *clusterUC) RemoveNode(_ context.Context, id string) error {
+	return u.change(func(cfg *cl.Config) error {
+		for i := range cfg.Nodes {
+			if cfg.Nodes[i].ID == id {
+				cfg.Nodes = append(cfg.Nodes[:i], cfg.Nodes[i+1:]...)
+				return nil
+			}
+		}
+		return errs.NotFound("node %q", id)
+	})
+}
+
+// change aplica edit sobre una copia de la membresía, la recarga y, si
+// viene de archivo, la persiste para que sobreviva a un reinicio.
+func (u *clusterUC) change(edit func(*cl.Config) error) error {
+	if u.fan == nil {
+		return errs.Unavailable("node is not running in cluster mode")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cfg := cl.CurrentConfig().Clone()
+	if err := edit(cfg); err != nil {
+		return err
+	}
+	if err := cl.Reload(u.fan, cfg); err != nil {
+		return err
+	}
+	if u.file != "" {
+		if err := cfg.Save(u.file); err != nil {
+			return errs.Unavailable("membership applied but not saved to %s: %v", u.file, err)
+		}
+	}
+	return nil
+}
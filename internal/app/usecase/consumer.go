@@ -3,8 +3,13 @@ package usecase
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
 )
@@ -12,34 +17,157 @@ import (
 type consumerUC struct {
 	meta outbound.MetaStore
 	msg  outbound.MessageStore
+	cons *cluster.Consensus // nil → se lee todo el log local
+	fwd  *cluster.Forwarder // nil → las particiones de otros nodos dan 503
 }
 
-func NewConsumer(meta outbound.MetaStore, msg outbound.MessageStore) inbound.Consumer {
-	return &consumerUC{meta: meta, msg: msg}
+func NewConsumer(meta outbound.MetaStore, msg outbound.MessageStore, cons *cluster.Consensus,
+	fwd *cluster.Forwarder) inbound.Consumer {
+
+	return &consumerUC{meta: meta, msg: msg, cons: cons, fwd: fwd}
 }
 
 // ---------------- TOPIC PULL -----------------------------
 
 // Pull lee los mensajes de un tópico para un grupo específico.
-func (c *consumerUC) Pull(ctx context.Context, topic, group string, part int, max int) ([]model.Message, error) {
+func (c *consumerUC) Pull(ctx context.Context, topic, group string, part int, max int) (msgs []model.Message, err error) {
+	res := metrics.Unknown // el tópico sólo es etiqueta si existe
+	defer func(t time.Time) { metrics.Observe("pull", res, t, err) }(time.Now())
+
 	// Verifica que la partición esté en el rango válido.
-	parts, err := c.meta.GetTopic(ctx, topic)
-	if err != nil {
+	if err := c.checkPartition(ctx, topic, part); err != nil {
 		return nil, err
 	}
-	if part >= parts {
-		return nil, errors.New("partition out of range")
+	res = topic
+	// con réplicas asignadas, sólo sus nodos tienen los mensajes: se leen
+	// en una de ellas (o se redirige allí)
+	var remote string
+	if c.cons != nil {
+		var nr *cluster.NotReplicaError
+		if err := c.cons.CheckReplica(topic, part); errors.As(err, &nr) && nr.Replica != "" && c.fwd != nil {
+			remote = nr.Replica
+		} else if err != nil {
+			return nil, err
+		}
 	}
 
 	// Obtiene el offset para el grupo y partición.
-	from, _ := c.meta.GetOffset(ctx, group, topic, part)
-	return c.msg.Read(ctx, topic, part, from, max)
+	from, err := c.meta.GetOffset(ctx, group, topic, part)
+	if err != nil {
+		return nil, err
+	}
+	if remote != "" {
+		msgs, err = c.fwd.Read(ctx, remote, topic, part, from, max)
+		return slices.DeleteFunc(msgs, model.Message.IsControl), err
+	}
+	msgs, err = c.msg.Read(ctx, topic, part, from, max)
+	if err != nil || c.cons == nil {
+		return msgs, err
+	}
+	return c.visible(topic, part, msgs), nil
+}
+
+// visible descarta lo que aún no está comprometido en el clúster y los
+// registros de control del log.
+func (c *consumerUC) visible(topic string, part int, msgs []model.Message) []model.Message {
+	commit := c.cons.Committed(topic, part)
+	out := msgs[:0]
+	for _, m := range msgs {
+		if m.Offset >= commit {
+			break
+		}
+		if !m.IsControl() {
+			out = append(out, m)
+		}
+	}
+	return out
 }
 
 // ---------------- COMMIT OFFSET --------------------------
 
 // Commit guarda el offset del grupo para una partición.
 func (c *consumerUC) Commit(ctx context.Context, topic, group string, part int, offset uint64) error {
+	if err := c.checkPartition(ctx, topic, part); err != nil {
+		return err
+	}
 	// Guarda el nuevo offset después de procesar los mensajes.
 	return c.meta.CommitOffset(ctx, group, topic, part, offset)
 }
+
+// ---------------- DESCRIBE GROUP -------------------------
+
+// DescribeGroup devuelve los offsets confirmados del grupo con su lag.
+func (c *consumerUC) DescribeGroup(ctx context.Context, group string) (model.GroupDescription, error) {
+	offs, err := c.meta.GroupOffsets(ctx, group)
+	if err != nil {
+		return model.GroupDescription{}, err
+	}
+	if len(offs) == 0 {
+		return model.GroupDescription{}, errs.NotFound("group %q", group)
+	}
+	if err := c.fillLag(ctx, offs); err != nil {
+		return model.GroupDescription{}, err
+	}
+	return model.GroupDescription{Group: group, Offsets: offs}, nil
+}
+
+// ---------------- LAG ------------------------------------
+
+// Lag calcula el lag de todos los grupos; group y topic filtran si no
+// están vacíos.
+func (c *consumerUC) Lag(ctx context.Context, group, topic string) ([]model.ConsumerLag, error) {
+	groups := []string{group}
+	if group == "" {
+		var err error
+		if groups, err = c.meta.ListGroups(ctx); err != nil {
+			return nil, err
+		}
+	}
+	out := []model.ConsumerLag{}
+	for _, g := range groups {
+		offs, err := c.meta.GroupOffsets(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.fillLag(ctx, offs); err != nil {
+			return nil, err
+		}
+		for _, o := range offs {
+			if topic == "" || o.Topic == topic {
+				out = append(out, model.ConsumerLag{Group: g, GroupOffset: o})
+			}
+		}
+	}
+	return out, nil
+}
+
+// fillLag completa EndOffset y Lag. El HWM es el mayor entre el que se
+// lleva en RAM (cluster.TrackNextOffset) y el persistido en el store.
+func (c *consumerUC) fillLag(ctx context.Context, offs []model.GroupOffset) error {
+	for i := range offs {
+		end, err := c.msg.HighWatermark(ctx, offs[i].Topic, offs[i].Partition)
+		if err != nil {
+			return err
+		}
+		if ram := cluster.NextOffset(offs[i].Topic, offs[i].Partition); ram > end {
+			end = ram
+		}
+		offs[i].EndOffset = end
+		if end > offs[i].Committed {
+			offs[i].Lag = end - offs[i].Committed
+		}
+	}
+	return nil
+}
+
+// checkPartition valida que el tópico exista y que part esté en rango.
+func (c *consumerUC) checkPartition(ctx context.Context, topic string, part int) error {
+	parts, err := c.meta.GetTopic(ctx, topic)
+	if err != nil {
+		return err
+	}
+	if part < 0 || part >= parts {
+		return errs.OutOfRange("partition %d of topic %q (has %d)", part, topic, parts)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	cl "github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+)
+
+// WithForwarding envuelve el publicador para que lo que este nodo no
+// atiende vaya al líder de la partición (cl.Forwarder). Los commits de
+// offsets y las colas no lo necesitan: viajan por los logs replicados
+// (cl.ReplicatedMeta, cl.ReplicatedQueues). Con fwd == nil lo devuelve
+// tal cual.
+func WithForwarding(fwd *cl.Forwarder, pub inbound.Publisher) inbound.Publisher {
+	if fwd == nil {
+		return pub
+	}
+	return &forwardPublisher{pub, fwd}
+}
+
+/*──────────  publish  ──────────*/
+
+type forwardPublisher struct {
+	inbound.Publisher
+	fwd *cl.Forwarder
+}
+
+// Publish lo intenta en local (el hash de la clave decide la partición) y
+// sólo si este nodo no es el líder lo reenvía al que sí lo es.
+func (p *forwardPublisher) Publish(ctx context.Context,
+	topic, key, payload, user string, acks model.Acks) (int, uint64, error) {
+
+	part, off, err := p.Publisher.Publish(ctx, topic, key, payload, user, acks)
+	var nl *cl.NotLeaderError
+	if !errors.As(err, &nl) || nl.Leader == "" {
+		return part, off, err
+	}
+	r, err := p.fwd.Call(ctx, nl.Leader, &pb.ForwardRequest{
+		Op: cl.OpPublish, User: user, Topic: topic, Key: key,
+		Payload: []byte(payload), Acks: int32(acks),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(r.Part), r.Offset, nil
+}
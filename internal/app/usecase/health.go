@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	cl "github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+type healthUC struct {
+	meta    outbound.MetaStore
+	storage outbound.HealthChecker
+	fan     *cl.Fanout    // nil si ejecuto single-node
+	cons    *cl.Consensus // nil → sin líderes por partición
+	ae      *cl.AntiEntropy
+	maxLag  uint64 // lag de réplica tolerado para estar "ready"
+}
+
+func NewHealth(meta outbound.MetaStore, storage outbound.HealthChecker,
+	fan *cl.Fanout, cons *cl.Consensus, ae *cl.AntiEntropy, maxLag uint64) inbound.Health {
+
+	return &healthUC{meta: meta, storage: storage, fan: fan, cons: cons, ae: ae, maxLag: maxLag}
+}
+
+// Ready comprueba storage, catálogo, gRPC (sólo en clúster) y que el
+// nodo no vaya más de maxLag mensajes por detrás de ningún peer vivo.
+func (h *healthUC) Ready(ctx context.Context) model.Readiness {
+	r := model.Readiness{Ready: true, Checks: map[string]string{}}
+	check := func(name string, err error) {
+		if err != nil {
+			r.Ready = false
+			r.Checks[name] = err.Error()
+			return
+		}
+		r.Checks[name] = "ok"
+	}
+
+	check("storage", h.storage.Check(ctx))
+	_, err := h.meta.ListTopics(ctx)
+	check("catalog", err)
+
+	if h.fan != nil {
+		if !cl.GRPCServing() {
+			check("grpc", fmt.Errorf("gRPC server not listening"))
+		} else {
+			check("grpc", nil)
+		}
+		if lag := cl.MaxReplicationLag(); lag > h.maxLag {
+			check("replication", fmt.Errorf("lag %d > %d", lag, h.maxLag))
+		} else {
+			check("replication", nil)
+		}
+	}
+	return r
+}
+
+func (h *healthUC) ClusterStatus(context.Context) model.ClusterStatus {
+	st := cl.ClusterStatus(h.fan)
+	if h.cons != nil {
+		st.PartitionLeaders = h.cons.Leaders()
+	}
+	st.Conflicts = h.ae.Conflicts()
+	return st
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+)
+
+// LagMonitor recalcula el lag de todos los grupos cada cierto tiempo,
+// actualiza la métrica mom_consumer_lag y, si hay umbral, publica una
+// alerta en model.LagAlertTopic la primera vez que se supera.
+type LagMonitor struct {
+	cons      inbound.Consumer
+	msg       outbound.MessageStore
+	threshold uint64          // 0 → sin alertas
+	alerted   map[string]bool // group:topic:part por encima del umbral
+}
+
+func NewLagMonitor(cons inbound.Consumer, msg outbound.MessageStore, threshold uint64) *LagMonitor {
+	return &LagMonitor{cons: cons, msg: msg, threshold: threshold, alerted: map[string]bool{}}
+}
+
+// Start lanza el bucle en segundo plano, contado en wg; termina al
+// cancelarse ctx.
+func (m *LagMonitor) Start(ctx context.Context, wg *sync.WaitGroup, every time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				m.check(ctx)
+			}
+		}
+	}()
+}
+
+func (m *LagMonitor) check(ctx context.Context) {
+	lags, err := m.cons.Lag(ctx, "", "")
+	if err != nil {
+		log.Printf("[lag] %v", err)
+		return
+	}
+
+	metrics.ConsumerLag.Reset() // descarta grupos/tópicos que ya no existen
+	for _, l := range lags {
+		part := strconv.Itoa(l.Partition)
+		metrics.ConsumerLag.WithLabelValues(l.Group, l.Topic, part).Set(float64(l.Lag))
+		if m.threshold == 0 {
+			continue
+		}
+
+		k := l.Group + ":" + l.Topic + ":" + part
+		if l.Lag <= m.threshold {
+			delete(m.alerted, k) // vuelve a alertar si se supera otra vez
+			continue
+		}
+		if m.alerted[k] {
+			continue
+		}
+		if err := m.alert(ctx, l); err != nil {
+			log.Printf("[lag] alert %s: %v", k, err)
+			continue
+		}
+		m.alerted[k] = true
+		metrics.LagAlerts.WithLabelValues(l.Group, l.Topic).Inc()
+	}
+}
+
+func (m *LagMonitor) alert(ctx context.Context, l model.ConsumerLag) error {
+	js, err := json.Marshal(model.LagAlert{ConsumerLag: l, Threshold: m.threshold, Time: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	_, err = m.msg.Append(ctx, model.Message{
+		Topic:   model.LagAlertTopic,
+		PartID:  0,
+		Key:     l.Group,
+		Payload: js,
+	})
+	return err
+}
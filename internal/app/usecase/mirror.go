@@ -0,0 +1,656 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"maps"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cl "github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/google/uuid"
+)
+
+/*
+Mirror entre clústeres.
+
+Cada nodo revisa los mirrors del catálogo (replicado) y arranca un copiador
+por partición; sólo copia el que lidera localmente la partición destino
+(en single-node, siempre), así que el trabajo se reparte con los líderes y
+se mueve con ellos. El copiador lee del origen con Pull (sólo mensajes
+comprometidos), escribe cada mensaje en la misma partición del destino con
+las cabeceras model.MirrorSourceHeader y model.MirrorOffsetHeader y, tras
+cada lote, guarda el checkpoint (próximo offset de origen) en el catálogo y
+lo confirma en el grupo del origen, que hace de cursor del siguiente Pull.
+Al tomar una partición parte del mayor entre el checkpoint y el último
+offset copiado que hay en la cola del log local: una caída entre escribir
+y guardar el checkpoint no duplica mensajes.
+*/
+
+var mirrorName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// MirrorOptions ajusta los copiadores.
+type MirrorOptions struct {
+	Interval     time.Duration // espera cuando no hay nada nuevo o tras un error
+	SyncInterval time.Duration // revisión de los mirrors y del HWM del origen
+	Batch        int           // mensajes por Pull
+	// Sources son las URLs de los clústeres de los que se puede copiar
+	// (se compara esquema y host); vacío, ninguno.
+	Sources []string
+}
+
+// MirrorManager implementa inbound.Mirror y ejecuta los copiadores.
+type MirrorManager struct {
+	meta  outbound.MetaStore
+	msg   outbound.MessageStore
+	admin inbound.Admin
+	cons  *cl.Consensus // nil → single-node
+	dial  outbound.RemoteDialer
+	opts  MirrorOptions
+
+	kick    chan struct{} // revisar ya (cambio hecho por la API)
+	mu      sync.Mutex
+	workers map[string]*mirrorWorker // <mirror>/<topic>/<part>
+	wg      *sync.WaitGroup          // copiadores en marcha (lo fija Start)
+}
+
+// mirrorWorker copia una partición de un mirror.
+type mirrorWorker struct {
+	def    model.Mirror
+	topic  string
+	target string
+	part   int
+	stop   context.CancelFunc
+
+	mu        sync.Mutex
+	active    bool   // este nodo lidera la partición destino
+	next      uint64 // próximo offset de origen por copiar
+	end       uint64 // HWM del origen (el último conocido)
+	described time.Time
+	err       string
+}
+
+func NewMirror(meta outbound.MetaStore, msg outbound.MessageStore, admin inbound.Admin,
+	cons *cl.Consensus, dial outbound.RemoteDialer, opts MirrorOptions) *MirrorManager {
+
+	return &MirrorManager{meta: meta, msg: msg, admin: admin, cons: cons, dial: dial, opts: opts,
+		kick: make(chan struct{}, 1), workers: map[string]*mirrorWorker{}}
+}
+
+var _ inbound.Mirror = (*MirrorManager)(nil)
+
+/*──────────  API  ──────────*/
+
+func (u *MirrorManager) ListMirrors(ctx context.Context) ([]model.Mirror, error) {
+	list, err := u.meta.ListMirrors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i] = list[i].Redacted()
+	}
+	return list, nil
+}
+
+func (u *MirrorManager) CreateMirror(ctx context.Context, m model.Mirror, user string) error {
+	if err := validateMirror(m); err != nil {
+		return err
+	}
+	remote, err := u.remote(m)
+	if err != nil {
+		return err
+	}
+	if _, err := u.meta.GetMirror(ctx, m.Name); err == nil {
+		return errs.AlreadyExists("mirror %q", m.Name)
+	} else if errs.Kind(err) != errs.ErrNotFound {
+		return err
+	}
+
+	// el destino de cada tópico tiene las mismas particiones que el origen
+	for _, t := range m.Topics {
+		src, err := remote.DescribeTopic(ctx, t)
+		if err != nil {
+			return err
+		}
+		target := m.Target(t)
+		parts, err := u.meta.GetTopic(ctx, target)
+		switch {
+		case errs.Kind(err) == errs.ErrNotFound:
+			if err := u.admin.CreateTopic(ctx, target, src.Partitions, 0, user); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case parts != src.Partitions:
+			return errs.Invalid("topic %q has %d partitions but source %q has %d",
+				target, parts, t, src.Partitions)
+		}
+	}
+
+	m.Creator, m.CreatedAt = user, time.Now().UTC()
+	if err := u.meta.PutMirror(ctx, m); err != nil {
+		return err
+	}
+	u.wake()
+	return nil
+}
+
+// remote conecta con el origen de m si está en la lista de orígenes
+// permitidos: el nodo no debe hacer peticiones (con el token del mirror) a
+// cualquier URL que le pidan.
+func (u *MirrorManager) remote(m model.Mirror) (outbound.RemoteCluster, error) {
+	src, err := url.Parse(m.Source)
+	if err != nil {
+		return nil, errs.Invalid("mirror source %q: %v", m.Source, err)
+	}
+	for _, s := range u.opts.Sources {
+		if a, err := url.Parse(s); err == nil && a.Scheme == src.Scheme && strings.EqualFold(a.Host, src.Host) {
+			return u.dial(m.Source, m.Token), nil
+		}
+	}
+	return nil, errs.Forbidden("mirror source %q is not in mirror.sources", m.Source)
+}
+
+// validateMirror comprueba la definición antes de tocar nada.
+func validateMirror(m model.Mirror) error {
+	if !mirrorName.MatchString(m.Name) {
+		return errs.Invalid("mirror name must match %s", mirrorName)
+	}
+	if u, err := url.Parse(m.Source); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errs.Invalid("mirror source must be an http(s) URL (got %q)", m.Source)
+	}
+	if len(m.Topics) == 0 {
+		return errs.Invalid("mirror needs at least one topic")
+	}
+	targets := map[string]bool{}
+	for _, t := range m.Topics {
+		if t == "" || strings.HasPrefix(t, model.InternalTopicPrefix) {
+			return errs.Invalid("topic %q cannot be mirrored", t)
+		}
+		target := m.Target(t)
+		if strings.HasPrefix(target, model.InternalTopicPrefix) {
+			return errs.Invalid("topic names starting with %q are reserved", model.InternalTopicPrefix)
+		}
+		if targets[target] {
+			return errs.Invalid("two topics are renamed to %q", target)
+		}
+		targets[target] = true
+	}
+	for t := range m.Rename {
+		if !slices.Contains(m.Topics, t) {
+			return errs.Invalid("rename rule for %q, which is not mirrored", t)
+		}
+	}
+	return nil
+}
+
+// MirrorStatus combina los checkpoints del catálogo, el HWM actual del
+// origen y lo que sabe este nodo de sus copiadores.
+func (u *MirrorManager) MirrorStatus(ctx context.Context, name string) (model.MirrorStatus, error) {
+	m, err := u.meta.GetMirror(ctx, name)
+	if err != nil {
+		return model.MirrorStatus{}, err
+	}
+	cps, err := u.meta.MirrorCheckpoints(ctx, name)
+	if err != nil {
+		return model.MirrorStatus{}, err
+	}
+	next := map[string]uint64{}
+	for _, cp := range cps {
+		next[workerKey(name, cp.Topic, cp.Partition)] = cp.Next
+	}
+
+	st := model.MirrorStatus{Mirror: m.Redacted(), Partitions: []model.MirrorPartition{}}
+	remote, remoteErr := u.remote(m)
+	for _, t := range m.Topics {
+		target := m.Target(t)
+		parts, err := u.meta.GetTopic(ctx, target)
+		if err != nil {
+			return model.MirrorStatus{}, err
+		}
+		var src model.TopicDescription
+		srcErr := remoteErr
+		if srcErr == nil {
+			src, srcErr = remote.DescribeTopic(ctx, t)
+		}
+		for p := range parts {
+			k := workerKey(name, t, p)
+			mp := model.MirrorPartition{Topic: t, Target: target, Partition: p, Checkpoint: next[k]}
+			if srcErr != nil {
+				mp.Error = srcErr.Error()
+			} else if p < len(src.PartitionStats) {
+				mp.SourceEnd = src.PartitionStats[p].EndOffset
+			}
+			if mp.SourceEnd > mp.Checkpoint {
+				mp.Lag = mp.SourceEnd - mp.Checkpoint
+			}
+			u.mu.Lock()
+			if w := u.workers[k]; w != nil {
+				w.mu.Lock()
+				mp.Active = w.active
+				if mp.Error == "" {
+					mp.Error = w.err
+				}
+				w.mu.Unlock()
+			}
+			u.mu.Unlock()
+			st.Partitions = append(st.Partitions, mp)
+		}
+	}
+	return st, nil
+}
+
+func (u *MirrorManager) PauseMirror(ctx context.Context, name string, paused bool) error {
+	m, err := u.meta.GetMirror(ctx, name)
+	if err != nil {
+		return err
+	}
+	if m.Paused == paused {
+		return nil
+	}
+	m.Paused = paused
+	if err := u.meta.PutMirror(ctx, m); err != nil {
+		return err
+	}
+	u.wake()
+	return nil
+}
+
+// DeleteMirror deja de copiar; los tópicos destino se conservan.
+func (u *MirrorManager) DeleteMirror(ctx context.Context, name string) error {
+	if err := u.meta.DeleteMirror(ctx, name); err != nil {
+		return err
+	}
+	u.wake()
+	return nil
+}
+
+// TranslateOffset busca (bisección sobre el log local) el primer mensaje
+// del destino copiado de offset o de uno posterior. Supone, como el resto
+// del mirror, que el destino sólo lo escribe el mirror: los mensajes sin
+// sus cabeceras se saltan.
+func (u *MirrorManager) TranslateOffset(ctx context.Context, name, topic string, part int, offset uint64) (model.OffsetTranslation, error) {
+	m, err := u.meta.GetMirror(ctx, name)
+	if err != nil {
+		return model.OffsetTranslation{}, err
+	}
+	if !slices.Contains(m.Topics, topic) {
+		return model.OffsetTranslation{}, errs.NotFound("topic %q in mirror %q", topic, name)
+	}
+	target := m.Target(topic)
+	parts, err := u.meta.GetTopic(ctx, target)
+	if err != nil {
+		return model.OffsetTranslation{}, err
+	}
+	if part < 0 || part >= parts {
+		return model.OffsetTranslation{}, errs.OutOfRange("partition %d of %q", part, target)
+	}
+	if u.cons != nil {
+		if err := u.cons.CheckReplica(target, part); err != nil {
+			return model.OffsetTranslation{}, err
+		}
+	}
+	st, err := u.msg.PartitionStats(ctx, target, part)
+	if err != nil {
+		return model.OffsetTranslation{}, err
+	}
+
+	src := mirrorSource(m, topic)
+	lo, hi := st.StartOffset, st.EndOffset
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		local, source, ok, err := u.copiedFrom(ctx, target, part, src, mid, hi)
+		if err != nil {
+			return model.OffsetTranslation{}, err
+		}
+		if !ok || source >= offset {
+			hi = mid
+		} else {
+			lo = local + 1
+		}
+	}
+	return model.OffsetTranslation{Mirror: name, Topic: topic, Target: target, Partition: part,
+		SourceOffset: offset, Offset: lo}, nil
+}
+
+// copiedFrom devuelve el primer mensaje copiado por src en [from, to):
+// su offset local y el de origen.
+func (u *MirrorManager) copiedFrom(ctx context.Context, topic string, part int, src string, from, to uint64) (uint64, uint64, bool, error) {
+	for from < to {
+		msgs, err := u.msg.Read(ctx, topic, part, from, min(u.opts.Batch, int(to-from)))
+		if err != nil || len(msgs) == 0 {
+			return 0, 0, false, err
+		}
+		for _, msg := range msgs {
+			if msg.Offset >= to {
+				return 0, 0, false, nil
+			}
+			if source, ok := copiedOffset(msg, src); ok {
+				return msg.Offset, source, true, nil
+			}
+		}
+		from = msgs[len(msgs)-1].Offset + 1
+	}
+	return 0, 0, false, nil
+}
+
+// copiedOffset es el offset de origen de msg si lo copió src.
+func copiedOffset(msg model.Message, src string) (uint64, bool) {
+	if msg.Headers[model.MirrorSourceHeader] != src {
+		return 0, false
+	}
+	off, err := strconv.ParseUint(msg.Headers[model.MirrorOffsetHeader], 10, 64)
+	return off, err == nil
+}
+
+func mirrorSource(m model.Mirror, topic string) string { return m.Name + "/" + topic }
+
+func workerKey(name, topic string, part int) string {
+	return name + "/" + topic + "/" + strconv.Itoa(part)
+}
+
+/*──────────  copiadores  ──────────*/
+
+// Start revisa los mirrors cada SyncInterval (y tras cada cambio hecho
+// desde este nodo) hasta que se cancele ctx. wg cuenta el bucle y los
+// copiadores.
+func (u *MirrorManager) Start(ctx context.Context, wg *sync.WaitGroup) {
+	u.wg = wg
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(u.opts.SyncInterval)
+		defer t.Stop()
+		for {
+			u.sync(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			case <-u.kick:
+			}
+		}
+	}()
+}
+
+func (u *MirrorManager) wake() {
+	select {
+	case u.kick <- struct{}{}:
+	default:
+	}
+}
+
+// sync arranca un copiador por partición de cada mirror activo y para los
+// de mirrors borrados, pausados o cambiados.
+func (u *MirrorManager) sync(ctx context.Context) {
+	list, err := u.meta.ListMirrors(ctx)
+	if err != nil {
+		log.Printf("[mirror] listar: %v", err)
+		return
+	}
+	want := map[string]*mirrorWorker{}
+	for _, m := range list {
+		if m.Paused {
+			continue
+		}
+		for _, t := range m.Topics {
+			target := m.Target(t)
+			parts, err := u.meta.GetTopic(ctx, target)
+			if err != nil {
+				log.Printf("[mirror] %s: %v", m.Name, err)
+				continue
+			}
+			for p := range parts {
+				want[workerKey(m.Name, t, p)] = &mirrorWorker{def: m, topic: t, target: target, part: p}
+			}
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for k, w := range u.workers {
+		if nw := want[k]; nw == nil || !reflect.DeepEqual(nw.def, w.def) {
+			w.stop()
+			delete(u.workers, k)
+			metrics.MirrorLag.DeleteLabelValues(w.def.Name, w.topic, strconv.Itoa(w.part))
+		}
+	}
+	for k, w := range want {
+		if u.workers[k] != nil {
+			continue
+		}
+		var wctx context.Context
+		wctx, w.stop = context.WithCancel(ctx)
+		u.workers[k] = w
+		u.wg.Add(1)
+		go func() {
+			defer u.wg.Done()
+			u.run(wctx, w)
+		}()
+	}
+}
+
+// run copia la partición de w mientras este nodo lidere el destino.
+func (u *MirrorManager) run(ctx context.Context, w *mirrorWorker) {
+	remote, err := u.remote(w.def)
+	if err != nil { // el origen dejó de estar permitido
+		w.set(false, err)
+		<-ctx.Done()
+		return
+	}
+	resumed := false
+	for {
+		wait := u.opts.Interval
+		if err := u.lead(ctx, w); err != nil {
+			resumed = false
+			w.set(false, nil)
+		} else {
+			if !resumed {
+				err = u.resume(ctx, w, remote)
+				resumed = err == nil
+			}
+			if err == nil {
+				var n int
+				if n, err = u.copyBatch(ctx, w, remote); err == nil && n == u.opts.Batch {
+					wait = 0 // queda más
+				}
+			}
+			if err != nil {
+				resumed = false // al volver se recalcula desde el log local
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			w.set(true, err)
+		}
+		if wait == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// lead devuelve nil si este nodo debe copiar la partición de w.
+func (u *MirrorManager) lead(ctx context.Context, w *mirrorWorker) error {
+	if u.cons == nil {
+		return nil
+	}
+	return u.cons.CheckLeader(ctx, w.target, w.part)
+}
+
+// set guarda el estado del copiador; los errores se registran al cambiar.
+func (w *mirrorWorker) set(active bool, err error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if msg != "" && msg != w.err {
+		log.Printf("[mirror] %s: %s", workerKey(w.def.Name, w.topic, w.part), msg)
+	}
+	w.active, w.err = active, msg
+}
+
+// resume fija desde dónde copiar al tomar la partición y mueve ahí el
+// cursor del grupo en el origen.
+func (u *MirrorManager) resume(ctx context.Context, w *mirrorWorker, remote outbound.RemoteCluster) error {
+	cps, err := u.meta.MirrorCheckpoints(ctx, w.def.Name)
+	if err != nil {
+		return err
+	}
+	var next uint64
+	for _, cp := range cps {
+		if cp.Topic == w.topic && cp.Partition == w.part {
+			next = cp.Next
+		}
+	}
+	last, ok, err := u.lastCopied(ctx, w)
+	if err != nil {
+		return err
+	}
+	if ok && last+1 > next {
+		next = last + 1
+	}
+	if err := remote.Commit(ctx, w.topic, w.def.SourceGroup(), w.part, next); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.next = next
+	w.mu.Unlock()
+	return nil
+}
+
+// lastCopied busca, en el último lote del log local, el mayor offset de
+// origen copiado por este mirror.
+func (u *MirrorManager) lastCopied(ctx context.Context, w *mirrorWorker) (uint64, bool, error) {
+	end, err := u.msg.HighWatermark(ctx, w.target, w.part)
+	if err != nil || end == 0 {
+		return 0, false, err
+	}
+	from := end - min(end, uint64(u.opts.Batch))
+	msgs, err := u.msg.Read(ctx, w.target, w.part, from, u.opts.Batch)
+	if err != nil {
+		return 0, false, err
+	}
+	src := mirrorSource(w.def, w.topic)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if off, ok := copiedOffset(msgs[i], src); ok {
+			return off, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// copyBatch copia un lote del origen; devuelve cuántos mensajes leyó.
+func (u *MirrorManager) copyBatch(ctx context.Context, w *mirrorWorker, remote outbound.RemoteCluster) (int, error) {
+	group := w.def.SourceGroup()
+	msgs, err := remote.Pull(ctx, w.topic, group, w.part, u.opts.Batch)
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	start := w.next
+	w.mu.Unlock()
+	var todo []model.Message
+	for _, m := range msgs {
+		if m.Offset >= start && !m.IsControl() { // lo anterior ya está copiado
+			todo = append(todo, m)
+		}
+	}
+	next, err := u.write(ctx, w, todo, start)
+	if next != start {
+		if cerr := u.meta.CommitMirror(ctx, w.def.Name, w.topic, w.part, next); cerr != nil {
+			return 0, cerr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) > 0 {
+		if err := remote.Commit(ctx, w.topic, group, w.part, next); err != nil {
+			return 0, err
+		}
+	}
+
+	w.mu.Lock()
+	w.next = next
+	refresh := len(msgs) < u.opts.Batch && time.Since(w.described) >= u.opts.SyncInterval
+	w.mu.Unlock()
+	if refresh {
+		if d, err := remote.DescribeTopic(ctx, w.topic); err == nil && w.part < len(d.PartitionStats) {
+			w.mu.Lock()
+			w.end, w.described = d.PartitionStats[w.part].EndOffset, time.Now()
+			w.mu.Unlock()
+		}
+	}
+	w.mu.Lock()
+	w.end = max(w.end, next)
+	lag := w.end - next
+	w.mu.Unlock()
+	metrics.MirrorLag.WithLabelValues(w.def.Name, w.topic, strconv.Itoa(w.part)).Set(float64(lag))
+	return len(msgs), nil
+}
+
+// write escribe msgs en el destino y devuelve el próximo offset de origen
+// por copiar. En clúster el lote cuenta cuando el quórum tiene el último
+// mensaje (y con él, los anteriores); si falla antes, resume lo retoma
+// desde el log local.
+func (u *MirrorManager) write(ctx context.Context, w *mirrorWorker, msgs []model.Message, next uint64) (uint64, error) {
+	src := mirrorSource(w.def, w.topic)
+	copied := metrics.Mirrored.WithLabelValues(w.def.Name, w.topic)
+	for i, m := range msgs {
+		h := make(map[string]string, len(m.Headers)+2)
+		maps.Copy(h, m.Headers)
+		h[model.MirrorSourceHeader] = src
+		h[model.MirrorOffsetHeader] = strconv.FormatUint(m.Offset, 10)
+		lm := model.Message{
+			ID:       uuid.New(),
+			Topic:    w.target,
+			PartID:   w.part,
+			Key:      m.Key,
+			Payload:  m.Payload,
+			Producer: m.Producer,
+			Headers:  h,
+		}
+		if u.cons != nil {
+			last := i == len(msgs)-1
+			acks := model.AcksLeader
+			if last {
+				acks = model.AcksAll
+			}
+			if _, err := u.cons.Propose(ctx, lm, acks); err != nil {
+				return next, err
+			}
+			if last {
+				copied.Add(float64(len(msgs)))
+				next = m.Offset + 1
+			}
+			continue
+		}
+		off, err := u.msg.Append(ctx, lm)
+		if err != nil {
+			return next, err
+		}
+		cl.TrackNextOffset(w.target, w.part, off+1)
+		copied.Inc()
+		next = m.Offset + 1
+	}
+	return next, nil
+}
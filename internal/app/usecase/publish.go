@@ -2,28 +2,36 @@ package usecase
 
 import (
 	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
 	cl "github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
-	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/service"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type publisherUC struct {
-	meta outbound.MetaStore
-	msg  outbound.MessageStore
-	auth outbound.AuthStore
-	fan  *cl.Fanout // nil si ejecuto single-node
+	meta  outbound.MetaStore
+	msg   outbound.MessageStore
+	auth  outbound.AuthStore
+	quota outbound.QuotaStore // nil → sin cuotas
+	cons  *cl.Consensus       // nil → sin log replicado (single-node)
+
+	pending sync.WaitGroup // escrituras acks=0 en curso
 }
 
 func NewPublisher(meta outbound.MetaStore, msg outbound.MessageStore,
-	auth outbound.AuthStore, fan *cl.Fanout) inbound.Publisher {
+	auth outbound.AuthStore, quota outbound.QuotaStore, cons *cl.Consensus) inbound.Publisher {
 
-	return &publisherUC{meta: meta, msg: msg, auth: auth, fan: fan}
+	return &publisherUC{meta: meta, msg: msg, auth: auth, quota: quota, cons: cons}
 }
 
 // --------------------------------------------------------------------
@@ -31,15 +39,44 @@ func NewPublisher(meta outbound.MetaStore, msg outbound.MessageStore,
 // --------------------------------------------------------------------
 
 func (p *publisherUC) Publish(ctx context.Context,
-	topic, key, payload, user string) (int, uint64, error) {
+	topic, key, payload, user string, acks model.Acks) (part int, offset uint64, err error) {
+
+	res := metrics.Unknown // el tópico sólo es etiqueta si existe
+	start, async := time.Now(), false
+	defer func() {
+		if !async { // con acks=0 lo registra la escritura al terminar
+			metrics.Observe("publish", res, start, err)
+		}
+	}()
+	ctx, span := tracing.Start(ctx, "publisherUC.Publish",
+		attribute.String("mom.topic", topic), attribute.String("mom.acks", acks.String()))
+	defer func() { tracing.End(span, err) }()
 
 	parts, err := p.meta.GetTopic(ctx, topic)
 	if err != nil {
 		return 0, 0, err
 	}
+	res = topic
 	partID := service.HashPartition(key, parts)
 
+	// sólo cobra el líder: si no lo somos la petición se reenvía (o se
+	// redirige) y el líder aplica las cuotas al atenderla
+	if p.cons != nil {
+		if err := p.cons.CheckLeader(ctx, topic, partID); err != nil {
+			return 0, 0, err
+		}
+	}
+	if p.quota != nil {
+		err := p.quota.Take(ctx, 1, len(payload),
+			model.QuotaRef{Scope: model.ScopeUser, Name: user},
+			model.QuotaRef{Scope: model.ScopeTopic, Name: topic})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
 	// --- construye con UUID antes de Append ---
+	// la traza viaja en las cabeceras para que el consumidor la continúe
 	m := model.Message{
 		ID:       uuid.New(),
 		Topic:    topic,
@@ -47,27 +84,54 @@ func (p *publisherUC) Publish(ctx context.Context,
 		Key:      key,
 		Payload:  []byte(payload),
 		Producer: user,
+		Headers:  map[string]string{model.TimeHeader: strconv.FormatInt(time.Now().UnixMilli(), 10)},
+	}
+	tracing.Inject(ctx, m.Headers)
+
+	// acks=0: se responde ya; los fallos sólo quedan en el log
+	if acks == model.AcksNone {
+		ctx = tracing.Detach(ctx)
+		p.pending.Add(1)
+		async = true
+		go func() {
+			defer p.pending.Done()
+			_, err := p.write(ctx, m, acks)
+			metrics.Observe("publish", res, start, err)
+			if err != nil {
+				log.Printf("[publish] %s:%d (acks=0): %v", topic, partID, err)
+			}
+		}()
+		return partID, 0, nil
 	}
-	offset, err := p.msg.Append(ctx, m)
+
+	offset, err = p.write(ctx, m, acks)
 	if err != nil {
 		return 0, 0, err
 	}
-	cluster.TrackNextOffset(topic, partID, offset+1)
-
-	// registro HWM para reconciliación ----
-	cl.TrackNextOffset(topic, partID, offset+1)
-
-	// ---- fan-out a peers (si se está en cluster) -------
-	if p.fan != nil {
-		p.fan.Broadcast(ctx, []*pb.Message{{
-			Uuid:    m.ID.String(),
-			Topic:   topic,
-			Part:    uint32(partID),
-			Offset:  offset,
-			Key:     key,
-			User:    user,
-			Payload: []byte(payload),
-		}})
-	}
 	return partID, offset, nil
 }
+
+// write agrega m al log. En clúster sólo el líder de la partición asigna
+// el offset y Propose espera las réplicas que pida acks; en single-node
+// se escribe directamente en el store.
+func (p *publisherUC) write(ctx context.Context, m model.Message, acks model.Acks) (uint64, error) {
+	if p.cons != nil {
+		return p.cons.Propose(ctx, m, acks)
+	}
+	return p.msg.Append(ctx, m)
+}
+
+// Flush espera las escrituras acks=0 pendientes o a que venza ctx.
+func (p *publisherUC) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,28 +2,38 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
 	"github.com/google/uuid"
 )
 
 type queueUC struct {
-	meta outbound.MetaStore
-	msg  outbound.MessageStore
+	meta  outbound.MetaStore
+	msg   outbound.MessageStore
+	quota outbound.QuotaStore // nil → sin cuotas
 }
 
-func NewQueue(meta outbound.MetaStore, msg outbound.MessageStore) inbound.Queue {
-	return &queueUC{meta: meta, msg: msg}
+func NewQueue(meta outbound.MetaStore, msg outbound.MessageStore, quota outbound.QuotaStore) inbound.Queue {
+	return &queueUC{meta: meta, msg: msg, quota: quota}
 }
 
 func (q *queueUC) CreateQueue(ctx context.Context, name, user string) error {
+	if q.quota != nil {
+		if err := q.quota.Reserve(ctx, user, model.ScopeQueue); err != nil {
+			return err
+		}
+	}
 	if err := q.meta.CreateQueue(ctx, name, user); err != nil {
+		if q.quota != nil {
+			q.quota.Release(ctx, user, model.ScopeQueue)
+		}
 		return err
 	}
-	// crea stub vacío para garantizar existencia en msgStore
-	return q.msg.Enqueue(ctx, name, model.Message{})
+	return nil
 }
 
 func (q *queueUC) ListQueues(ctx context.Context) ([]string, error) {
@@ -31,10 +41,26 @@ func (q *queueUC) ListQueues(ctx context.Context) ([]string, error) {
 }
 
 func (q *queueUC) DeleteQueue(ctx context.Context, name, user string) error {
-	return q.meta.DeleteQueue(ctx, name, user)
+	if err := q.meta.DeleteQueue(ctx, name, user); err != nil {
+		return err
+	}
+	if q.quota != nil {
+		q.quota.Release(ctx, user, model.ScopeQueue)
+	}
+	return nil
 }
 
-func (q *queueUC) Enqueue(ctx context.Context, queue, payload, user string) error {
+func (q *queueUC) Enqueue(ctx context.Context, queue, payload, user string) (err error) {
+	res := q.label(ctx, queue)
+	defer func(t time.Time) { metrics.Observe("enqueue", res, t, err) }(time.Now())
+	if q.quota != nil {
+		err := q.quota.Take(ctx, 1, len(payload),
+			model.QuotaRef{Scope: model.ScopeUser, Name: user},
+			model.QuotaRef{Scope: model.ScopeQueue, Name: queue})
+		if err != nil {
+			return err
+		}
+	}
 	m := model.Message{
 		ID:       uuid.New(),
 		Payload:  []byte(payload),
@@ -43,10 +69,23 @@ func (q *queueUC) Enqueue(ctx context.Context, queue, payload, user string) erro
 	return q.msg.Enqueue(ctx, queue, m)
 }
 
-func (q *queueUC) Dequeue(ctx context.Context, queue string) (*model.Message, error) {
+func (q *queueUC) Dequeue(ctx context.Context, queue string) (m *model.Message, err error) {
+	res := q.label(ctx, queue)
+	defer func(t time.Time) { metrics.Observe("dequeue", res, t, err) }(time.Now())
 	return q.msg.Dequeue(ctx, queue)
 }
 
-func (q *queueUC) Ack(ctx context.Context, queue string, id uuid.UUID) error {
+func (q *queueUC) Ack(ctx context.Context, queue string, id uuid.UUID) (err error) {
+	res := q.label(ctx, queue)
+	defer func(t time.Time) { metrics.Observe("ack", res, t, err) }(time.Now())
 	return q.msg.Ack(ctx, queue, id)
 }
+
+// label es la etiqueta de métricas de la cola: su nombre si el catálogo la
+// conoce, metrics.Unknown si no, para no crear una serie por nombre pedido.
+func (q *queueUC) label(ctx context.Context, queue string) string {
+	if _, err := q.meta.DescribeQueue(ctx, queue); err != nil {
+		return metrics.Unknown
+	}
+	return queue
+}
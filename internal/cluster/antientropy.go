@@ -0,0 +1,299 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	digestBuckets = 16  // hijos por nivel del árbol
+	digestLeaf    = 64  // ancho a partir del cual un rango distinto se copia entero
+	maxConflicts  = 100 // conflictos que se recuerdan para /cluster/status
+)
+
+/*──────────  resumen de un rango  ──────────*/
+
+// digest resume [from, to) en n rangos de igual ancho (el último puede ser
+// menor) con una sola lectura, por páginas, del store.
+func digest(ctx context.Context, store outbound.MessageStore, topic string, part int,
+	from, to uint64, n int) ([]*pb.RangeDigest, error) {
+
+	if to <= from || n <= 0 {
+		return nil, nil
+	}
+	width := (to - from + uint64(n) - 1) / uint64(n)
+	var out []*pb.RangeDigest
+	for lo := from; lo < to; lo += width {
+		out = append(out, &pb.RangeDigest{From: lo, To: min(lo+width, to)})
+	}
+
+	h := sha256.New()
+	var off [8]byte
+	i, next := 0, from
+	for next < to {
+		msgs, err := store.Read(ctx, topic, part, next, rangePage)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			if m.Offset >= to {
+				break
+			}
+			for m.Offset >= out[i].To {
+				out[i].Hash = h.Sum(nil)
+				h.Reset()
+				i++
+			}
+			binary.BigEndian.PutUint64(off[:], m.Offset)
+			h.Write(off[:])
+			h.Write([]byte(m.ID.String()))
+			h.Write(m.Payload)
+			out[i].Count++
+			next = m.Offset + 1
+		}
+		if len(msgs) < rangePage || (len(msgs) > 0 && msgs[len(msgs)-1].Offset >= to) {
+			break
+		}
+	}
+	for ; i < len(out); i++ {
+		out[i].Hash = h.Sum(nil)
+		h.Reset()
+	}
+	return out, nil
+}
+
+func (s *replicaSrv) Digest(ctx context.Context, in *pb.DigestRequest) (*pb.DigestReply, error) {
+	ctx, span := tracing.StartKind(tracing.IncomingGRPC(ctx), "replicaSrv.Digest", trace.SpanKindServer,
+		attribute.String("mom.topic", in.Topic), attribute.Int("mom.part", int(in.Part)))
+	defer span.End()
+
+	if in.Buckets == 0 {
+		st, err := s.store.PartitionStats(ctx, in.Topic, int(in.Part))
+		if err != nil {
+			return nil, err
+		}
+		return &pb.DigestReply{Low: st.StartOffset, End: st.EndOffset}, nil
+	}
+	ranges, err := digest(ctx, s.store, in.Topic, int(in.Part), in.From, in.To, int(in.Buckets))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DigestReply{Ranges: ranges}, nil
+}
+
+/*──────────  anti-entropía  ──────────*/
+
+// RangeStore sustituye un rango del log local por el de otra réplica:
+// AppendWithOffset no pisa offsets que ya existen.
+type RangeStore interface {
+	// ReplaceRange borra [from, to) de (topic, part) y escribe msgs; no
+	// toca el HWM.
+	ReplaceRange(ctx context.Context, topic string, part int, from, to uint64, msgs []model.Message) error
+}
+
+// AntiEntropy compara periódicamente cada partición de este nodo con la
+// réplica de referencia —el líder de la partición con consenso; sin él, el
+// primer nodo vivo de la configuración— mediante árboles de hashes por
+// rangos de offsets: baja sólo por los rangos que difieren y los vuelve a
+// copiar de la referencia. Sólo se compara lo que ambos tienen (por encima
+// de la retención y, con consenso, por debajo del commit).
+type AntiEntropy struct {
+	self  string
+	fan   *Fanout
+	cons  *Consensus // nil → particiones del catálogo
+	store outbound.MessageStore
+	fix   RangeStore
+	meta  outbound.MetaStore
+
+	mu        sync.Mutex
+	conflicts []model.Conflict // los últimos maxConflicts
+}
+
+// NewAntiEntropy devuelve nil en single-node.
+func NewAntiEntropy(self string, fan *Fanout, cons *Consensus,
+	store outbound.MessageStore, fix RangeStore, meta outbound.MetaStore) *AntiEntropy {
+
+	if fan == nil {
+		return nil
+	}
+	return &AntiEntropy{self: self, fan: fan, cons: cons, store: store, fix: fix, meta: meta}
+}
+
+// Start hace una pasada cada `every`, y otra en cuanto un nodo muerto
+// vuelve, hasta que se cancele ctx.
+func (a *AntiEntropy) Start(ctx context.Context, wg *sync.WaitGroup, every time.Duration) {
+	if a == nil {
+		return
+	}
+	back := a.fan.detector().Subscribe()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				a.Round(ctx)
+			case ev := <-back:
+				if ev.From == model.MemberDead && ev.To == model.MemberAlive {
+					a.Round(ctx)
+				}
+			}
+		}
+	}()
+}
+
+// Conflicts devuelve los últimos conflictos detectados, del más antiguo al
+// más reciente.
+func (a *AntiEntropy) Conflicts() []model.Conflict {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]model.Conflict(nil), a.conflicts...)
+}
+
+// target es una partición por revisar y hasta dónde (0 = sin límite).
+type target struct {
+	topic string
+	part  int
+	peer  string
+	limit uint64
+}
+
+// Round compara una vez todas las particiones.
+func (a *AntiEntropy) Round(ctx context.Context) {
+	defer func(t time.Time) { metrics.ReconcileDuration.Observe(time.Since(t).Seconds()) }(time.Now())
+	for _, t := range a.targets(ctx) {
+		if t.peer == "" || t.peer == a.self || !a.fan.detector().alive(t.peer) {
+			continue
+		}
+		if err := a.check(ctx, t); err != nil && ctx.Err() == nil {
+			log.Printf("[anti-entropy] %s:%d contra %s: %v", t.topic, t.part, t.peer, err)
+		}
+	}
+}
+
+func (a *AntiEntropy) targets(ctx context.Context) []target {
+	var out []target
+	if a.cons != nil {
+		for _, ps := range a.cons.all() {
+			ps.mu.Lock()
+			out = append(out, target{ps.topic, ps.part, ps.leader, ps.commit})
+			ps.mu.Unlock()
+		}
+		return out
+	}
+	ref := a.fan.Leader()
+	topics, _ := a.meta.ListTopics(ctx)
+	for _, tp := range topics {
+		parts, err := a.meta.GetTopic(ctx, tp) // nº de particiones
+		if err != nil {
+			continue
+		}
+		for p := 0; p < parts; p++ {
+			out = append(out, target{tp, p, ref, 0})
+		}
+	}
+	return out
+}
+
+func (a *AntiEntropy) check(ctx context.Context, t target) error {
+	cli := a.fan.client(t.peer)
+	if cli == nil {
+		return nil
+	}
+	remote, err := cli.Digest(tracing.OutgoingGRPC(ctx), &pb.DigestRequest{Topic: t.topic, Part: uint32(t.part)})
+	if err != nil {
+		return err
+	}
+	local, err := a.store.PartitionStats(ctx, t.topic, t.part)
+	if err != nil {
+		return err
+	}
+	from := max(remote.Low, local.StartOffset)
+	to := min(remote.End, local.EndOffset) // lo que falta al final es lag, no conflicto
+	if t.limit > 0 {
+		to = min(to, t.limit)
+	}
+	return a.compare(ctx, cli, t, from, to)
+}
+
+// compare baja por el árbol de [from, to): pide el resumen de sus hijos a
+// los dos lados y sigue sólo por los que difieren.
+func (a *AntiEntropy) compare(ctx context.Context, cli pb.ReplicatorClient, t target, from, to uint64) error {
+	if from >= to {
+		return nil
+	}
+	n := int(min(uint64(digestBuckets), to-from))
+	remote, err := cli.Digest(tracing.OutgoingGRPC(ctx), &pb.DigestRequest{
+		Topic: t.topic, Part: uint32(t.part), From: from, To: to, Buckets: uint32(n),
+	})
+	if err != nil {
+		return err
+	}
+	local, err := digest(ctx, a.store, t.topic, t.part, from, to, n)
+	if err != nil {
+		return err
+	}
+	for i, l := range local {
+		if i < len(remote.Ranges) {
+			r := remote.Ranges[i]
+			if r.From == l.From && r.To == l.To && r.Count == l.Count && bytes.Equal(r.Hash, l.Hash) {
+				continue
+			}
+		}
+		if l.To-l.From <= digestLeaf {
+			a.repair(ctx, t, l.From, l.To)
+			continue
+		}
+		if err := a.compare(ctx, cli, t, l.From, l.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repair vuelve a copiar [from, to) de la referencia y lo anota.
+func (a *AntiEntropy) repair(ctx context.Context, t target, from, to uint64) {
+	var msgs []model.Message // como mucho digestLeaf
+	err := a.fan.fetchRange(ctx, t.peer, t.topic, t.part, from, to, func(m *pb.Message) error {
+		msgs = append(msgs, FromPB(m))
+		return nil
+	})
+	if err == nil {
+		err = a.fix.ReplaceRange(ctx, t.topic, t.part, from, to, msgs)
+	}
+	c := model.Conflict{Topic: t.topic, Part: t.part, From: from, To: to, Peer: t.peer,
+		Repaired: err == nil, At: time.Now().UTC()}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	log.Printf("[anti-entropy] %s:%d [%d, %d) distinto de %s (reparado: %v)",
+		t.topic, t.part, from, to, t.peer, c.Repaired)
+	metrics.AntiEntropyConflicts.WithLabelValues(t.topic).Inc()
+
+	a.mu.Lock()
+	a.conflicts = append(a.conflicts, c)
+	if len(a.conflicts) > maxConflicts {
+		a.conflicts = a.conflicts[len(a.conflicts)-maxConflicts:]
+	}
+	a.mu.Unlock()
+}
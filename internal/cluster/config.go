@@ -1,29 +1,37 @@
 package cluster
 
 import (
-	"encoding/json"
 	"os"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Node describe a cada proceso del clúster.
 type Node struct {
-	ID   string `json:"id"`   // ej.: n1, n2 …
-	Host string `json:"host"` // host:port donde escucha gRPC
+	ID   string `json:"id" yaml:"id"`     // ej.: n1, n2 …
+	Host string `json:"host" yaml:"host"` // host:port donde escucha gRPC
+	// REST es el host:port HTTP del nodo; sólo hace falta para redirigir
+	// clientes al líder (cluster.forward = redirect).
+	REST string `json:"rest,omitempty" yaml:"rest,omitempty"`
 }
 
 // Config se carga desde cluster.yaml.
 type Config struct {
-	Nodes []Node `json:"nodes"`
+	Nodes []Node `json:"nodes" yaml:"nodes"`
 }
 
-// Load lee el archivo YAML/JSON indicado y lo decodifica.
+// Load lee el archivo YAML/JSON indicado y lo decodifica (el JSON es YAML
+// válido). Devuelve nil y el error si no se puede leer o parsear.
 func Load(path string) (*Config, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 	var c Config
-	return &c, json.Unmarshal(b, &c)
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
 }
 
 // Self devuelve la entrada del nodo que coincide con selfID.
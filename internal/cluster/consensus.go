@@ -0,0 +1,735 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+Log replicado por partición al estilo Raft:
+
+  - cada (topic, part) tiene un líder por término (epoch); sólo él asigna
+    offsets (Propose) y los envía en orden a los seguidores (Append);
+  - un seguidor sin noticias del líder durante ElectionTimeout pide votos
+    (Vote) y gana con la mayoría; sólo se vota a quien tenga un log al
+    menos tan al día como el propio;
+  - una entrada se compromete cuando la tiene la mayoría y es del término
+    vigente; los consumidores sólo leen hasta ahí (Committed);
+  - si el tópico tiene réplicas asignadas (model.Topic.Replicas), sólo esos
+    nodos guardan la partición, votan y cuentan para la mayoría; los
+    cambios de réplicas pasan por learners y una configuración conjunta
+    (reconfig.go).
+
+El estado del consenso vive en la instancia y no usa los globales del
+paquete (el HWM en RAM de hwm.go, CurrentConfig, GlobalSelfID), que sólo
+alimentan métricas, /cluster/status y la API admin: así los tests levantan
+varios nodos en un mismo proceso.
+*/
+
+// TermStore persiste término y voto por partición: deben sobrevivir a un
+// reinicio para no votar dos veces en el mismo término.
+// También guarda, ya serializada, la configuración de votantes (nil si no
+// hay ninguna).
+type TermStore interface {
+	LoadTerm(topic string, part int) (epoch uint64, voted string, err error)
+	SaveTerm(topic string, part int, epoch uint64, voted string) error
+	LoadConfig(topic string, part int) ([]byte, error)
+	SaveConfig(topic string, part int, raw []byte) error
+}
+
+// ConsensusOptions ajusta tiempos y quórum.
+type ConsensusOptions struct {
+	Quorum          int           // réplicas (con el líder) que confirman un publish; 0 = mayoría
+	ElectionTimeout time.Duration // sin latidos del líder → elección
+	Heartbeat       time.Duration
+	CommitTimeout   time.Duration // espera máxima del quórum en Propose
+	MaxBatch        int           // entradas por Append
+	// MaxBatchBytes acota el tamaño de un Append (siempre lleva al menos
+	// una entrada): gRPC rechaza los mensajes de más de 4 MiB.
+	MaxBatchBytes int
+	// CatchUpRate limita los bytes/s que se envían a una réplica nueva
+	// (learner) mientras se pone al día; 0 = sin límite.
+	CatchUpRate int64
+}
+
+// NotLeaderError indica que este nodo no lidera la partición; Leader es
+// el líder conocido ("" si hay elección en curso).
+type NotLeaderError struct {
+	Topic  string
+	Part   int
+	Leader string
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.Leader == "" {
+		return fmt.Sprintf("partition %s:%d has no leader yet", e.Topic, e.Part)
+	}
+	return fmt.Sprintf("node is not the leader of %s:%d (leader: %s)", e.Topic, e.Part, e.Leader)
+}
+
+func (e *NotLeaderError) Unwrap() error { return errs.ErrUnavailable }
+
+// NotReplicaError indica que este nodo no guarda la partición; Replica es
+// la réplica a la que llevar la lectura ("" si no hay ninguna viva).
+type NotReplicaError struct {
+	Topic    string
+	Part     int
+	Replicas []string
+	Replica  string
+}
+
+func (e *NotReplicaError) Error() string {
+	return fmt.Sprintf("partition %s:%d is not stored on this node (replicas: %s)",
+		e.Topic, e.Part, strings.Join(e.Replicas, ", "))
+}
+
+func (e *NotReplicaError) Unwrap() error { return errs.ErrUnavailable }
+
+// partState es el estado Raft de una partición en este nodo.
+type partState struct {
+	topic string
+	part  int
+
+	mu        sync.Mutex
+	target    []string // réplicas que pide el catálogo; nil → todos
+	placed    bool     // target ya viene del catálogo
+	conf      partConfig
+	prev      *partConfig // la anterior, por si se trunca el registro de conf
+	known     bool        // conf viene del log, del catálogo o del disco
+	legacy    bool        // tenía datos sin configuración guardada
+	epoch     uint64
+	voted     string
+	leader    string // "" si se desconoce
+	end       uint64 // próximo offset del log local
+	lastEpoch uint64 // término de la última entrada local
+	commit    uint64 // próximo offset no comprometido
+	deadline  time.Time
+	heard     time.Time // último Append del líder
+	electing  bool
+	pulling   bool // pull trae lo comprometido por StreamRange
+
+	// sólo como líder
+	epochStart uint64            // offset del registro de control del término
+	match      map[string]uint64 // peer → end confirmado
+	loops      map[string]bool   // bucles de réplica activos
+	stop       context.CancelFunc
+
+	changed chan struct{} // se cierra (y renueva) en cada cambio
+}
+
+// notify despierta a quien espera en changed; requiere ps.mu.
+func (ps *partState) notify() {
+	close(ps.changed)
+	ps.changed = make(chan struct{})
+}
+
+// Consensus gestiona los logs replicados de todas las particiones.
+type Consensus struct {
+	self  string
+	fan   *Fanout
+	store outbound.MessageStore
+	terms TermStore
+	opts  ConsensusOptions
+
+	mu    sync.Mutex
+	parts map[string]*partState
+	ctx   context.Context
+	wg    *sync.WaitGroup // cuenta los bucles, réplicas y elecciones
+
+	// Drain: closing rechaza propuestas nuevas, proposals cuenta las que
+	// están en curso y abort corta sus esperas de acks.
+	closing   bool
+	proposals sync.WaitGroup
+	abort     chan struct{}
+}
+
+func NewConsensus(self string, fan *Fanout, store outbound.MessageStore,
+	terms TermStore, opts ConsensusOptions) *Consensus {
+
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = 500
+	}
+	if opts.MaxBatchBytes <= 0 {
+		opts.MaxBatchBytes = rangeChunkMax
+	}
+	return &Consensus{self: self, fan: fan, store: store, terms: terms, opts: opts,
+		parts: map[string]*partState{}, ctx: context.Background(), wg: &sync.WaitGroup{},
+		abort: make(chan struct{})}
+}
+
+// Start lanza el bucle de latidos/elecciones; meta se usa para descubrir
+// las particiones. Todo se detiene al cancelarse ctx y wg cuenta cada
+// goroutine que se lanza desde aquí. Debe llamarse antes de servir gRPC:
+// HandleAppend y HandleVote ya usan ctx y wg.
+func (c *Consensus) Start(ctx context.Context, wg *sync.WaitGroup, meta outbound.MetaStore) {
+	if c == nil {
+		return
+	}
+	c.ctx, c.wg = ctx, wg
+	c.spawn(func() { c.run(ctx, meta) })
+}
+
+// spawn lanza f en segundo plano contada en c.wg.
+func (c *Consensus) spawn(f func()) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		f()
+	}()
+}
+
+// Drain deja de aceptar propuestas y espera a que terminen las que están
+// en curso, con su espera de acks, o a que venza ctx; entonces corta las
+// esperas que queden (devuelven Unavailable) y devuelve ctx.Err(). Lo ya
+// escrito en el líder se sigue replicando hasta que se cancela Start.
+func (c *Consensus) Drain(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	if c.closing {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closing = true
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.proposals.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		close(c.abort)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// enter registra una propuesta; false si el nodo se está apagando.
+func (c *Consensus) enter() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return false
+	}
+	c.proposals.Add(1)
+	return true
+}
+
+/*──────────  estado por partición  ──────────*/
+
+func (c *Consensus) state(topic string, part int) *partState {
+	k := topic + ":" + strconv.Itoa(part)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ps := c.parts[k]; ps != nil {
+		return ps
+	}
+	ps := &partState{topic: topic, part: part, changed: make(chan struct{})}
+	ps.epoch, ps.voted, _ = c.terms.LoadTerm(topic, part)
+	ps.end, _ = c.store.HighWatermark(c.ctx, topic, part)
+	if ps.end > 0 {
+		ps.lastEpoch, _ = c.epochOf(c.ctx, topic, part, ps.end-1)
+	}
+	c.loadConfig(ps)
+	ps.legacy = ps.end > 0 && !ps.known
+	ps.deadline = time.Now().Add(c.timeout(ps))
+	c.parts[k] = ps
+	return ps
+}
+
+// place fija las réplicas que pide el catálogo para las particiones de
+// topic (nil → todos los nodos) y crea su estado; el líder hace el cambio
+// (reconfigure). Con seed, voters son los votantes de partida (los de la
+// creación o los de antes de una reasignación) para el nodo que aún no
+// conoce la configuración; sin él sólo se toman del catálogo si el nodo
+// tenía datos de antes de que se guardara.
+func (c *Consensus) place(topic string, parts int, replicas, voters [][]string, seed bool) {
+	at := func(rs [][]string, p int) []string {
+		if p < len(rs) {
+			return rs[p]
+		}
+		return nil
+	}
+	for p := 0; p < parts; p++ {
+		reps := at(replicas, p)
+		ps := c.state(topic, p)
+		ps.mu.Lock()
+		if !ps.known && (seed || ps.legacy) {
+			ps.conf, ps.known = partConfig{Voters: slices.Clone(at(voters, p))}, true
+			c.saveConfig(ps)
+		}
+		if !ps.placed || !slices.Equal(ps.target, reps) || (ps.target == nil) != (reps == nil) {
+			ps.target, ps.placed = slices.Clone(reps), true
+			ps.notify()
+		}
+		ps.mu.Unlock()
+	}
+}
+
+func (c *Consensus) all() []*partState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*partState, 0, len(c.parts))
+	for _, ps := range c.parts {
+		out = append(out, ps)
+	}
+	return out
+}
+
+// epochOf devuelve el término de la entrada off; ok = false si no está
+// (p. ej. la borró la retención).
+func (c *Consensus) epochOf(ctx context.Context, topic string, part int, off uint64) (uint64, bool) {
+	msgs, err := c.store.Read(ctx, topic, part, off, 1)
+	if err != nil || len(msgs) == 0 || msgs[0].Offset != off {
+		return 0, false
+	}
+	return msgs[0].Epoch, true
+}
+
+// timeout es aleatorio para que no empiecen todos la elección a la vez; el
+// nodo preferido (preferred) espera menos y suele ganar; requiere ps.mu.
+func (c *Consensus) timeout(ps *partState) time.Duration {
+	base := c.opts.ElectionTimeout
+	if c.preferred(ps) == c.self {
+		return base/2 + time.Duration(rand.Int63n(int64(base/2)+1))
+	}
+	return base + time.Duration(rand.Int63n(int64(base)+1))
+}
+
+/*──────────  réplicas de la partición  ──────────*/
+
+// hosts indica si este nodo guarda la partición: vota o es una de las
+// réplicas que pide el catálogo; requiere ps.mu.
+func (c *Consensus) hosts(ps *partState) bool {
+	return c.voter(ps) || ps.target == nil || slices.Contains(ps.target, c.self)
+}
+
+// peers devuelve las réplicas conectadas, votantes o learners, sin la
+// propia; requiere ps.mu.
+func (c *Consensus) peers(ps *partState) []string {
+	all := c.fan.Peers()
+	if ps.target == nil && (!ps.known || ps.conf.Voters == nil) {
+		return all
+	}
+	out := make([]string, 0, len(all))
+	for _, id := range all {
+		if ps.target == nil || slices.Contains(ps.target, id) || (ps.known && c.votes(ps, id)) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// electors son los peers conectados que votan; requiere ps.mu.
+func (c *Consensus) electors(ps *partState) []string {
+	var out []string
+	for _, id := range c.fan.Peers() {
+		if c.votes(ps, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// preferred es el líder preferido: la primera réplica viva o, sin
+// asignación, Fanout.Leader; requiere ps.mu.
+func (c *Consensus) preferred(ps *partState) string {
+	if ps.target == nil {
+		return c.fan.Leader()
+	}
+	for _, id := range ps.target {
+		if id == c.self || c.fan.detector().alive(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// clusterSize es el nº de votantes de la partición; requiere ps.mu.
+func (c *Consensus) clusterSize(ps *partState) int { return len(c.members(ps.conf.Voters)) }
+
+// ackQuorum es Quorum acotado a [mayoría, nº de réplicas]; requiere ps.mu.
+func (c *Consensus) ackQuorum(ps *partState) int {
+	q, n := c.opts.Quorum, c.clusterSize(ps)
+	if q < n/2+1 {
+		q = n/2 + 1
+	}
+	if q > n {
+		q = n
+	}
+	return q
+}
+
+// stepDown pasa a seguidor (y adopta epoch si es mayor); requiere ps.mu.
+func (c *Consensus) stepDown(ps *partState, epoch uint64) {
+	if epoch > ps.epoch {
+		ps.epoch, ps.voted = epoch, ""
+		if err := c.terms.SaveTerm(ps.topic, ps.part, ps.epoch, ps.voted); err != nil {
+			log.Printf("[raft] %s:%d guardar término: %v", ps.topic, ps.part, err)
+		}
+	}
+	// el plazo de elección sólo se reinicia al votar o al oír al líder: si
+	// no, un candidato que no puede ganar bloquearía al resto
+	if ps.leader == c.self {
+		ps.stop()
+		ps.match, ps.loops = nil, nil
+		ps.deadline = time.Now().Add(c.timeout(ps))
+		log.Printf("[raft] %s:%d deja de ser líder (término %d)", ps.topic, ps.part, ps.epoch)
+	}
+	ps.leader = ""
+	ps.notify()
+}
+
+/*──────────  API para los use-cases  ──────────*/
+
+// Propose agrega m al log de su partición si este nodo es el líder. Con
+// acks=all espera además a que lo tengan ackQuorum réplicas; si vence el
+// plazo el mensaje queda escrito en el líder y puede comprometerse más
+// tarde (*model.ReplicationError).
+func (c *Consensus) Propose(ctx context.Context, m model.Message, acks model.Acks) (off uint64, err error) {
+	ctx, span := tracing.Start(ctx, "Consensus.Propose",
+		attribute.String("mom.topic", m.Topic), attribute.Int("mom.partition", m.PartID))
+	defer func() { tracing.End(span, err) }()
+
+	if !c.enter() {
+		return 0, errs.Unavailable("node %s is shutting down", c.self)
+	}
+	defer c.proposals.Done()
+
+	ps := c.state(m.Topic, m.PartID)
+	ps.mu.Lock()
+	if err := c.notHosted(ps); err != nil {
+		ps.mu.Unlock()
+		return 0, err
+	}
+	c.awaitLeader(ctx, ps)
+	if ps.leader != c.self {
+		leader := ps.leader
+		ps.mu.Unlock()
+		return 0, &NotLeaderError{Topic: m.Topic, Part: m.PartID, Leader: leader}
+	}
+	epoch := ps.epoch
+	m.Epoch = epoch
+	off, err = c.store.Append(ctx, m) // bajo ps.mu: un solo escritor por partición
+	if err != nil {
+		ps.mu.Unlock()
+		return 0, err
+	}
+	ps.end, ps.lastEpoch = off+1, epoch
+	c.advanceCommit(ps)
+	ps.notify()
+	ps.mu.Unlock()
+
+	if acks != model.AcksAll {
+		return off, nil
+	}
+	return off, c.waitAcks(ctx, ps, epoch, off+1)
+}
+
+func (c *Consensus) waitAcks(ctx context.Context, ps *partState, epoch, target uint64) error {
+	timer := time.NewTimer(c.opts.CommitTimeout)
+	defer timer.Stop()
+	for {
+		ps.mu.Lock()
+		need := c.ackQuorum(ps)
+		if ps.leader != c.self || ps.epoch != epoch {
+			ps.mu.Unlock()
+			return errs.Unavailable("lost leadership of %s:%d before offset %d was replicated",
+				ps.topic, ps.part, target-1)
+		}
+		acks := 1
+		for id, end := range ps.match {
+			if end >= target && c.votes(ps, id) {
+				acks++
+			}
+		}
+		ok := acks >= need && c.quorate(ps, func(id string) bool { return c.matched(ps, id) >= target })
+		ch := ps.changed
+		ps.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.abort:
+			return errs.Unavailable("node %s shut down before %s:%d offset %d was replicated",
+				c.self, ps.topic, ps.part, target-1)
+		case <-timer.C:
+			return &model.ReplicationError{Topic: ps.topic, Part: ps.part, Offset: target - 1,
+				Acked: acks, Required: need, Timeout: c.opts.CommitTimeout}
+		}
+	}
+}
+
+// CheckLeader devuelve *NotLeaderError si este nodo no lidera la
+// partición; nil si la lidera. Durante una elección espera a que termine.
+func (c *Consensus) CheckLeader(ctx context.Context, topic string, part int) error {
+	ps := c.state(topic, part)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if err := c.notHosted(ps); err != nil {
+		return err
+	}
+	c.awaitLeader(ctx, ps)
+	if ps.leader != c.self {
+		return &NotLeaderError{Topic: topic, Part: part, Leader: ps.leader}
+	}
+	return nil
+}
+
+// notHosted devuelve *NotLeaderError con el líder preferido si este nodo
+// no guarda la partición: no hay líder que esperar; requiere ps.mu.
+func (c *Consensus) notHosted(ps *partState) error {
+	if c.hosts(ps) {
+		return nil
+	}
+	return &NotLeaderError{Topic: ps.topic, Part: ps.part, Leader: c.preferred(ps)}
+}
+
+// CheckReplica devuelve *NotReplicaError si este nodo no guarda la
+// partición (sus lecturas hay que hacerlas en una de sus réplicas).
+func (c *Consensus) CheckReplica(topic string, part int) error {
+	ps := c.state(topic, part)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if c.hosts(ps) {
+		return nil
+	}
+	return &NotReplicaError{Topic: topic, Part: part, Replicas: slices.Clone(ps.target), Replica: c.preferred(ps)}
+}
+
+// awaitLeader espera, como mucho dos plazos de elección, a que la
+// partición tenga líder (p. ej. recién creada o tras caer el anterior);
+// requiere ps.mu, que suelta mientras espera.
+func (c *Consensus) awaitLeader(ctx context.Context, ps *partState) {
+	deadline := time.Now().Add(2 * c.opts.ElectionTimeout)
+	for ps.leader == "" && ctx.Err() == nil {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return
+		}
+		ch := ps.changed
+		ps.mu.Unlock()
+		t := time.NewTimer(wait)
+		select {
+		case <-ch:
+		case <-ctx.Done():
+		case <-t.C:
+		}
+		t.Stop()
+		ps.mu.Lock()
+	}
+}
+
+// Committed devuelve el próximo offset no comprometido: los consumidores
+// no deben ver nada a partir de ahí.
+func (c *Consensus) Committed(topic string, part int) uint64 {
+	ps := c.state(topic, part)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.commit
+}
+
+// Leaders devuelve "topic:part" → líder conocido ("" durante elecciones).
+func (c *Consensus) Leaders() map[string]string {
+	out := map[string]string{}
+	if c == nil {
+		return out
+	}
+	for _, ps := range c.all() {
+		ps.mu.Lock()
+		out[ps.topic+":"+strconv.Itoa(ps.part)] = ps.leader
+		ps.mu.Unlock()
+	}
+	return out
+}
+
+/*──────────  líder  ──────────*/
+
+// becomeLeader requiere ps.mu. Escribe un registro de control con el
+// término nuevo: Raft sólo compromete entradas del término vigente, y así
+// lo heredado de términos anteriores se compromete sin esperar a un publish.
+// Lleva también la configuración vigente, para los nodos que no la conocen.
+// Backlog devuelve, de cada partición que lidera este nodo, cuántas
+// entradas le faltan por confirmar a cada réplica (end del líder menos su
+// match). El log del líder hace de cola persistente por peer: se reenvía
+// en orden desde match en cada latido y se vacía cuando el peer vuelve.
+func (c *Consensus) Backlog() map[string]map[string]uint64 {
+	out := map[string]map[string]uint64{}
+	if c == nil {
+		return out
+	}
+	for _, ps := range c.all() {
+		ps.mu.Lock()
+		if ps.leader == c.self {
+			peers := map[string]uint64{}
+			for _, id := range c.peers(ps) {
+				peers[id] = ps.end - min(ps.match[id], ps.end)
+			}
+			out[ps.topic+":"+strconv.Itoa(ps.part)] = peers
+		}
+		ps.mu.Unlock()
+	}
+	return out
+}
+
+// RegisterBacklogGauge publica Backlog de c como
+// mom_replication_outbox_depth.
+func RegisterBacklogGauge(c *Consensus) { prometheus.MustRegister(backlogGauge(c)) }
+
+func backlogGauge(c *Consensus) prometheus.Collector {
+	return metrics.GaugeFunc("mom_replication_outbox_depth",
+		"Log entries a follower has not confirmed yet, per partition this node leads.",
+		[]string{"topic", "partition", "peer"}, func() []metrics.Sample {
+			var out []metrics.Sample
+			for k, peers := range c.Backlog() {
+				i := strings.LastIndexByte(k, ':')
+				for id, n := range peers {
+					out = append(out, metrics.Sample{Labels: []string{k[:i], k[i+1:], id}, Value: float64(n)})
+				}
+			}
+			return out
+		})
+}
+
+func (c *Consensus) becomeLeader(ps *partState) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	ps.leader, ps.stop = c.self, cancel
+	ps.match, ps.loops = map[string]uint64{}, map[string]bool{}
+
+	off, err := c.appendControl(ctx, ps, "leader-epoch", ps.conf)
+	if err != nil {
+		log.Printf("[raft] %s:%d registro de control: %v", ps.topic, ps.part, err)
+		c.stepDown(ps, ps.epoch)
+		return
+	}
+	ps.epochStart = off
+	log.Printf("[raft] %s:%d líder en término %d (end %d)", ps.topic, ps.part, ps.epoch, ps.end)
+	c.ensureLoops(ps)
+	c.advanceCommit(ps)
+	ps.notify()
+}
+
+// appendControl escribe como líder un registro de control kind que lleva
+// conf; requiere ps.mu.
+func (c *Consensus) appendControl(ctx context.Context, ps *partState, kind string, conf partConfig) (uint64, error) {
+	conf.End = 0
+	payload, _ := json.Marshal(conf)
+	off, err := c.store.Append(ctx, model.Message{
+		ID: uuid.New(), Topic: ps.topic, PartID: ps.part, Epoch: ps.epoch, Producer: c.self,
+		Payload: payload, Headers: map[string]string{model.ControlHeader: kind},
+	})
+	if err != nil {
+		return 0, err
+	}
+	ps.end, ps.lastEpoch = off+1, ps.epoch
+	return off, nil
+}
+
+// ensureLoops arranca un bucle de réplica por cada peer actual que no lo
+// tenga (la membresía puede crecer en caliente); requiere ps.mu.
+func (c *Consensus) ensureLoops(ps *partState) {
+	for _, id := range c.peers(ps) {
+		if !ps.loops[id] {
+			ps.loops[id] = true
+			epoch := ps.epoch
+			c.spawn(func() { c.replicate(ps, id, epoch) })
+		}
+	}
+}
+
+// advanceCommit sube commit al mayor offset que tiene la mayoría (en los
+// dos conjuntos durante un cambio), siempre que sea del término vigente;
+// requiere ps.mu.
+func (c *Consensus) advanceCommit(ps *partState) {
+	ends := []uint64{ps.end}
+	for _, n := range ps.match {
+		ends = append(ends, n)
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i] > ends[j] })
+	for _, n := range ends {
+		if n <= ps.commit || n <= ps.epochStart {
+			return
+		}
+		if c.quorate(ps, func(id string) bool { return c.matched(ps, id) >= n }) {
+			ps.commit = n
+			ps.notify()
+			return
+		}
+	}
+}
+
+/*──────────  bucle de latidos y elecciones  ──────────*/
+
+func (c *Consensus) run(ctx context.Context, meta outbound.MetaStore) {
+	t := time.NewTicker(c.opts.Heartbeat)
+	defer t.Stop()
+	for tick := 0; ; tick++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if tick%10 == 0 {
+			c.discover(ctx, meta)
+		}
+		now := time.Now()
+		for _, ps := range c.all() {
+			ps.mu.Lock()
+			if ps.leader == c.self {
+				c.ensureLoops(ps)
+				c.reconfigure(ps)
+			}
+			due := ps.leader != c.self && !ps.electing && c.voter(ps) && now.After(ps.deadline)
+			if due {
+				ps.electing = true
+			}
+			ps.mu.Unlock()
+			if due {
+				c.spawn(func() { c.elect(ctx, ps) })
+			}
+		}
+	}
+}
+
+// discover crea el estado de las particiones del catálogo local, con sus
+// réplicas; las que sólo existen en otros nodos aparecen al recibir su
+// primer Append/Vote.
+func (c *Consensus) discover(ctx context.Context, meta outbound.MetaStore) {
+	topics, err := meta.ListTopics(ctx)
+	if err != nil {
+		return
+	}
+	for _, name := range topics {
+		t, err := meta.DescribeTopic(ctx, name)
+		if err != nil {
+			continue
+		}
+		c.place(name, t.Partitions, t.Replicas, t.Replicas, false)
+	}
+}
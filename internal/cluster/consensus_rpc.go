@@ -0,0 +1,431 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+/*──────────  elección (candidato)  ──────────*/
+
+func (c *Consensus) elect(ctx context.Context, ps *partState) {
+	defer func() {
+		ps.mu.Lock()
+		ps.electing = false
+		ps.mu.Unlock()
+	}()
+
+	ps.mu.Lock()
+	ps.epoch++
+	ps.voted, ps.leader = c.self, ""
+	ps.deadline = time.Now().Add(c.timeout(ps))
+	epoch := ps.epoch
+	peers := c.electors(ps)
+	req := &pb.VoteRequest{Topic: ps.topic, Part: uint32(ps.part), Epoch: epoch,
+		Candidate: c.self, End: ps.end, LastEpoch: ps.lastEpoch}
+	err := c.terms.SaveTerm(ps.topic, ps.part, epoch, c.self)
+	ps.mu.Unlock()
+	if err != nil {
+		log.Printf("[raft] %s:%d guardar término: %v", ps.topic, ps.part, err)
+		return
+	}
+
+	type vote struct {
+		id string
+		r  *pb.VoteReply
+	}
+	replies := make(chan vote, len(peers))
+	for _, id := range peers {
+		go func(id string) {
+			cli := c.fan.client(id)
+			if cli == nil {
+				replies <- vote{id, nil}
+				return
+			}
+			rctx, cancel := context.WithTimeout(ctx, c.opts.ElectionTimeout/2)
+			defer cancel()
+			r, err := cli.Vote(rctx, req)
+			if err != nil {
+				r = nil
+			}
+			replies <- vote{id, r}
+		}(id)
+	}
+
+	granted := map[string]bool{c.self: true}
+	for range peers {
+		v := <-replies
+		r := v.r
+		if r == nil {
+			continue
+		}
+		if r.Epoch > epoch {
+			ps.mu.Lock()
+			c.stepDown(ps, r.Epoch)
+			ps.mu.Unlock()
+			return
+		}
+		if r.Granted {
+			granted[v.id] = true
+		}
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	won := c.quorate(ps, func(id string) bool { return granted[id] })
+	if won && ps.epoch == epoch && ps.leader == "" && c.voter(ps) {
+		c.becomeLeader(ps)
+	}
+}
+
+// HandleVote atiende Replicator.Vote.
+func (c *Consensus) HandleVote(req *pb.VoteRequest) *pb.VoteReply {
+	ps := c.state(req.Topic, int(req.Part))
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	// con un líder vivo se ignora la petición sin adoptar su término: un
+	// nodo que vuelve atrasado no debe derribar al líder en cada intento
+	sticky := ps.leader == c.self ||
+		(ps.leader != "" && time.Since(ps.heard) < c.opts.ElectionTimeout)
+	if req.Epoch < ps.epoch || sticky {
+		return &pb.VoteReply{Epoch: ps.epoch}
+	}
+	if req.Epoch > ps.epoch {
+		c.stepDown(ps, req.Epoch)
+	}
+	upToDate := req.LastEpoch > ps.lastEpoch ||
+		(req.LastEpoch == ps.lastEpoch && req.End >= ps.end)
+	if (ps.voted != "" && ps.voted != req.Candidate) || !upToDate {
+		return &pb.VoteReply{Epoch: ps.epoch}
+	}
+	ps.voted = req.Candidate
+	if err := c.terms.SaveTerm(ps.topic, ps.part, ps.epoch, ps.voted); err != nil {
+		return &pb.VoteReply{Epoch: ps.epoch}
+	}
+	ps.deadline = time.Now().Add(c.timeout(ps))
+	return &pb.VoteReply{Epoch: ps.epoch, Granted: true}
+}
+
+/*──────────  réplica (líder → seguidor)  ──────────*/
+
+// replicate envía al peer las entradas que le faltan, en orden, y latidos
+// cuando está al día. Mientras un learner (réplica nueva que aún no vota)
+// se pone al día el envío se limita a CatchUpRate. Termina al perder el
+// liderazgo del término epoch o si el peer sale del clúster o de las
+// réplicas de la partición.
+func (c *Consensus) replicate(ps *partState, peer string, epoch uint64) {
+	defer func() {
+		ps.mu.Lock()
+		if ps.loops != nil && ps.epoch == epoch {
+			delete(ps.loops, peer)
+		}
+		ps.mu.Unlock()
+	}()
+
+	ps.mu.Lock()
+	next := ps.end // optimista: se retrocede si el seguidor no coincide
+	ps.mu.Unlock()
+	hb := time.NewTicker(c.opts.Heartbeat)
+	defer hb.Stop()
+
+	for {
+		cli := c.fan.client(peer)
+		if cli == nil {
+			return
+		}
+		ps.mu.Lock()
+		if ps.leader != c.self || ps.epoch != epoch || !slices.Contains(c.peers(ps), peer) {
+			ps.mu.Unlock()
+			return
+		}
+		ctx, end, commit := c.ctx, ps.end, ps.commit
+		ps.mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if next > end {
+			next = end
+		}
+		var msgs []model.Message
+		if next < end {
+			var err error
+			if msgs, err = c.store.Read(ctx, ps.topic, ps.part, next, c.opts.MaxBatch); err != nil {
+				log.Printf("[raft] %s:%d leer desde %d: %v", ps.topic, ps.part, next, err)
+			}
+		}
+		// la retención ya borró lo que le falta al peer: descarta su log y
+		// sigue desde el inicio del nuestro (resetHeader)
+		reset := len(msgs) > 0 && msgs[0].Offset > next
+		if reset {
+			next = msgs[0].Offset
+		}
+		req := &pb.AppendRequest{Topic: ps.topic, Part: uint32(ps.part), Epoch: epoch,
+			Leader: c.self, From: next, Commit: commit}
+		if next > 0 && !reset {
+			req.PrevEpoch, _ = c.epochOf(ctx, ps.topic, ps.part, next-1)
+		}
+		var size int64
+		bytes := 0
+		for i, m := range msgs {
+			if m.Offset != next+uint64(i) { // hueco: se corta el lote
+				break
+			}
+			e := ToPB(m)
+			if bytes += proto.Size(e); i > 0 && bytes > c.opts.MaxBatchBytes {
+				break
+			}
+			req.Entries = append(req.Entries, e)
+			size += int64(len(m.Payload))
+		}
+
+		rctx, cancel := context.WithTimeout(ctx, c.opts.ElectionTimeout)
+		if reset {
+			rctx = metadata.AppendToOutgoingContext(rctx, resetHeader, strconv.FormatUint(next, 10))
+		}
+		r, err := cli.Append(tracing.OutgoingGRPC(rctx), req)
+		cancel()
+		if err != nil {
+			metrics.ReplicationErrors.WithLabelValues(peer).Inc()
+			select {
+			case <-ctx.Done():
+				return
+			case <-hb.C:
+			}
+			continue
+		}
+
+		pulling := false
+		ps.mu.Lock()
+		if r.Epoch > ps.epoch {
+			c.stepDown(ps, r.Epoch)
+			ps.mu.Unlock()
+			return
+		}
+		if ps.leader != c.self || ps.epoch != epoch {
+			ps.mu.Unlock()
+			return
+		}
+		if r.Ok {
+			next = r.End
+			if next > ps.match[peer] {
+				ps.match[peer] = next
+				c.advanceCommit(ps)
+				c.reconfigure(ps)
+				ps.notify()
+			}
+		} else if r.End < next {
+			next = r.End
+		} else {
+			// el seguidor se está poniendo al día con pull: se espera al
+			// próximo latido y se sigue desde donde llegó
+			next, pulling = r.End, true
+		}
+		behind := (next < ps.end || !r.Ok) && !pulling
+		learner := !c.votes(ps, peer)
+		ch := ps.changed
+		ps.mu.Unlock()
+		if behind {
+			if learner {
+				c.throttle(ctx, size)
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		case <-hb.C:
+		}
+	}
+}
+
+// HandleAppend atiende Replicator.Append en el seguidor.
+func (c *Consensus) HandleAppend(ctx context.Context, req *pb.AppendRequest) (*pb.AppendReply, error) {
+	ps := c.state(req.Topic, int(req.Part))
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if req.Epoch < ps.epoch {
+		return &pb.AppendReply{Epoch: ps.epoch, End: ps.end}, nil
+	}
+	if req.Epoch > ps.epoch || ps.leader == c.self {
+		c.stepDown(ps, req.Epoch)
+	}
+	ps.leader, ps.heard = req.Leader, time.Now()
+	ps.deadline = ps.heard.Add(c.timeout(ps))
+	if ps.pulling {
+		return &pb.AppendReply{Epoch: ps.epoch, End: ps.end}, nil
+	}
+
+	// el líder ya no tiene lo que nos falta: se descarta el log local y se
+	// sigue desde el inicio del suyo, como tras instalar un snapshot
+	if req.From > ps.end && len(req.Entries) > 0 && resetFrom(ctx) == req.From {
+		log.Printf("[raft] %s:%d el líder empieza en %d y el log local acaba en %d: se descarta",
+			req.Topic, req.Part, req.From, ps.end)
+		if err := c.store.Truncate(ctx, req.Topic, int(req.Part), 0); err != nil {
+			return nil, err
+		}
+		ps.end = req.From
+	}
+
+	// ¿coincide el prefijo?
+	if req.From > ps.end {
+		c.catchUp(ps, req.Leader, req.Commit)
+		return &pb.AppendReply{Epoch: ps.epoch, End: ps.end}, nil
+	}
+	if req.From > 0 {
+		// si la entrada ya no está (retención) se da por buena
+		if e, ok := c.epochOf(ctx, req.Topic, int(req.Part), req.From-1); ok && e != req.PrevEpoch {
+			return &pb.AppendReply{Epoch: ps.epoch, End: req.From - 1}, nil
+		}
+	}
+
+	for i, e := range req.Entries {
+		off := req.From + uint64(i)
+		if off < ps.end {
+			if ep, ok := c.epochOf(ctx, req.Topic, int(req.Part), off); ok && ep == e.Epoch {
+				continue // ya la tenía
+			}
+			// conflicto: se descarta lo que el líder no tiene
+			if off < ps.commit {
+				log.Printf("[raft] %s:%d truncando por debajo de commit (%d < %d)",
+					req.Topic, req.Part, off, ps.commit)
+			}
+			if err := c.store.Truncate(ctx, req.Topic, int(req.Part), off); err != nil {
+				return nil, err
+			}
+			ps.end = off
+			c.truncated(ps, off)
+		}
+		m := FromPB(e)
+		m.Offset = off
+		if err := c.store.AppendWithOffset(ctx, m); err != nil {
+			return nil, err
+		}
+		ps.end, ps.lastEpoch = off+1, e.Epoch
+		if m.IsControl() {
+			if conf, ok := configOf(m.Payload); ok {
+				c.adopt(ps, conf, off)
+			}
+		}
+	}
+
+	last := req.From + uint64(len(req.Entries))
+	if commit := min(req.Commit, last); commit > ps.commit {
+		ps.commit = commit
+		ps.notify()
+	}
+	c.catchUp(ps, req.Leader, req.Commit)
+	return &pb.AppendReply{Epoch: ps.epoch, Ok: true, End: last}, nil
+}
+
+// catchUp lanza pull si a este nodo le faltan más de MaxBatch entradas
+// comprometidas, en vez de esperarlas lote a lote; requiere ps.mu.
+func (c *Consensus) catchUp(ps *partState, leader string, commit uint64) {
+	if ps.pulling || commit <= ps.end+uint64(c.opts.MaxBatch) {
+		return
+	}
+	ps.pulling = true
+	from := ps.end
+	c.spawn(func() { c.pull(ps, leader, from) })
+}
+
+// errDiverged corta pull cuando el log local no es un prefijo del líder.
+var errDiverged = errors.New("log diverges from the leader")
+
+// pull copia de leader, por StreamRange, lo comprometido desde from. Pide
+// también la entrada from-1: si su término no es el local los logs
+// divergen y se deja a Append resolver el conflicto. Mientras dura,
+// HandleAppend sólo atiende latidos.
+func (c *Consensus) pull(ps *partState, leader string, from uint64) {
+	defer func() {
+		ps.mu.Lock()
+		ps.pulling = false
+		ps.mu.Unlock()
+	}()
+	ctx := c.ctx
+	start, prev := from, uint64(0)
+	if from > 0 {
+		var ok bool
+		if prev, ok = c.epochOf(ctx, ps.topic, ps.part, from-1); !ok {
+			return
+		}
+		start = from - 1
+	}
+	err := c.fan.fetchRange(ctx, leader, ps.topic, ps.part, start, 0, func(e *pb.Message) error {
+		if e.Offset < from {
+			if e.Offset != from-1 || e.Epoch != prev {
+				return errDiverged
+			}
+			return nil
+		}
+		ps.mu.Lock()
+		if ps.leader != leader || e.Offset != ps.end {
+			ps.mu.Unlock()
+			return errDiverged
+		}
+		m := FromPB(e)
+		if err := c.store.AppendWithOffset(ctx, m); err != nil {
+			ps.mu.Unlock()
+			return err
+		}
+		ps.end, ps.lastEpoch = m.Offset+1, m.Epoch
+		if m.IsControl() {
+			if conf, ok := configOf(m.Payload); ok {
+				c.adopt(ps, conf, m.Offset)
+			}
+		}
+		ps.commit = max(ps.commit, ps.end) // StreamRange sólo sirve lo comprometido
+		ps.notify()
+		learner := !c.votes(ps, c.self)
+		ps.mu.Unlock()
+		if learner {
+			c.throttle(ctx, int64(len(m.Payload)))
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[raft] %s:%d pull desde %s en %d: %v", ps.topic, ps.part, leader, from, err)
+	}
+}
+
+// resetHeader es la metadata gRPC con la que el líder indica en un Append
+// que su log empieza en From (la retención borró lo anterior).
+const resetHeader = "mom-log-start"
+
+func resetFrom(ctx context.Context) uint64 {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(resetHeader); len(v) > 0 {
+		if n, err := strconv.ParseUint(v[0], 10, 64); err == nil {
+			return n
+		}
+	}
+	return math.MaxUint64
+}
+
+// throttle espera lo que tardarían size bytes a CatchUpRate.
+func (c *Consensus) throttle(ctx context.Context, size int64) {
+	if c.opts.CatchUpRate <= 0 || size == 0 {
+		return
+	}
+	t := time.NewTimer(time.Duration(size * int64(time.Second) / c.opts.CatchUpRate))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
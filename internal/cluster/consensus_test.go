@@ -0,0 +1,707 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	memmeta "github.com/MateoRamirezRubio1/project_MOM/internal/adapters/meta"
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+/*──────────  store en memoria  ──────────*/
+
+// memStore es el MessageStore y TermStore de cada nodo de prueba; las
+// colas no se usan.
+type memStore struct {
+	outbound.MessageStore
+
+	mu    sync.Mutex
+	logs  map[string]map[uint64]model.Message
+	hwm   map[string]uint64
+	terms map[string][2]string
+	confs map[string][]byte
+	appl  map[string]uint64
+}
+
+func newMemStore() *memStore {
+	return &memStore{logs: map[string]map[uint64]model.Message{}, hwm: map[string]uint64{},
+		terms: map[string][2]string{}, confs: map[string][]byte{}, appl: map[string]uint64{}}
+}
+
+func partKey(topic string, part int) string { return topic + ":" + strconv.Itoa(part) }
+
+func (s *memStore) put(m model.Message) {
+	k := partKey(m.Topic, m.PartID)
+	if s.logs[k] == nil {
+		s.logs[k] = map[uint64]model.Message{}
+	}
+	s.logs[k][m.Offset] = m
+	s.hwm[k] = max(s.hwm[k], m.Offset+1)
+}
+
+func (s *memStore) Append(_ context.Context, m model.Message) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.Offset = s.hwm[partKey(m.Topic, m.PartID)]
+	s.put(m)
+	return m.Offset, nil
+}
+
+func (s *memStore) AppendWithOffset(_ context.Context, m model.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(m)
+	return nil
+}
+
+func (s *memStore) Read(_ context.Context, topic string, part int, from uint64, n int) ([]model.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := partKey(topic, part)
+	var out []model.Message
+	for off := from; off < s.hwm[k] && len(out) < n; off++ {
+		if m, ok := s.logs[k][off]; ok {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) Truncate(_ context.Context, topic string, part int, from uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := partKey(topic, part)
+	for off := range s.logs[k] {
+		if off >= from {
+			delete(s.logs[k], off)
+		}
+	}
+	s.hwm[k] = from
+	return nil
+}
+
+func (s *memStore) HighWatermark(_ context.Context, topic string, part int) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hwm[partKey(topic, part)], nil
+}
+
+func (s *memStore) LoadTerm(topic string, part int) (uint64, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.terms[partKey(topic, part)]
+	epoch, _ := strconv.ParseUint(t[0], 10, 64)
+	return epoch, t[1], nil
+}
+
+func (s *memStore) SaveTerm(topic string, part int, epoch uint64, voted string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terms[partKey(topic, part)] = [2]string{strconv.FormatUint(epoch, 10), voted}
+	return nil
+}
+
+func (s *memStore) LoadConfig(topic string, part int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.confs[partKey(topic, part)], nil
+}
+
+func (s *memStore) SaveConfig(topic string, part int, raw []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.confs[partKey(topic, part)] = raw
+	return nil
+}
+
+func (s *memStore) LoadApplied(name string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appl[name], nil
+}
+
+func (s *memStore) SaveApplied(name string, next uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appl[name] = next
+	return nil
+}
+
+func (s *memStore) Compact(name string, before uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for off := range s.logs[partKey(name, 0)] {
+		if off < before {
+			delete(s.logs[partKey(name, 0)], off)
+		}
+	}
+	return nil
+}
+
+// entries devuelve el log de la partición en orden de offset.
+func (s *memStore) entries(topic string, part int) []model.Message {
+	msgs, _ := s.Read(context.Background(), topic, part, 0, 1<<20)
+	return msgs
+}
+
+// seed escribe en el log, con offset y término, mensajes con esos ids.
+func (s *memStore) seed(topic string, part int, from, epoch uint64, ids ...uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, id := range ids {
+		s.put(model.Message{ID: id, Topic: topic, PartID: part, Offset: from + uint64(i), Epoch: epoch})
+	}
+}
+
+func ids(n int) []uuid.UUID {
+	out := make([]uuid.UUID, n)
+	for i := range out {
+		out[i] = uuid.New()
+	}
+	return out
+}
+
+// noMeta es un catálogo vacío: las particiones se crean en cada prueba.
+type noMeta struct{ outbound.MetaStore }
+
+func (noMeta) ListTopics(context.Context) ([]string, error) { return nil, nil }
+
+/*──────────  clúster en proceso  ──────────*/
+
+type testNode struct {
+	id     string
+	cfg    *Config
+	opts   ConsensusOptions
+	store  *memStore
+	cons   *Consensus
+	srv    *grpc.Server
+	fan    *Fanout
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+	once   sync.Once
+}
+
+func (n *testNode) stop() {
+	n.once.Do(func() {
+		n.cancel()
+		n.srv.Stop()
+		n.wg.Wait()
+		n.fan.Close()
+	})
+}
+
+// startCluster levanta un nodo por store, con gRPC en localhost.
+func startCluster(t *testing.T, opts ConsensusOptions, stores ...*memStore) []*testNode {
+	t.Helper()
+	if opts.ElectionTimeout == 0 {
+		opts.ElectionTimeout = 300 * time.Millisecond
+	}
+	if opts.Heartbeat == 0 {
+		opts.Heartbeat = 30 * time.Millisecond
+	}
+	if opts.CommitTimeout == 0 {
+		opts.CommitTimeout = 5 * time.Second
+	}
+	cfg := &Config{}
+	lis := make([]net.Listener, len(stores))
+	for i := range stores {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lis[i] = l
+		cfg.Nodes = append(cfg.Nodes, Node{ID: fmt.Sprintf("n%d", i+1), Host: l.Addr().String()})
+	}
+	nodes := make([]*testNode, len(stores))
+	for i, st := range stores {
+		nodes[i] = boot(t, cfg, cfg.Nodes[i].ID, opts, st, lis[i])
+	}
+	return nodes
+}
+
+const testSecret = "test"
+
+func boot(t *testing.T, cfg *Config, id string, opts ConsensusOptions, st *memStore, lis net.Listener) *testNode {
+	n := &testNode{id: id, store: st, cfg: cfg, opts: opts, wg: &sync.WaitGroup{}}
+	n.fan = NewFanout(cfg, n.id, testSecret)
+	n.cons = NewConsensus(n.id, n.fan, st, st, opts)
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	n.cons.Start(n.ctx, n.wg, noMeta{})
+	n.srv = grpc.NewServer(grpc.UnaryInterceptor(peerSecret(testSecret).unary),
+		grpc.StreamInterceptor(peerSecret(testSecret).stream))
+	pb.RegisterReplicatorServer(n.srv, &replicaSrv{store: st, cons: n.cons})
+	go func() { _ = n.srv.Serve(lis) }()
+	t.Cleanup(n.stop)
+	return n
+}
+
+// restart vuelve a levantar el nodo parado n, en la misma dirección, con
+// el store st.
+func restart(t *testing.T, n *testNode, st *memStore) *testNode {
+	t.Helper()
+	n.stop()
+	var host string
+	for _, node := range n.cfg.Nodes {
+		if node.ID == n.id {
+			host = node.Host
+		}
+	}
+	lis, err := net.Listen("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return boot(t, n.cfg, n.id, n.opts, st, lis)
+}
+
+// open crea el estado de la partición en todos los nodos para que entre
+// en el bucle de elecciones.
+func open(nodes []*testNode, topic string, part int) {
+	for _, n := range nodes {
+		n.cons.state(topic, part)
+	}
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// leader espera a que alguno de nodes lidere la partición y comprueba que
+// no hay dos líderes en el mismo término.
+func leader(t *testing.T, nodes []*testNode, topic string, part int) *testNode {
+	t.Helper()
+	var found *testNode
+	eventually(t, "a leader of "+partKey(topic, part), func() bool {
+		byEpoch := map[uint64]string{}
+		found = nil
+		for _, n := range nodes {
+			ps := n.cons.state(topic, part)
+			ps.mu.Lock()
+			if ps.leader == n.id {
+				if other, dup := byEpoch[ps.epoch]; dup {
+					t.Errorf("term %d has two leaders: %s and %s", ps.epoch, other, n.id)
+				}
+				byEpoch[ps.epoch] = n.id
+				found = n
+			}
+			ps.mu.Unlock()
+		}
+		return found != nil
+	})
+	return found
+}
+
+func propose(t *testing.T, n *testNode, topic string, payload []byte) uint64 {
+	t.Helper()
+	off, err := n.cons.Propose(context.Background(),
+		model.Message{ID: uuid.New(), Topic: topic, Payload: payload}, model.AcksAll)
+	if err != nil {
+		t.Fatalf("propose on %s: %v", n.id, err)
+	}
+	return off
+}
+
+// sameLog espera a que el log de n sea igual que el de ref.
+func sameLog(t *testing.T, ref, n *testNode, topic string, part int) {
+	t.Helper()
+	eventually(t, n.id+" to match the log of "+ref.id, func() bool {
+		a, b := ref.store.entries(topic, part), n.store.entries(topic, part)
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i].ID != b[i].ID || a[i].Offset != b[i].Offset || a[i].Epoch != b[i].Epoch {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+/*──────────  pruebas  ──────────*/
+
+func TestElectionReplicatesAndSurvivesLeaderLoss(t *testing.T) {
+	nodes := startCluster(t, ConsensusOptions{}, newMemStore(), newMemStore(), newMemStore())
+	open(nodes, "t", 0)
+
+	first := leader(t, nodes, "t", 0)
+	for i := 0; i < 3; i++ {
+		propose(t, first, "t", []byte("m"+strconv.Itoa(i)))
+	}
+	for _, n := range nodes {
+		sameLog(t, first, n, "t", 0)
+	}
+
+	first.stop()
+	var rest []*testNode
+	for _, n := range nodes {
+		if n != first {
+			rest = append(rest, n)
+		}
+	}
+	second := leader(t, rest, "t", 0)
+	off := propose(t, second, "t", []byte("after"))
+	for _, n := range rest {
+		sameLog(t, second, n, "t", 0)
+		eventually(t, n.id+" to commit "+strconv.FormatUint(off, 10), func() bool {
+			return n.cons.Committed("t", 0) > off
+		})
+	}
+}
+
+func TestFollowerTruncatesDivergentEntries(t *testing.T) {
+	common, won, lost := ids(2), ids(1), ids(3)
+	stores := []*memStore{newMemStore(), newMemStore(), newMemStore()}
+	for _, s := range stores {
+		s.seed("t", 0, 0, 1, common...)
+	}
+	// n1 y n2 tienen la entrada del término 3; n3, las de un líder del
+	// término 2 que no llegó a comprometerlas
+	for _, s := range stores[:2] {
+		s.seed("t", 0, 2, 3, won...)
+		_ = s.SaveTerm("t", 0, 3, "")
+	}
+	stores[2].seed("t", 0, 2, 2, lost...)
+	_ = stores[2].SaveTerm("t", 0, 2, "")
+
+	nodes := startCluster(t, ConsensusOptions{}, stores...)
+	open(nodes, "t", 0)
+	ref := leader(t, nodes, "t", 0)
+	if ref == nodes[2] {
+		t.Fatal("n3 won an election with an out-of-date log")
+	}
+	sameLog(t, ref, nodes[2], "t", 0)
+	for _, m := range nodes[2].store.entries("t", 0) {
+		for _, id := range lost {
+			if m.ID == id {
+				t.Fatalf("entry %v of the lost term survived at offset %d", id, m.Offset)
+			}
+		}
+	}
+}
+
+func TestFollowerBehindRetentionIsReset(t *testing.T) {
+	old := ids(10)
+	stores := []*memStore{newMemStore(), newMemStore(), newMemStore()}
+	for _, s := range stores[:2] {
+		s.seed("t", 0, 5, 1, old[5:]...) // la retención borró 0..4
+		_ = s.SaveTerm("t", 0, 1, "")
+	}
+	stores[2].seed("t", 0, 0, 1, old[:3]...)
+	_ = stores[2].SaveTerm("t", 0, 1, "")
+
+	nodes := startCluster(t, ConsensusOptions{}, stores...)
+	open(nodes, "t", 0)
+	ref := leader(t, nodes, "t", 0)
+	sameLog(t, ref, nodes[2], "t", 0)
+	if got := nodes[2].store.entries("t", 0)[0].Offset; got != 5 {
+		t.Fatalf("reset follower starts at %d, want 5", got)
+	}
+}
+
+func TestLargeEntriesAreSplitBelowTheGRPCLimit(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 1<<20)
+	stores := []*memStore{newMemStore(), newMemStore(), newMemStore()}
+	for _, s := range stores[:2] {
+		for i, id := range ids(8) { // 8 MiB: no cabe en un solo Append
+			s.mu.Lock()
+			s.put(model.Message{ID: id, Topic: "t", Offset: uint64(i), Epoch: 1, Payload: big})
+			s.mu.Unlock()
+		}
+		_ = s.SaveTerm("t", 0, 1, "")
+	}
+	nodes := startCluster(t, ConsensusOptions{}, stores...)
+	open(nodes, "t", 0)
+	ref := leader(t, nodes, "t", 0)
+	sameLog(t, ref, nodes[2], "t", 0)
+}
+
+func TestReassignToDisjointReplicasKeepsCommittedData(t *testing.T) {
+	nodes := startCluster(t, ConsensusOptions{}, newMemStore(), newMemStore(), newMemStore())
+	for _, n := range nodes {
+		n.cons.place("t", 1, [][]string{{"n1"}}, [][]string{{"n1"}}, true)
+	}
+	if l := leader(t, nodes, "t", 0); l != nodes[0] {
+		t.Fatalf("leader is %s, want the only replica n1", l.id)
+	}
+	var offs []uint64
+	for i := 0; i < 3; i++ {
+		offs = append(offs, propose(t, nodes[0], "t", []byte("m"+strconv.Itoa(i))))
+	}
+
+	// set_replicas: n3 sustituye a n1
+	for _, n := range nodes {
+		n.cons.place("t", 1, [][]string{{"n3"}}, [][]string{{"n1"}}, true)
+	}
+	eventually(t, "n3 to lead t:0", func() bool {
+		return leader(t, nodes, "t", 0) == nodes[2]
+	})
+	got := map[uint64][]byte{}
+	for _, m := range nodes[2].store.entries("t", 0) {
+		got[m.Offset] = m.Payload
+	}
+	for i, off := range offs {
+		if want := "m" + strconv.Itoa(i); string(got[off]) != want {
+			t.Fatalf("n3 offset %d = %q, want %q", off, got[off], want)
+		}
+	}
+	off := propose(t, nodes[2], "t", []byte("after"))
+	eventually(t, "n3 to commit alone", func() bool { return nodes[2].cons.Committed("t", 0) > off })
+}
+
+func TestFollowerFarBehindPullsCommittedEntries(t *testing.T) {
+	stores := []*memStore{newMemStore(), newMemStore(), newMemStore()}
+	all := ids(200)
+	for _, s := range stores[:2] {
+		s.seed("t", 0, 0, 1, all...)
+		_ = s.SaveTerm("t", 0, 1, "")
+	}
+	stores[2].seed("t", 0, 0, 1, all[:10]...)
+	_ = stores[2].SaveTerm("t", 0, 1, "")
+
+	// con lotes de 4 mensajes, a n3 le faltan muchos más de los que cabe
+	// en un Append: los trae con pull
+	nodes := startCluster(t, ConsensusOptions{MaxBatch: 4}, stores...)
+	open(nodes, "t", 0)
+	ref := leader(t, nodes, "t", 0)
+	sameLog(t, ref, nodes[2], "t", 0)
+	off := propose(t, ref, "t", []byte("after"))
+	for _, n := range nodes {
+		eventually(t, n.id+" to commit the new entry", func() bool { return n.cons.Committed("t", 0) > off })
+	}
+}
+
+// listState es la máquina de estados de prueba de un replicatedLog: la
+// lista de payloads aplicados.
+type listState struct {
+	mu    sync.Mutex
+	items []string
+}
+
+func (s *listState) apply(_ context.Context, m model.Message) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, string(m.Payload))
+	return nil, nil
+}
+
+func (s *listState) snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.items)
+}
+
+func (s *listState) restore(_ context.Context, raw []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Unmarshal(raw, &s.items)
+}
+
+func (s *listState) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.items)
+}
+
+func startListLog(n *testNode, every uint64) (*replicatedLog, *listState) {
+	s := &listState{}
+	l := newReplicatedLog("log", "test", n.cons, n.fan, n.store, n.store, s.apply)
+	l.snapshot, l.restore, l.every = s.snapshot, s.restore, every
+	l.Start(n.ctx, n.wg)
+	return l, s
+}
+
+func TestCompactedLogIsRestoredFromSnapshot(t *testing.T) {
+	nodes := startCluster(t, ConsensusOptions{}, newMemStore(), newMemStore(), newMemStore())
+	logs := map[*testNode]*replicatedLog{}
+	states := map[*testNode]*listState{}
+	for _, n := range nodes {
+		logs[n], states[n] = startListLog(n, 5)
+	}
+	nodes[2].stop()
+
+	ref := leader(t, nodes[:2], "log", 0)
+	var want []string
+	for i := 0; i < 12; i++ {
+		p := "e" + strconv.Itoa(i)
+		if _, err := logs[ref].propose(context.Background(), uuid.New(), []byte(p), ""); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, p)
+	}
+	eventually(t, "the leader to compact its log", func() bool {
+		e := ref.store.entries("log", 0)
+		return len(e) > 0 && e[0].Offset > 0
+	})
+
+	// n3 vuelve sin nada: el líder ya no tiene el principio del log
+	n3 := restart(t, nodes[2], newMemStore())
+	_, s3 := startListLog(n3, 5)
+	eventually(t, "n3 to restore the snapshot and catch up", func() bool {
+		return slices.Equal(s3.get(), want)
+	})
+	if got := states[ref].get(); !slices.Equal(got, want) {
+		t.Fatalf("leader state = %v, want %v", got, want)
+	}
+}
+
+func TestDrainCancelsAckWaitsAndRejectsProposals(t *testing.T) {
+	nodes := startCluster(t, ConsensusOptions{}, newMemStore(), newMemStore(), newMemStore())
+	open(nodes, "t", 0)
+	l := leader(t, nodes, "t", 0)
+	propose(t, l, "t", []byte("before"))
+	for _, n := range nodes {
+		if n != l {
+			n.stop() // sin quórum, acks=all espera hasta CommitTimeout
+		}
+	}
+
+	base := len(l.store.entries("t", 0))
+	res := make(chan error, 1)
+	go func() {
+		_, err := l.cons.Propose(context.Background(), model.Message{Topic: "t", Payload: []byte("stuck")}, model.AcksAll)
+		res <- err
+	}()
+	eventually(t, "the proposal to reach the leader log", func() bool { return len(l.store.entries("t", 0)) > base })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.cons.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Drain = %v, want deadline exceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Drain took %v", time.Since(start))
+	}
+	if err := <-res; errs.Kind(err) != errs.ErrUnavailable {
+		t.Fatalf("cut ack wait: err = %v, want unavailable", err)
+	}
+	_, err := l.cons.Propose(context.Background(), model.Message{Topic: "t", Payload: []byte("late")}, model.AcksLeader)
+	if errs.Kind(err) != errs.ErrUnavailable {
+		t.Fatalf("proposal after Drain: err = %v, want unavailable", err)
+	}
+}
+
+func TestBacklogCountsWhatEachFollowerMisses(t *testing.T) {
+	nodes := startCluster(t, ConsensusOptions{}, newMemStore(), newMemStore(), newMemStore())
+	open(nodes, "t", 0)
+	l := leader(t, nodes, "t", 0)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(backlogGauge(l.cons))
+
+	var down, up *testNode
+	for _, n := range nodes {
+		if n == l {
+			continue
+		}
+		if down == nil {
+			down = n
+		} else {
+			up = n
+		}
+	}
+	propose(t, l, "t", []byte("m0"))
+	down.stop()
+	for i := 1; i <= 5; i++ {
+		propose(t, l, "t", []byte("m"+strconv.Itoa(i)))
+	}
+
+	backlog := func(id string) uint64 { return l.cons.Backlog()["t:0"][id] }
+	eventually(t, up.id+" to confirm everything", func() bool { return backlog(up.id) == 0 })
+	if n := backlog(down.id); n < 5 {
+		t.Fatalf("backlog of stopped %s = %d, want at least 5", down.id, n)
+	}
+	if n := gauge(t, reg, "mom_replication_outbox_depth", down.id); n != float64(backlog(down.id)) {
+		t.Fatalf("mom_replication_outbox_depth{peer=%s} = %v, want %d", down.id, n, backlog(down.id))
+	}
+
+	// al volver se pone al día desde el log del líder
+	restart(t, down, down.store)
+	eventually(t, down.id+" to drain its backlog", func() bool { return backlog(down.id) == 0 })
+}
+
+// gauge lee de reg la serie de name con peer=peer.
+func gauge(t *testing.T, reg prometheus.Gatherer, name, peer string) float64 {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == "peer" && lp.GetValue() == peer {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no %s series for peer %s", name, peer)
+	return 0
+}
+
+// noSnap basta mientras el log no llegue a compactarse.
+type noSnap struct{}
+
+func (noSnap) Snapshot() ([]byte, error) { return nil, nil }
+func (noSnap) Restore([]byte) error      { return nil }
+
+func TestPutQuotaIsAppliedOnEveryNode(t *testing.T) {
+	nodes := startCluster(t, ConsensusOptions{}, newMemStore(), newMemStore(), newMemStore())
+	metas := map[*testNode]*ReplicatedMeta{}
+	applied := map[*testNode]chan model.QuotaSetting{}
+	for _, n := range nodes {
+		r := NewReplicatedMeta(memmeta.NewMemoryCatalog(), noSnap{}, n.cons, n.fan, n.store, n.store)
+		ch := make(chan model.QuotaSetting, 1)
+		r.OnQuota(func(s model.QuotaSetting) { ch <- s })
+		r.Start(n.ctx, n.wg)
+		metas[n], applied[n] = r, ch
+	}
+
+	l := leader(t, nodes, model.MetaTopic, 0)
+	want := model.QuotaSetting{Scope: model.ScopeUser, Name: "bob", Quota: model.Quota{MsgsPerSec: 5}}
+	if err := metas[l].PutQuota(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		select {
+		case got := <-applied[n]:
+			if got != want {
+				t.Fatalf("%s applied %+v, want %+v", n.id, got, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s never applied the quota", n.id)
+		}
+		stored, err := metas[n].ListQuotas(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stored) != 1 || stored[0] != want {
+			t.Fatalf("%s catalog holds %+v, want [%+v]", n.id, stored, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// ToPB convierte un mensaje de dominio al formato de réplica.
+func ToPB(m model.Message) *pb.Message {
+	return &pb.Message{
+		Uuid:    m.ID.String(),
+		Topic:   m.Topic,
+		Part:    uint32(m.PartID),
+		Offset:  m.Offset,
+		Key:     m.Key,
+		User:    m.Producer,
+		Payload: m.Payload,
+		Headers: m.Headers,
+		Epoch:   m.Epoch,
+	}
+}
+
+// FromPB es la inversa de ToPB; un UUID inválido se deja en uuid.Nil
+// para que el store le asigne uno.
+func FromPB(m *pb.Message) model.Message {
+	id, _ := uuid.Parse(m.Uuid)
+	return model.Message{
+		ID:       id,
+		Topic:    m.Topic,
+		PartID:   int(m.Part),
+		Offset:   m.Offset,
+		Key:      m.Key,
+		Producer: m.User,
+		Payload:  m.Payload,
+		Headers:  m.Headers,
+		Epoch:    m.Epoch,
+	}
+}
@@ -0,0 +1,240 @@
+package cluster
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const maxEvents = 50 // cambios de estado que se recuerdan para /cluster/status
+
+/*
+Detector de fallos por latidos y gossip.
+
+Cada nodo incrementa su contador de latidos en cada ronda y, en la misma
+ronda, intercambia con cada peer (Replicator.Gossip) el último contador que
+conoce de cada nodo. Un nodo está vivo mientras su contador avance, lo vea
+uno directamente o se lo cuente otro: así un enlace caído entre dos nodos
+no basta para darlos por muertos. Sin avances durante SuspectAfter pasa a
+sospechoso y, durante DeadAfter, a muerto; vuelve a vivo en cuanto avanza.
+
+El estado decide el líder preferido (Fanout.Leader), los destinos del
+fan-out (Fanout.Targets) y con quién compara la anti-entropía.
+*/
+
+type member struct {
+	heartbeat uint64
+	updated   time.Time // último avance del contador (reloj local)
+	state     model.MemberState
+	since     time.Time
+}
+
+// Detector sigue el estado de los peers de un Fanout.
+type Detector struct {
+	fan          *Fanout
+	suspectAfter time.Duration
+	deadAfter    time.Duration
+
+	mu      sync.Mutex
+	self    uint64             // contador propio
+	members map[string]*member // por ID, sin el propio
+	events  []model.MemberEvent
+	subs    []chan model.MemberEvent
+}
+
+// NewDetector engancha el detector a f (nil en single-node). Hasta la
+// primera ronda todos los peers cuentan como vivos.
+func NewDetector(f *Fanout, suspectAfter, deadAfter time.Duration) *Detector {
+	if f == nil {
+		return nil
+	}
+	d := &Detector{fan: f, suspectAfter: suspectAfter, deadAfter: deadAfter,
+		members: map[string]*member{}}
+	f.mu.Lock()
+	f.det = d
+	f.mu.Unlock()
+	return d
+}
+
+// member devuelve (creándola si hace falta) la entrada de id; requiere d.mu.
+func (d *Detector) member(id string, now time.Time) *member {
+	m := d.members[id]
+	if m == nil {
+		m = &member{updated: now, state: model.MemberAlive, since: now}
+		d.members[id] = m
+	}
+	return m
+}
+
+// State devuelve el estado de id y desde cuándo; un nodo sin noticias
+// todavía cuenta como vivo.
+func (d *Detector) State(id string) (model.MemberState, time.Time) {
+	if d == nil {
+		return model.MemberAlive, time.Time{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if m := d.members[id]; m != nil {
+		return m.state, m.since
+	}
+	return model.MemberAlive, time.Time{}
+}
+
+func (d *Detector) alive(id string) bool {
+	st, _ := d.State(id)
+	return st != model.MemberDead
+}
+
+// Subscribe devuelve un canal con los cambios de estado; si el receptor no
+// los lee a tiempo se pierden (el canal tiene búfer).
+func (d *Detector) Subscribe() <-chan model.MemberEvent {
+	ch := make(chan model.MemberEvent, 16)
+	if d == nil {
+		return ch
+	}
+	d.mu.Lock()
+	d.subs = append(d.subs, ch)
+	d.mu.Unlock()
+	return ch
+}
+
+// Events devuelve los últimos cambios de estado, del más antiguo al más
+// reciente.
+func (d *Detector) Events() []model.MemberEvent {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]model.MemberEvent(nil), d.events...)
+}
+
+/*──────────  gossip  ──────────*/
+
+// view es lo que se envía a los demás: el contador propio y el de los
+// nodos no muertos (el de un muerto ya no dice nada); requiere d.mu.
+func (d *Detector) view() []*pb.Member {
+	out := []*pb.Member{{Id: d.fan.self, Heartbeat: d.self}}
+	for id, m := range d.members {
+		if m.state != model.MemberDead {
+			out = append(out, &pb.Member{Id: id, Heartbeat: m.heartbeat})
+		}
+	}
+	return out
+}
+
+// merge se queda con los contadores más altos de in, recibido en contacto
+// directo con from. El contador que from da de sí mismo se acepta tal cual:
+// si es menor que el conocido, from se ha reiniciado.
+func (d *Detector) merge(from string, in []*pb.Member) {
+	cfg, _ := d.fan.view()
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cfg.Self(from) != nil {
+		d.member(from, now).updated = now
+	}
+	for _, g := range in {
+		if g.Id == d.fan.self || cfg.Self(g.Id) == nil {
+			continue
+		}
+		m := d.member(g.Id, now)
+		switch {
+		case g.Id == from:
+			m.heartbeat = g.Heartbeat
+		case g.Heartbeat > m.heartbeat:
+			m.heartbeat, m.updated = g.Heartbeat, now
+		}
+	}
+	d.evaluate(now)
+}
+
+// evaluate recalcula los estados y publica los cambios; requiere d.mu.
+func (d *Detector) evaluate(now time.Time) {
+	for id, m := range d.members {
+		next := model.MemberAlive
+		switch quiet := now.Sub(m.updated); {
+		case quiet >= d.deadAfter:
+			next = model.MemberDead
+		case quiet >= d.suspectAfter:
+			next = model.MemberSuspect
+		}
+		if next == m.state {
+			continue
+		}
+		ev := model.MemberEvent{Node: id, From: m.state, To: next, At: now.UTC()}
+		m.state, m.since = next, now
+		log.Printf("[membership] %s: %s → %s", id, ev.From, ev.To)
+		metrics.MembershipChanges.WithLabelValues(id, string(next)).Inc()
+		d.events = append(d.events, ev)
+		if len(d.events) > maxEvents {
+			d.events = d.events[len(d.events)-maxEvents:]
+		}
+		for _, ch := range d.subs {
+			select {
+			case ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// forget borra a los nodos que salieron del clúster.
+func (d *Detector) forget(ids []string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range ids {
+		delete(d.members, id)
+	}
+}
+
+// probe hace la ronda con un peer: Gossip, o Ping si el peer es anterior
+// al detector. Devuelve el error de la llamada.
+func (d *Detector) probe(ctx context.Context, id string, cli pb.ReplicatorClient) error {
+	d.mu.Lock()
+	req := &pb.GossipRequest{From: d.fan.self, Members: d.view()}
+	d.mu.Unlock()
+
+	r, err := cli.Gossip(ctx, req)
+	if status.Code(err) == codes.Unimplemented {
+		if _, err = cli.Ping(ctx, &emptypb.Empty{}); err == nil {
+			d.merge(id, nil)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	d.merge(id, r.Members)
+	return nil
+}
+
+// tick empieza una ronda: sube el contador propio y revisa los estados.
+func (d *Detector) tick() {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.self++
+	d.evaluate(now)
+}
+
+func (s *replicaSrv) Gossip(_ context.Context, in *pb.GossipRequest) (*pb.GossipReply, error) {
+	if s.det == nil {
+		return nil, status.Error(codes.Unimplemented, "failure detector disabled")
+	}
+	s.det.merge(in.From, in.Members)
+	s.det.mu.Lock()
+	defer s.det.mu.Unlock()
+	return &pb.GossipReply{Members: s.det.view()}, nil
+}
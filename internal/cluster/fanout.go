@@ -1,111 +1,190 @@
 package cluster
 
 import (
-	"context"
 	"log"
+	"sync"
 
 	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
-	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
-	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
-	"github.com/google/uuid"
 	"google.golang.org/grpc"
 )
 
-// Fanout mantiene los clientes gRPC a los peers.
+// peer es la conexión con un nodo remoto.
+type peer struct {
+	host string
+	cli  pb.ReplicatorClient
+	cc   *grpc.ClientConn
+}
+
+// Fanout mantiene los clientes gRPC a los peers. La membresía puede
+// cambiar en caliente con Update.
 type Fanout struct {
-	self  string  // ID propio
+	self   string     // ID propio
+	secret peerSecret // se envía en cada llamada (ver peerauth.go)
+
+	mu    sync.RWMutex
 	cfg   *Config // referencia de utilidad
-	peers map[string]pb.ReplicatorClient
+	peers map[string]*peer
+	det   *Detector // nil → todos los peers conectados cuentan como vivos
 }
 
 // NewFanout devuelve nil si:
 //
 //   - cfg == nil                       → modo single-node
-//   - sólo hay 1 nodo en el JSON       → “ ”
-func NewFanout(cfg *Config, selfID string) *Fanout {
-	if cfg == nil || len(cfg.Nodes) <= 1 {
+//   - selfID no figura en cfg.Nodes    → “ ”
+//
+// Un clúster de un solo nodo tiene Fanout (sin peers) para poder crecer.
+// secret es el cluster.secret con el que los peers aceptan las llamadas.
+func NewFanout(cfg *Config, selfID, secret string) *Fanout {
+	if cfg == nil || cfg.Self(selfID) == nil {
 		return nil
 	}
-	f := &Fanout{self: selfID, cfg: cfg, peers: map[string]pb.ReplicatorClient{}}
+	f := &Fanout{self: selfID, secret: peerSecret(secret), peers: map[string]*peer{}}
+	f.Update(cfg)
+	return f
+}
 
+func dial(n Node, secret peerSecret) (*peer, error) {
+	cc, err := grpc.Dial(n.Host, grpc.WithInsecure(), grpc.WithPerRPCCredentials(secret))
+	if err != nil {
+		return nil, err
+	}
+	return &peer{host: n.Host, cli: pb.NewReplicatorClient(cc), cc: cc}, nil
+}
+
+// Update aplica una nueva membresía: conecta los peers nuevos (o los que
+// cambiaron de host) y cierra las de los que ya no están; las llamadas en
+// curso por ellas fallan y el consenso las reintenta con la membresía
+// nueva.
+func (f *Fanout) Update(cfg *Config) (added, removed []string) {
+	if f == nil {
+		return nil, nil
+	}
+	f.mu.Lock()
+	next := map[string]*peer{}
 	for _, n := range cfg.Nodes {
-		if n.ID == selfID {
+		if n.ID == f.self {
 			continue
 		}
-		cc, err := grpc.Dial(n.Host, grpc.WithInsecure())
+		if p := f.peers[n.ID]; p != nil && p.host == n.Host {
+			next[n.ID] = p
+			continue
+		}
+		p, err := dial(n, f.secret)
 		if err != nil {
 			log.Printf("[cluster] peer %s: %v", n.ID, err)
 			continue
 		}
-		f.peers[n.ID] = pb.NewReplicatorClient(cc)
+		next[n.ID] = p
+		added = append(added, n.ID)
 	}
-	return f
-}
+	var gone []*peer
+	for id, p := range f.peers {
+		if next[id] != p {
+			gone = append(gone, p)
+			if next[id] == nil {
+				removed = append(removed, id)
+			}
+		}
+	}
+	f.cfg, f.peers = cfg, next
+	f.mu.Unlock()
 
-/*────────────  publicación normal  ───────────*/
+	for _, p := range gone {
+		_ = p.cc.Close()
+	}
+	return added, removed
+}
 
-func (f *Fanout) Broadcast(ctx context.Context, batch []*pb.Message) {
+// Peers devuelve los IDs de los peers conectados, en orden del JSON.
+func (f *Fanout) Peers() []string {
 	if f == nil {
-		return
+		return nil
 	}
-	req := &pb.ReplicateRequest{Batch: batch}
-	for id, cli := range f.peers {
-		go func(id string, c pb.ReplicatorClient) {
-			if _, err := c.Replicate(ctx, req); err != nil {
-				log.Printf("[cluster] peer %s error: %v", id, err)
-			}
-		}(id, cli)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]string, 0, len(f.peers))
+	for _, n := range f.cfg.Nodes {
+		if f.peers[n.ID] != nil {
+			out = append(out, n.ID)
+		}
 	}
+	return out
 }
 
-/*────────────  catch-up  ───────────*/
-
-// store.Append se encarga de asignar el offset local;
-// offset que llega en el lote sólo se usa para avanzar el HWM.
-func (f *Fanout) CatchUp(ctx context.Context, peerID string,
-	store outbound.MessageStore, topic string, part int,
-	from, to uint64) error {
-
+// Targets devuelve los peers conectados que el detector no da por muertos,
+// en orden del JSON: son los destinos del fan-out y de la anti-entropía.
+func (f *Fanout) Targets() []string {
 	if f == nil {
 		return nil
 	}
-	cli := f.peers[peerID]
-	if cli == nil {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]string, 0, len(f.peers))
+	for _, n := range f.cfg.Nodes {
+		if f.peers[n.ID] != nil && f.det.alive(n.ID) {
+			out = append(out, n.ID)
+		}
+	}
+	return out
+}
+
+// detector devuelve el detector de fallos enganchado (nil si no hay).
+func (f *Fanout) detector() *Detector {
+	if f == nil {
 		return nil
 	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.det
+}
 
-	resp, err := cli.GetRange(ctx, &pb.RangeRequest{
-		Topic: topic, Part: uint32(part), From: from, To: to,
-	})
-	if err != nil {
-		return err
+// view devuelve la membresía actual y los clientes conectados.
+func (f *Fanout) view() (*Config, map[string]pb.ReplicatorClient) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make(map[string]pb.ReplicatorClient, len(f.peers))
+	for id, p := range f.peers {
+		out[id] = p.cli
 	}
+	return f.cfg, out
+}
 
-	for _, m := range resp.Batch {
-		_ = store.AppendWithOffset(ctx, model.Message{
-			ID:       uuid.MustParse(m.Uuid),
-			Topic:    m.Topic,
-			PartID:   int(m.Part),
-			Offset:   m.Offset,
-			Key:      m.Key,
-			Producer: m.User,
-			Payload:  m.Payload,
-		})
+func (f *Fanout) client(id string) pb.ReplicatorClient {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if p := f.peers[id]; p != nil {
+		return p.cli
 	}
 	return nil
 }
 
+// Close cierra las conexiones gRPC con los peers.
+func (f *Fanout) Close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.peers {
+		_ = p.cc.Close()
+	}
+}
+
 /*────────────  elección simple de líder  ───────────*/
 
+// Leader es el primer nodo de la configuración que no está muerto según el
+// detector (o, sin detector, con conexión abierta).
 func (f *Fanout) Leader() string {
 	if f == nil {
-		return f.self
+		return GlobalSelfID // single-node: uno mismo
 	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	for _, n := range f.cfg.Nodes { // orden del JSON
 		if n.ID == f.self {
 			return n.ID
 		}
-		if f.peers[n.ID] != nil { // “vive”
+		if f.peers[n.ID] != nil && f.det.alive(n.ID) {
 			return n.ID
 		}
 	}
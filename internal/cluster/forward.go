@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/inbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Modos de cluster.forward.
+const (
+	ForwardProxy    = "proxy"    // se reenvía por gRPC y se responde aquí
+	ForwardRedirect = "redirect" // 307 hacia el REST del dueño
+	ForwardOff      = "off"      // 503 con el líder en el detalle
+)
+
+// OpPublish es la ForwardRequest.op de una publicación; los logs
+// replicados usan OpMeta y OpQueue.
+const OpPublish = "publish"
+
+// OpPull etiqueta en las métricas las lecturas que se hacen en otra
+// réplica (Forwarder.Read); no viajan como ForwardRequest sino por
+// StreamRange.
+const OpPull = "pull"
+
+/*──────────  lado cliente  ──────────*/
+
+// Forwarder lleva las publicaciones que este nodo no atiende al líder de
+// la partición y las lecturas de particiones que no guarda a una de sus
+// réplicas. Las colas no lo necesitan: cualquier nodo las sirve a través
+// del log de colas (ReplicatedQueues).
+type Forwarder struct {
+	self string
+	fan  *Fanout
+	cons *Consensus
+	mode string
+}
+
+// NewForwarder devuelve nil en single-node o con mode = off.
+func NewForwarder(self string, fan *Fanout, cons *Consensus, mode string) *Forwarder {
+	if fan == nil || mode == ForwardOff {
+		return nil
+	}
+	return &Forwarder{self: self, fan: fan, cons: cons, mode: mode}
+}
+
+// Call ejecuta req en el nodo id. En modo redirect devuelve
+// *model.RedirectError si se conoce su dirección REST (si no, reenvía).
+func (f *Forwarder) Call(ctx context.Context, id string, req *pb.ForwardRequest) (r *pb.ForwardReply, err error) {
+	if f.mode == ForwardRedirect {
+		if addr := f.restAddr(id); addr != "" {
+			return nil, &model.RedirectError{Leader: id, Addr: addr}
+		}
+	}
+	cli := f.fan.client(id)
+	if cli == nil {
+		return nil, errs.Unavailable("owner node %s is not connected", id)
+	}
+	ctx, span := tracing.StartKind(ctx, "Replicator.Forward", trace.SpanKindClient,
+		attribute.String("mom.op", req.Op), attribute.String("mom.peer", id))
+	defer func() { tracing.End(span, err) }()
+
+	metrics.Forwarded.WithLabelValues(req.Op, id).Inc()
+	r, err = cli.Forward(tracing.OutgoingGRPC(ctx), req)
+	if err != nil {
+		return nil, errs.Unavailable("forward %s to %s: %v", req.Op, id, err)
+	}
+	return r, decodeError(r)
+}
+
+// Read lee en la réplica id hasta max mensajes comprometidos de (topic,
+// part) desde from; el rango puede traer registros de control. En modo
+// redirect devuelve *model.RedirectError si se conoce su dirección REST.
+func (f *Forwarder) Read(ctx context.Context, id, topic string, part int, from uint64, max int) (out []model.Message, err error) {
+	if f.mode == ForwardRedirect {
+		if addr := f.restAddr(id); addr != "" {
+			return nil, &model.RedirectError{Leader: id, Addr: addr}
+		}
+	}
+	if f.fan.client(id) == nil {
+		return nil, errs.Unavailable("replica node %s is not connected", id)
+	}
+	metrics.Forwarded.WithLabelValues(OpPull, id).Inc()
+	err = f.fan.fetchRange(ctx, id, topic, part, from, from+uint64(max), func(m *pb.Message) error {
+		out = append(out, FromPB(m))
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Unavailable("read %s:%d from %s: %v", topic, part, id, err)
+	}
+	return out, nil
+}
+
+func (f *Forwarder) restAddr(id string) string {
+	cfg, _ := f.fan.view()
+	if n := cfg.Self(id); n != nil {
+		return n.REST
+	}
+	return ""
+}
+
+/*──────────  lado servidor  ──────────*/
+
+// Local son los casos de uso, sin reenvío, que atienden un Forward: así
+// una operación nunca rebota entre nodos.
+type Local struct {
+	Pub    inbound.Publisher
+	Meta   *ReplicatedMeta   // nil → sin log de metadatos
+	Queues *ReplicatedQueues // nil → sin log de colas
+}
+
+func (s *replicaSrv) Forward(ctx context.Context, in *pb.ForwardRequest) (*pb.ForwardReply, error) {
+	if s.local == nil {
+		return nil, status.Error(codes.Unimplemented, "forwarding disabled")
+	}
+	ctx, span := tracing.StartKind(tracing.IncomingGRPC(ctx), "replicaSrv.Forward",
+		trace.SpanKindServer, attribute.String("mom.op", in.Op), attribute.String("mom.topic", in.Topic))
+	defer span.End()
+
+	r, err := s.local.serve(ctx, in)
+	if err != nil {
+		r = &pb.ForwardReply{}
+		r.ErrorKind, r.Error = encodeError(err)
+	}
+	return r, nil
+}
+
+func (l *Local) serve(ctx context.Context, in *pb.ForwardRequest) (*pb.ForwardReply, error) {
+	r := &pb.ForwardReply{}
+	switch in.Op {
+	case OpPublish:
+		part, off, err := l.Pub.Publish(ctx, in.Topic, in.Key, string(in.Payload), in.User, model.Acks(in.Acks))
+		r.Part, r.Offset = int32(part), off
+		return r, err
+	case OpMeta:
+		if l.Meta == nil {
+			return r, errs.Unavailable("metadata log disabled")
+		}
+		return r, l.Meta.log.serveForward(ctx, in)
+	case OpQueue:
+		if l.Queues == nil {
+			return r, errs.Unavailable("queue log disabled")
+		}
+		return r, l.Queues.log.serveForward(ctx, in)
+	}
+	return r, errs.Invalid("unknown forward op %q", in.Op)
+}
+
+/*──────────  errores entre nodos  ──────────*/
+
+// remoteError conserva el texto del error del dueño y su centinela, para
+// que la capa REST responda lo mismo que habría respondido él.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.kind }
+
+func encodeError(err error) (kind, text string) {
+	var qe *model.QuotaError
+	var re *model.ReplicationError
+	switch {
+	case errors.As(err, &qe):
+		b, _ := json.Marshal(qe)
+		return "quota", string(b)
+	case errors.As(err, &re):
+		b, _ := json.Marshal(re)
+		return "replication", string(b)
+	}
+	if k := errs.Kind(err); k != nil {
+		return k.Error(), err.Error()
+	}
+	return "internal", err.Error()
+}
+
+func decodeError(r *pb.ForwardReply) error {
+	switch r.ErrorKind {
+	case "":
+		return nil
+	case "quota":
+		var qe model.QuotaError
+		if json.Unmarshal([]byte(r.Error), &qe) == nil {
+			return &qe
+		}
+	case "replication":
+		var re model.ReplicationError
+		if json.Unmarshal([]byte(r.Error), &re) == nil {
+			return &re
+		}
+	}
+	return &remoteError{msg: r.Error, kind: errs.ByName(r.ErrorKind)}
+}
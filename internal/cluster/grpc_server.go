@@ -2,52 +2,50 @@ package cluster
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net"
 	"strconv"
-	"time"
+	"sync/atomic"
 
 	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
-	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
 	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
-	"github.com/google/uuid"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 /*──────────  variables rellenadas por wiring.go ──────────*/
 
-var GlobalCfg *Config
 var GlobalSelfID string
-var GlobalMeta outbound.MetaStore
+
+// grpcServing indica si el listener gRPC está abierto (lo usa /readyz).
+var grpcServing atomic.Bool
+
+// GRPCServing informa si este nodo está sirviendo gRPC.
+func GRPCServing() bool { return grpcServing.Load() }
 
 /*──────────  servicio gRPC  ──────────*/
 
 type replicaSrv struct {
 	pb.UnimplementedReplicatorServer
 	store outbound.MessageStore
+	cons  *Consensus // nil → sin log replicado (Append da Unimplemented)
+	local *Local     // nil → no se aceptan Forward
+	det   *Detector  // nil → Gossip no implementado
 }
 
 // ---------- Replicate ----------
 
-func (s *replicaSrv) Replicate(ctx context.Context,
-	in *pb.ReplicateRequest,
-) (*pb.ReplicateAck, error) {
-
-	for _, m := range in.Batch {
-		_, _ = s.store.Append(ctx, model.Message{
-			ID:       uuid.MustParse(m.Uuid),
-			Topic:    m.Topic,
-			PartID:   int(m.Part),
-			Offset:   m.Offset,
-			Key:      m.Key,
-			Producer: m.User,
-			Payload:  m.Payload,
-		})
-		TrackNextOffset(m.Topic, int(m.Part), m.Offset+1)
-	}
-	return &pb.ReplicateAck{}, nil
+// Replicate era la réplica por broadcast: escribía con offsets locales y
+// sin término, fuera del log que controla el consenso. Los logs se
+// replican con Append; un peer antiguo que aún lo llame recibe
+// FailedPrecondition.
+func (s *replicaSrv) Replicate(context.Context, *pb.ReplicateRequest) (*pb.ReplicateAck, error) {
+	return nil, status.Error(codes.FailedPrecondition, "Replicate is no longer accepted: partition logs replicate through consensus Append")
 }
 
 /*──────────  ping  ──────────*/
@@ -56,15 +54,43 @@ func (s *replicaSrv) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, erro
 	return &emptypb.Empty{}, nil
 }
 
+/*──────────  estado (HWM) para /cluster/status  ──────────*/
+
+func (s *replicaSrv) Status(context.Context, *emptypb.Empty) (*pb.NodeStatus, error) {
+	return &pb.NodeStatus{NodeId: GlobalSelfID, Hwm: Snapshot()}, nil
+}
+
+/*──────────  log replicado (consenso)  ──────────*/
+
+func (s *replicaSrv) Append(ctx context.Context, in *pb.AppendRequest) (*pb.AppendReply, error) {
+	if s.cons == nil {
+		return nil, status.Error(codes.Unimplemented, "consensus disabled")
+	}
+	ctx, span := tracing.StartKind(tracing.IncomingGRPC(ctx), "replicaSrv.Append",
+		trace.SpanKindServer, attribute.String("mom.topic", in.Topic), attribute.Int("mom.batch", len(in.Entries)))
+	defer span.End()
+	return s.cons.HandleAppend(ctx, in)
+}
+
+func (s *replicaSrv) Vote(_ context.Context, in *pb.VoteRequest) (*pb.VoteReply, error) {
+	if s.cons == nil {
+		return nil, status.Error(codes.Unimplemented, "consensus disabled")
+	}
+	return s.cons.HandleVote(in), nil
+}
+
 /*──────────  range para catch-up  ──────────*/
 
+// GetRange devuelve como mucho getRangeMax mensajes: todo el rango en una
+// sola respuesta no cabe en un mensaje gRPC. El catch-up usa StreamRange;
+// GetRange queda para los nodos que aún no lo tienen.
 func (s *replicaSrv) GetRange(ctx context.Context,
 	in *pb.RangeRequest,
 ) (*pb.RangeBatch, error) {
 
-	max := int(in.To - in.From)
-	if in.To == 0 {
-		max = 1_000_000
+	max := getRangeMax
+	if in.To > in.From && in.To-in.From < uint64(max) {
+		max = int(in.To - in.From)
 	}
 	msgs, err := s.store.Read(ctx, in.Topic, int(in.Part), in.From, max)
 	if err != nil {
@@ -72,78 +98,52 @@ func (s *replicaSrv) GetRange(ctx context.Context,
 	}
 	out := make([]*pb.Message, 0, len(msgs))
 	for _, m := range msgs {
-		out = append(out, &pb.Message{
-			Uuid:    m.ID.String(),
-			Topic:   m.Topic,
-			Part:    uint32(m.PartID),
-			Offset:  m.Offset,
-			Key:     m.Key,
-			User:    m.Producer,
-			Payload: m.Payload,
-		})
+		out = append(out, ToPB(m))
 	}
 	return &pb.RangeBatch{Batch: out}, nil
 }
 
-/*──────────  arranque y reconciliación  ──────────*/
-
-func StartGRPCServer(addr string, store outbound.MessageStore, meta outbound.MetaStore) {
-	// guarda la referencia a MetaStore para el reconciliador
-	GlobalMeta = meta
+/*──────────  arranque  ──────────*/
 
+// StartGRPCServer abre el listener del Replicator; sólo acepta llamadas
+// que traigan secret. local atiende las operaciones que reenvían otros
+// nodos y det responde al gossip. El servidor devuelto se para con
+// StopGRPC; la reparación entre réplicas la hace AntiEntropy.
+func StartGRPCServer(addr, secret string, store outbound.MessageStore, cons *Consensus, local *Local,
+	det *Detector) *grpc.Server {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Fatalf("[cluster] listen %s: %v", addr, err)
 	}
-	s := grpc.NewServer()
-	pb.RegisterReplicatorServer(s, &replicaSrv{store: store})
+	s := grpc.NewServer(grpc.UnaryInterceptor(peerSecret(secret).unary),
+		grpc.StreamInterceptor(peerSecret(secret).stream))
+	pb.RegisterReplicatorServer(s, &replicaSrv{store: store, cons: cons, local: local, det: det})
 	log.Printf("[cluster] gRPC en %s", addr)
 
-	/*── reconciliación cada 30 s ─*/
+	grpcServing.Store(true)
 	go func() {
-		t := time.NewTicker(30 * time.Second)
-		for range t.C {
-			reconcile(store)
-		}
+		_ = s.Serve(lis)
+		grpcServing.Store(false)
 	}()
-
-	go func() { _ = s.Serve(lis) }()
+	return s
 }
 
-/*──────────  reconciliador  ──────────*/
-
-// pide a UN peer vivo todos los mensajes que le falten en cada (topic,part)
-func reconcile(store outbound.MessageStore) {
-	if GlobalMeta == nil {
+// StopGRPC deja de aceptar llamadas y espera a las que están en curso; si
+// ctx vence antes, corta las que queden.
+func StopGRPC(ctx context.Context, s *grpc.Server) {
+	if s == nil {
 		return
 	}
-	ctx := context.Background()
-
-	// 1) snapshot de los HWM en RAM
-	local := Snapshot()
-
-	// 2) prepara fan-out
-	f := NewFanout(GlobalCfg, GlobalSelfID)
-	if f == nil {
-		return
-	}
-
-	// 3) por cada topic y part que existan en el catálogo
-	topics, _ := GlobalMeta.ListTopics(ctx)
-	for _, tp := range topics {
-		parts, err := GlobalMeta.GetTopic(ctx, tp) // nº de particiones
-		if err != nil {
-			continue
-		}
-		for p := 0; p < parts; p++ {
-			key := fmt.Sprintf("%s:%d", tp, p)
-			from := local[key] // 0 si no existe
-			// busca el primer peer vivo
-			for peer := range f.peers {
-				_ = f.CatchUp(ctx, peer, store, tp, p, from, 0)
-				break
-			}
-		}
+	grpcServing.Store(false)
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
 	}
 }
 
@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var (
+	peerMu    sync.RWMutex
+	peerState = map[string]*model.PeerStatus{}
+)
+
+/*──────────  sondeo periódico  ──────────*/
+
+// StartHealthLoop hace una ronda del detector de fallos (Gossip, o Ping sin
+// detector) y un Status a cada peer cada `every`, y guarda el resultado
+// para /readyz y /cluster/status. Se detiene al cancelarse ctx; wg cuenta
+// el bucle.
+func StartHealthLoop(ctx context.Context, wg *sync.WaitGroup, f *Fanout, every time.Duration) {
+	if f == nil {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			probePeers(f, every)
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func probePeers(f *Fanout, timeout time.Duration) {
+	local := Snapshot()
+	det := f.detector()
+	if det != nil {
+		det.tick()
+	}
+	cfg, clients := f.view()
+	for _, n := range cfg.Nodes {
+		cli := clients[n.ID]
+		if cli == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var st *model.PeerStatus
+		var err error
+		if det != nil {
+			err = det.probe(ctx, n.ID, cli)
+		} else {
+			_, err = cli.Ping(ctx, &emptypb.Empty{})
+		}
+		if err == nil {
+			var ns *pb.NodeStatus
+			if ns, err = cli.Status(ctx, &emptypb.Empty{}); err == nil {
+				now := time.Now().UTC()
+				st = &model.PeerStatus{Alive: true, LastPing: &now, Lag: lagAgainst(local, ns.Hwm)}
+			}
+		}
+		cancel()
+
+		peerMu.Lock()
+		prev := peerState[n.ID]
+		if st == nil { // falló: se conserva el último ping bueno
+			st = &model.PeerStatus{Lag: map[string]uint64{}}
+			if prev != nil {
+				st.LastPing = prev.LastPing
+			}
+			st.LastError = err.Error()
+		}
+		st.ID, st.Host = n.ID, n.Host
+		peerState[n.ID] = st
+		peerMu.Unlock()
+	}
+}
+
+// lagAgainst devuelve, por partición, cuánto va el peer por delante. Los
+// tópicos locales de cada nodo no cuentan: nunca se igualan.
+func lagAgainst(local, remote map[string]uint64) map[string]uint64 {
+	out := map[string]uint64{}
+	for k, r := range remote {
+		if i := strings.LastIndexByte(k, ':'); i >= 0 && model.NodeLocal(k[:i]) {
+			continue
+		}
+		if l := local[k]; r > l {
+			out[k] = r - l
+		}
+	}
+	return out
+}
+
+/*──────────  consultas  ──────────*/
+
+// ClusterStatus devuelve el estado de todos los peers de cfg.
+func ClusterStatus(f *Fanout) model.ClusterStatus {
+	det := f.detector()
+	st := model.ClusterStatus{Self: GlobalSelfID, Leader: f.Leader(), Peers: []model.PeerStatus{},
+		Events: det.Events()}
+	cfg := CurrentConfig()
+	if cfg == nil {
+		return st
+	}
+	peerMu.RLock()
+	defer peerMu.RUnlock()
+	for _, n := range cfg.Nodes {
+		if n.ID == GlobalSelfID {
+			continue
+		}
+		p := model.PeerStatus{ID: n.ID, Host: n.Host, Lag: map[string]uint64{}}
+		if prev := peerState[n.ID]; prev != nil {
+			p = *prev
+		}
+		var since time.Time
+		if p.State, since = det.State(n.ID); !since.IsZero() {
+			since = since.UTC()
+			p.Since = &since
+		}
+		st.Peers = append(st.Peers, p)
+	}
+	return st
+}
+
+// forgetPeers borra el estado de los nodos que salieron del clúster.
+func forgetPeers(ids []string) {
+	peerMu.Lock()
+	defer peerMu.Unlock()
+	for _, id := range ids {
+		delete(peerState, id)
+	}
+}
+
+// MaxReplicationLag es el mayor lag respecto a cualquier peer vivo.
+func MaxReplicationLag() uint64 {
+	peerMu.RLock()
+	defer peerMu.RUnlock()
+	var max uint64
+	for _, p := range peerState {
+		if !p.Alive {
+			continue
+		}
+		for _, l := range p.Lag {
+			if l > max {
+				max = l
+			}
+		}
+	}
+	return max
+}
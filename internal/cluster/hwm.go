@@ -2,8 +2,10 @@ package cluster
 
 import (
 	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/MateoRamirezRubio1/project_MOM/internal/metrics"
 	"github.com/dgraph-io/badger/v4"
 )
 
@@ -12,6 +14,22 @@ var (
 	hwm   = map[string]uint64{} // topic:part → próximo offset
 )
 
+/*────────────────────────  métrica  ─────────────────────────*/
+
+func init() {
+	metrics.RegisterGaugeFunc("mom_partition_hwm",
+		"Next offset to assign per partition, as tracked in memory.",
+		[]string{"topic", "partition"}, func() []metrics.Sample {
+			snap := Snapshot()
+			out := make([]metrics.Sample, 0, len(snap))
+			for k, next := range snap {
+				i := strings.LastIndexByte(k, ':')
+				out = append(out, metrics.Sample{Labels: []string{k[:i], k[i+1:]}, Value: float64(next)})
+			}
+			return out
+		})
+}
+
 /*────────────────────────  API en RAM  ───────────────────────*/
 
 // TrackNextOffset deja constancia (en memoria) del próximo offset.
@@ -24,7 +42,21 @@ func TrackNextOffset(topic string, part int, next uint64) {
 	hwmMu.Unlock()
 }
 
-// Snapshot copia el mapa de forma segura (lo usa el reconciliador).
+// ResetNextOffset fija el HWM en RAM aunque baje (tras truncar el log).
+func ResetNextOffset(topic string, part int, next uint64) {
+	hwmMu.Lock()
+	hwm[topic+":"+strconv.Itoa(part)] = next
+	hwmMu.Unlock()
+}
+
+// NextOffset devuelve el HWM en RAM de una partición (0 si no se conoce).
+func NextOffset(topic string, part int) uint64 {
+	hwmMu.RLock()
+	defer hwmMu.RUnlock()
+	return hwm[topic+":"+strconv.Itoa(part)]
+}
+
+// Snapshot copia el mapa de forma segura (para la métrica y /cluster/status).
 func Snapshot() map[string]uint64 {
 	hwmMu.RLock()
 	cp := make(map[string]uint64, len(hwm))
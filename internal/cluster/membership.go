@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+)
+
+/*──────────  membresía vigente  ──────────*/
+
+var (
+	cfgMu     sync.RWMutex
+	globalCfg *Config
+)
+
+// SetConfig publica la membresía vigente (la rellena wiring.go al arrancar).
+func SetConfig(c *Config) {
+	cfgMu.Lock()
+	globalCfg = c
+	cfgMu.Unlock()
+}
+
+// CurrentConfig devuelve la membresía vigente; nil en single-node.
+func CurrentConfig() *Config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return globalCfg
+}
+
+// Clone copia la lista de nodos para poder modificarla sin tocar la vigente.
+func (c *Config) Clone() *Config {
+	return &Config{Nodes: append([]Node(nil), c.Nodes...)}
+}
+
+// Validate comprueba IDs y hosts únicos y que selfID siga en el clúster.
+func (c *Config) Validate(selfID string) error {
+	ids, hosts := map[string]bool{}, map[string]bool{}
+	for i, n := range c.Nodes {
+		if n.ID == "" || n.Host == "" {
+			return errs.Invalid("node #%d needs id and host", i)
+		}
+		if ids[n.ID] {
+			return errs.Invalid("node id %q duplicated", n.ID)
+		}
+		if hosts[n.Host] {
+			return errs.Invalid("node host %q duplicated", n.Host)
+		}
+		ids[n.ID], hosts[n.Host] = true, true
+	}
+	if !ids[selfID] {
+		return errs.Invalid("node %q cannot leave its own cluster", selfID)
+	}
+	return nil
+}
+
+// Save escribe la membresía en formato cluster.json (escritura atómica).
+func (c *Config) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cluster-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+/*──────────  recarga en caliente  ──────────*/
+
+// Reload valida cfg, la publica y reconecta el fan-out. Los peers nuevos
+// entran en el siguiente latido del consenso o pasada de anti-entropía y
+// los retirados se desconectan.
+func Reload(f *Fanout, cfg *Config) error {
+	if f == nil {
+		return errs.Unavailable("node is not running in cluster mode")
+	}
+	if err := cfg.Validate(f.self); err != nil {
+		return err
+	}
+	SetConfig(cfg)
+	added, removed := f.Update(cfg)
+	forgetPeers(removed)
+	f.detector().forget(removed)
+	if len(added)+len(removed) > 0 {
+		log.Printf("[cluster] membresía actualizada: +%v -%v", added, removed)
+	}
+	return nil
+}
+
+// WatchFile relee path cada `every` y aplica Reload cuando cambia su
+// contenido. Un archivo inválido se registra y se ignora.
+func WatchFile(ctx context.Context, wg *sync.WaitGroup, path string, every time.Duration, f *Fanout) {
+	if f == nil || path == "" {
+		return
+	}
+	last, _ := os.ReadFile(path)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			b, err := os.ReadFile(path)
+			if err != nil || bytes.Equal(b, last) {
+				continue
+			}
+			last = b
+			cfg, err := Load(path)
+			if err == nil {
+				err = Reload(f, cfg)
+			}
+			if err != nil {
+				log.Printf("[cluster] %s ignorado: %v", path, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,269 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// Operaciones del log de metadatos.
+const (
+	metaCreateTopic  = "create_topic"
+	metaDeleteTopic  = "delete_topic"
+	metaCreateQueue  = "create_queue"
+	metaDeleteQueue  = "delete_queue"
+	metaCommitOffset = "commit_offset"
+	metaSetReplicas  = "set_replicas"
+	metaPutMirror    = "put_mirror"
+	metaDeleteMirror = "delete_mirror"
+	metaCommitMirror = "commit_mirror"
+	metaPutUser      = "put_user"
+	metaDeleteUser   = "delete_user"
+	metaPutACL       = "put_acl"
+	metaDeleteACL    = "delete_acl"
+	metaPutQuota     = "put_quota"
+)
+
+// OpMeta es la ForwardRequest.op con la que un seguidor entrega una
+// entrada del log de metadatos al líder.
+const OpMeta = "meta"
+
+type metaOp struct {
+	Op     string `json:"op"`
+	Name   string `json:"name,omitempty"` // tópico o cola
+	Parts  int    `json:"parts,omitempty"`
+	User   string `json:"user,omitempty"`
+	Group  string `json:"group,omitempty"`
+	Part   int    `json:"part,omitempty"`
+	Offset uint64 `json:"offset,omitempty"`
+	// Replicas: réplicas por partición (create_topic, set_replicas).
+	Replicas [][]string `json:"replicas,omitempty"`
+	// Mirror: definición completa (put_mirror); commit_mirror usa Name
+	// (mirror), Topic, Part y Offset.
+	Mirror *model.Mirror `json:"mirror,omitempty"`
+	Topic  string        `json:"topic,omitempty"`
+	// Account y ACL: put_user y put_acl; los delete usan Name (usuario o
+	// id de la ACL).
+	Account *model.User `json:"account,omitempty"`
+	ACL     *model.ACL  `json:"acl,omitempty"`
+	// Quota: put_quota.
+	Quota *model.QuotaSetting `json:"quota,omitempty"`
+}
+
+// ReplicatedMeta es un MetaStore cuyas escrituras pasan por el log
+// replicado model.MetaTopic: cada nodo aplica las entradas comprometidas,
+// en orden, sobre su catálogo local, así que todos ven los mismos tópicos,
+// colas y offsets. Las lecturas van directas al catálogo local. snap
+// vuelca el catálogo entero —usuarios, ACLs y cuotas incluidos— para
+// compactar el log.
+type ReplicatedMeta struct {
+	outbound.MetaStore // catálogo local
+
+	snap    Snapshotter
+	log     *replicatedLog
+	onQuota func(model.QuotaSetting) // nil → nadie aplica las cuotas
+}
+
+func NewReplicatedMeta(local outbound.MetaStore, snap Snapshotter, cons *Consensus, fan *Fanout,
+	store outbound.MessageStore, applied AppliedStore) *ReplicatedMeta {
+
+	r := &ReplicatedMeta{MetaStore: local, snap: snap}
+	r.log = newReplicatedLog(model.MetaTopic, OpMeta, cons, fan, store, applied, r.apply)
+	r.log.snapshot, r.log.restore = snap.Snapshot, r.restore
+	return r
+}
+
+var _ outbound.MetaStore = (*ReplicatedMeta)(nil)
+
+// OnQuota hace que f reciba cada cuota que este nodo aplica del log o de
+// un snapshot. Se llama antes de Start.
+func (r *ReplicatedMeta) OnQuota(f func(model.QuotaSetting)) { r.onQuota = f }
+
+/*──────────  escrituras  ──────────*/
+
+func (r *ReplicatedMeta) CreateTopic(ctx context.Context, name string, parts int, replicas [][]string, creator string) error {
+	return r.propose(ctx, metaOp{Op: metaCreateTopic, Name: name, Parts: parts, Replicas: replicas, User: creator})
+}
+
+func (r *ReplicatedMeta) SetReplicas(ctx context.Context, name string, replicas [][]string) error {
+	return r.propose(ctx, metaOp{Op: metaSetReplicas, Name: name, Replicas: replicas})
+}
+
+func (r *ReplicatedMeta) DeleteTopic(ctx context.Context, name, user string) error {
+	return r.propose(ctx, metaOp{Op: metaDeleteTopic, Name: name, User: user})
+}
+
+func (r *ReplicatedMeta) CreateQueue(ctx context.Context, name, creator string) error {
+	return r.propose(ctx, metaOp{Op: metaCreateQueue, Name: name, User: creator})
+}
+
+func (r *ReplicatedMeta) DeleteQueue(ctx context.Context, name, user string) error {
+	return r.propose(ctx, metaOp{Op: metaDeleteQueue, Name: name, User: user})
+}
+
+func (r *ReplicatedMeta) CommitOffset(ctx context.Context, group, topic string, part int, offset uint64) error {
+	return r.propose(ctx, metaOp{Op: metaCommitOffset, Group: group, Name: topic, Part: part, Offset: offset})
+}
+
+func (r *ReplicatedMeta) PutMirror(ctx context.Context, m model.Mirror) error {
+	return r.propose(ctx, metaOp{Op: metaPutMirror, Name: m.Name, Mirror: &m})
+}
+
+func (r *ReplicatedMeta) DeleteMirror(ctx context.Context, name string) error {
+	return r.propose(ctx, metaOp{Op: metaDeleteMirror, Name: name})
+}
+
+func (r *ReplicatedMeta) CommitMirror(ctx context.Context, name, topic string, part int, next uint64) error {
+	return r.propose(ctx, metaOp{Op: metaCommitMirror, Name: name, Topic: topic, Part: part, Offset: next})
+}
+
+func (r *ReplicatedMeta) PutUser(ctx context.Context, u model.User) error {
+	return r.propose(ctx, metaOp{Op: metaPutUser, Name: u.Name, Account: &u})
+}
+
+func (r *ReplicatedMeta) DeleteUser(ctx context.Context, name string) error {
+	return r.propose(ctx, metaOp{Op: metaDeleteUser, Name: name})
+}
+
+func (r *ReplicatedMeta) PutACL(ctx context.Context, a model.ACL) error {
+	return r.propose(ctx, metaOp{Op: metaPutACL, Name: a.ID, ACL: &a})
+}
+
+func (r *ReplicatedMeta) DeleteACL(ctx context.Context, id string) error {
+	return r.propose(ctx, metaOp{Op: metaDeleteACL, Name: id})
+}
+
+func (r *ReplicatedMeta) PutQuota(ctx context.Context, s model.QuotaSetting) error {
+	return r.propose(ctx, metaOp{Op: metaPutQuota, Name: s.Name, Quota: &s})
+}
+
+// propose escribe op en el log de metadatos y espera a aplicarla aquí: el
+// error es el de la aplicación, idéntico en todos los nodos.
+func (r *ReplicatedMeta) propose(ctx context.Context, op metaOp) (err error) {
+	ctx, span := tracing.Start(ctx, "ReplicatedMeta."+op.Op)
+	defer func() { tracing.End(span, err) }()
+
+	payload, _ := json.Marshal(op)
+	_, err = r.log.propose(ctx, uuid.New(), payload, op.User)
+	return err
+}
+
+/*──────────  aplicación  ──────────*/
+
+// Start registra la partición de MetaTopic en el consenso y aplica sus
+// entradas comprometidas hasta que se cancele ctx.
+func (r *ReplicatedMeta) Start(ctx context.Context, wg *sync.WaitGroup) { r.log.Start(ctx, wg) }
+
+func (r *ReplicatedMeta) apply(ctx context.Context, m model.Message) (any, error) {
+	var op metaOp
+	if err := json.Unmarshal(m.Payload, &op); err != nil {
+		log.Printf("[meta] entrada %d ilegible: %v", m.Offset, err)
+		return nil, errs.Invalid("malformed metadata entry")
+	}
+	return nil, r.applyOp(ctx, op)
+}
+
+// restore sustituye el catálogo por un snapshot, mete en el consenso las
+// particiones de sus tópicos, como haría create_topic, y entrega sus
+// cuotas a onQuota.
+func (r *ReplicatedMeta) restore(ctx context.Context, raw []byte) error {
+	if err := r.snap.Restore(raw); err != nil {
+		return err
+	}
+	if r.onQuota != nil {
+		quotas, err := r.ListQuotas(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range quotas {
+			r.onQuota(s)
+		}
+	}
+	names, err := r.ListTopics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		t, err := r.DescribeTopic(ctx, name)
+		if err != nil {
+			return err
+		}
+		r.log.cons.place(name, t.Partitions, t.Replicas, t.Replicas, true)
+	}
+	return nil
+}
+
+func (r *ReplicatedMeta) applyOp(ctx context.Context, op metaOp) error {
+	local := r.MetaStore
+	switch op.Op {
+	case metaCreateTopic:
+		if err := local.CreateTopic(ctx, op.Name, op.Parts, op.Replicas, op.User); err != nil {
+			return err
+		}
+		// las particiones entran ya en el consenso, sin esperar a discover
+		r.log.cons.place(op.Name, op.Parts, op.Replicas, op.Replicas, true)
+		return nil
+	case metaSetReplicas:
+		// las réplicas de antes votan hasta que el líder complete el cambio
+		t, err := local.DescribeTopic(ctx, op.Name)
+		if err != nil {
+			return err
+		}
+		if err := local.SetReplicas(ctx, op.Name, op.Replicas); err != nil {
+			return err
+		}
+		r.log.cons.place(op.Name, t.Partitions, op.Replicas, t.Replicas, true)
+		return nil
+	case metaDeleteTopic:
+		return local.DeleteTopic(ctx, op.Name, op.User)
+	case metaCreateQueue:
+		return local.CreateQueue(ctx, op.Name, op.User)
+	case metaDeleteQueue:
+		return local.DeleteQueue(ctx, op.Name, op.User)
+	case metaCommitOffset:
+		return local.CommitOffset(ctx, op.Group, op.Name, op.Part, op.Offset)
+	case metaPutMirror:
+		if op.Mirror == nil {
+			return errs.Invalid("put_mirror without mirror")
+		}
+		return local.PutMirror(ctx, *op.Mirror)
+	case metaDeleteMirror:
+		return local.DeleteMirror(ctx, op.Name)
+	case metaCommitMirror:
+		return local.CommitMirror(ctx, op.Name, op.Topic, op.Part, op.Offset)
+	case metaPutUser:
+		if op.Account == nil {
+			return errs.Invalid("put_user without account")
+		}
+		return local.PutUser(ctx, *op.Account)
+	case metaDeleteUser:
+		return local.DeleteUser(ctx, op.Name)
+	case metaPutACL:
+		if op.ACL == nil {
+			return errs.Invalid("put_acl without acl")
+		}
+		return local.PutACL(ctx, *op.ACL)
+	case metaDeleteACL:
+		return local.DeleteACL(ctx, op.Name)
+	case metaPutQuota:
+		if op.Quota == nil {
+			return errs.Invalid("put_quota without quota")
+		}
+		if err := local.PutQuota(ctx, *op.Quota); err != nil {
+			return err
+		}
+		if r.onQuota != nil {
+			r.onQuota(*op.Quota)
+		}
+		return nil
+	}
+	log.Printf("[meta] operación desconocida %q", op.Op)
+	return errs.Invalid("unknown metadata operation %q", op.Op)
+}
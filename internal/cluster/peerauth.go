@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// peerSecretHeader lleva en cada llamada gRPC el secreto compartido del
+// clúster (cluster.secret): el puerto gRPC no tiene TLS y sin él cualquiera
+// podría escribir en los logs o reenviar operaciones en nombre de otro
+// usuario.
+const peerSecretHeader = "mom-cluster-secret"
+
+// peerSecret añade el secreto a las llamadas salientes.
+type peerSecret string
+
+func (s peerSecret) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{peerSecretHeader: string(s)}, nil
+}
+
+func (peerSecret) RequireTransportSecurity() bool { return false }
+
+// check rechaza las llamadas entrantes sin el secreto.
+func (s peerSecret) check(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(peerSecretHeader) {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(s)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong cluster secret")
+}
+
+func (s peerSecret) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+	if err := s.check(ctx); err != nil {
+		return nil, err
+	}
+	return h(ctx, req)
+}
+
+func (s peerSecret) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+	if err := s.check(ss.Context()); err != nil {
+		return err
+	}
+	return h(srv, ss)
+}
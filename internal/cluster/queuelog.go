@@ -0,0 +1,197 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// QueueLogStore aplica en el store local, de forma determinista, las
+// entradas del log de colas. at es el offset de la entrada: el store lo
+// guarda como progreso del log en la misma transacción e ignora lo ya
+// aplicado, así reaplicar tras una caída no entrega un mensaje dos veces.
+type QueueLogStore interface {
+	ApplyEnqueue(at uint64, queue string, msg model.Message) error
+	// ApplyDequeue pasa a en vuelo, hasta deadline, la cabeza de la cola
+	// (nil si está vacía).
+	ApplyDequeue(at uint64, queue string, deadline time.Time) (*model.Message, error)
+	ApplyAck(at uint64, queue string, id uuid.UUID) error
+	// ApplyRequeue devuelve id al final de la cola si sigue en vuelo con
+	// plazo anterior a now.
+	ApplyRequeue(at uint64, queue string, id uuid.UUID, now time.Time) error
+	// Expired lista los mensajes en vuelo con plazo anterior a now.
+	Expired(now time.Time) ([]QueueRef, error)
+	// SnapshotQueues vuelca pendientes y en vuelo de todas las colas;
+	// RestoreQueues los sustituye por un volcado.
+	SnapshotQueues() ([]byte, error)
+	RestoreQueues(raw []byte) error
+}
+
+// QueueRef identifica un mensaje en vuelo.
+type QueueRef struct {
+	Queue string
+	ID    uuid.UUID
+}
+
+// Operaciones del log de colas.
+const (
+	queueEnqueue = "enqueue"
+	queueDequeue = "dequeue"
+	queueAck     = "ack"
+	queueRequeue = "requeue"
+)
+
+// OpQueue es la ForwardRequest.op con la que un seguidor entrega una
+// entrada del log de colas al líder.
+const OpQueue = "queue"
+
+type queueOp struct {
+	Op    string         `json:"op"`
+	Queue string         `json:"queue"`
+	Msg   *model.Message `json:"msg,omitempty"` // enqueue
+	ID    uuid.UUID      `json:"id,omitempty"`  // ack, requeue
+	At    time.Time      `json:"at,omitempty"`  // plazo (dequeue) o ahora (requeue)
+}
+
+// ReplicatedQueues es un MessageStore cuyas operaciones de cola pasan por
+// el log replicado model.QueueTopic: cada nodo aplica en orden altas,
+// entregas, acks y reencolados sobre su store local, así que cualquiera
+// atiende cualquier cola, un mensaje se entrega a un solo consumidor del
+// clúster y, si cae un nodo, los demás conservan lo que estaba en vuelo.
+// Sólo el líder del log reencola los mensajes caducados. El log se
+// compacta con snapshots de las colas: las altas ya confirmadas no
+// sobreviven al siguiente.
+type ReplicatedQueues struct {
+	outbound.MessageStore // store local: tópicos y lecturas de colas
+
+	local    QueueLogStore
+	ttl      time.Duration // plazo para hacer ack
+	interval time.Duration // cada cuánto se buscan caducados
+	log      *replicatedLog
+}
+
+func NewReplicatedQueues(store outbound.MessageStore, local QueueLogStore, cons *Consensus, fan *Fanout,
+	applied AppliedStore, ttl, interval time.Duration) *ReplicatedQueues {
+
+	r := &ReplicatedQueues{MessageStore: store, local: local, ttl: ttl, interval: interval}
+	r.log = newReplicatedLog(model.QueueTopic, OpQueue, cons, fan, store, applied, r.apply)
+	r.log.snapshot = local.SnapshotQueues
+	r.log.restore = func(_ context.Context, raw []byte) error { return local.RestoreQueues(raw) }
+	return r
+}
+
+var _ outbound.MessageStore = (*ReplicatedQueues)(nil)
+
+/*──────────  operaciones  ──────────*/
+
+func (r *ReplicatedQueues) Enqueue(ctx context.Context, queue string, msg model.Message) error {
+	if msg.ID == uuid.Nil {
+		msg.ID = uuid.New()
+	}
+	_, err := r.propose(ctx, msg.ID, queueOp{Op: queueEnqueue, Queue: queue, Msg: &msg}, msg.Producer)
+	return err
+}
+
+// Dequeue no escribe en el log si la cola está vacía tras aplicar aquí
+// todo lo comprometido; si este nodo no se pone al día a tiempo, propone
+// la entrega igualmente.
+func (r *ReplicatedQueues) Dequeue(ctx context.Context, queue string) (*model.Message, error) {
+	if r.log.caughtUp(ctx) {
+		if st, err := r.QueueStats(ctx, queue); err == nil && st.Depth == 0 {
+			return nil, nil
+		}
+	}
+	val, err := r.propose(ctx, uuid.New(),
+		queueOp{Op: queueDequeue, Queue: queue, At: time.Now().Add(r.ttl)}, "")
+	m, _ := val.(*model.Message)
+	return m, err
+}
+
+func (r *ReplicatedQueues) Ack(ctx context.Context, queue string, id uuid.UUID) error {
+	_, err := r.propose(ctx, uuid.New(), queueOp{Op: queueAck, Queue: queue, ID: id}, "")
+	return err
+}
+
+func (r *ReplicatedQueues) propose(ctx context.Context, id uuid.UUID, op queueOp, user string) (val any, err error) {
+	ctx, span := tracing.Start(ctx, "ReplicatedQueues."+op.Op)
+	defer func() { tracing.End(span, err) }()
+
+	payload, _ := json.Marshal(op)
+	return r.log.propose(ctx, id, payload, user)
+}
+
+/*──────────  aplicación  ──────────*/
+
+// Start registra la partición de QueueTopic en el consenso, aplica sus
+// entradas y, mientras este nodo sea el líder, reencola los mensajes en
+// vuelo caducados. Termina al cancelar ctx.
+func (r *ReplicatedQueues) Start(ctx context.Context, wg *sync.WaitGroup) {
+	r.log.Start(ctx, wg)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(r.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+			if r.log.isLeader() {
+				r.requeueExpired(ctx)
+			}
+		}
+	}()
+}
+
+func (r *ReplicatedQueues) requeueExpired(ctx context.Context) {
+	now := time.Now()
+	refs, err := r.local.Expired(now)
+	if err != nil {
+		log.Printf("[queues] caducados: %v", err)
+		return
+	}
+	for _, ref := range refs {
+		op := queueOp{Op: queueRequeue, Queue: ref.Queue, ID: ref.ID, At: now}
+		if _, err := r.propose(ctx, uuid.New(), op, ""); err != nil {
+			log.Printf("[queues] reencolar %s/%s: %v", ref.Queue, ref.ID, err)
+			return
+		}
+	}
+}
+
+func (r *ReplicatedQueues) apply(_ context.Context, m model.Message) (any, error) {
+	var op queueOp
+	if err := json.Unmarshal(m.Payload, &op); err != nil {
+		log.Printf("[queues] entrada %d ilegible: %v", m.Offset, err)
+		return nil, errs.Invalid("malformed queue entry")
+	}
+	switch op.Op {
+	case queueEnqueue:
+		if op.Msg == nil {
+			return nil, errs.Invalid("enqueue entry without message")
+		}
+		return nil, r.local.ApplyEnqueue(m.Offset, op.Queue, *op.Msg)
+	case queueDequeue:
+		msg, err := r.local.ApplyDequeue(m.Offset, op.Queue, op.At)
+		if msg == nil {
+			return nil, err // interfaz nil, no (*model.Message)(nil)
+		}
+		return msg, err
+	case queueAck:
+		return nil, r.local.ApplyAck(m.Offset, op.Queue, op.ID)
+	case queueRequeue:
+		return nil, r.local.ApplyRequeue(m.Offset, op.Queue, op.ID, op.At)
+	}
+	log.Printf("[queues] entrada %d: operación desconocida %q", m.Offset, op.Op)
+	return nil, errs.Invalid("unknown queue operation %q", op.Op)
+}
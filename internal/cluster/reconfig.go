@@ -0,0 +1,214 @@
+package cluster
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+)
+
+/*
+Cambio de réplicas de una partición (Raft joint consensus):
+
+ 1. el catálogo pide réplicas nuevas (place): las que no votan aún entran
+    como learners; reciben el log del líder, a CatchUpRate, pero no votan
+    ni cuentan para la mayoría ni empiezan elecciones;
+ 2. cuando todas llegan a commit, el líder escribe la configuración
+    conjunta (viejas + nuevas): para comprometer y para ganar una elección
+    hace falta mayoría en los dos conjuntos;
+ 3. comprometida la conjunta, escribe la nueva; un líder que no está en
+    ella deja el liderazgo cuando se compromete.
+
+La configuración viaja en registros de control del log y cada nodo adopta
+la última que tiene, aunque no esté comprometida; si se trunca, vuelve a
+la anterior. Sólo hay un cambio pendiente a la vez.
+*/
+
+// ControlConfig es el registro de control con una configuración nueva.
+const ControlConfig = "config"
+
+// partConfig son los votantes de una partición.
+type partConfig struct {
+	Voters []string `json:"voters,omitempty"` // nil → todos los nodos
+	Joint  []string `json:"joint,omitempty"`  // réplicas nuevas durante el cambio
+	// End es el offset siguiente al registro que la fijó; 0 si no viene
+	// del log (catálogo).
+	End uint64 `json:"end,omitempty"`
+}
+
+// confRec es lo que se guarda en TermStore: la configuración vigente (nil
+// → desconocida) y la anterior, a la que se vuelve si se trunca.
+type confRec struct {
+	Conf *partConfig `json:"conf,omitempty"`
+	Prev *partConfig `json:"prev,omitempty"`
+}
+
+func (c *Consensus) loadConfig(ps *partState) {
+	raw, err := c.terms.LoadConfig(ps.topic, ps.part)
+	if err != nil || raw == nil {
+		return
+	}
+	var rec confRec
+	if err := json.Unmarshal(raw, &rec); err != nil || rec.Conf == nil {
+		log.Printf("[raft] %s:%d configuración ilegible: %v", ps.topic, ps.part, err)
+		return
+	}
+	ps.conf, ps.prev, ps.known = *rec.Conf, rec.Prev, true
+}
+
+// saveConfig requiere ps.mu.
+func (c *Consensus) saveConfig(ps *partState) {
+	rec := confRec{Prev: ps.prev}
+	if ps.known {
+		rec.Conf = &ps.conf
+	}
+	raw, _ := json.Marshal(rec)
+	if err := c.terms.SaveConfig(ps.topic, ps.part, raw); err != nil {
+		log.Printf("[raft] %s:%d guardar configuración: %v", ps.topic, ps.part, err)
+	}
+}
+
+/*──────────  quién vota  ──────────*/
+
+// members resuelve un conjunto de votantes: nil son todos los nodos.
+func (c *Consensus) members(ids []string) []string {
+	if ids != nil {
+		return ids
+	}
+	cfg, _ := c.fan.view()
+	out := make([]string, 0, len(cfg.Nodes))
+	for _, n := range cfg.Nodes {
+		out = append(out, n.ID)
+	}
+	return out
+}
+
+// votes indica si id vota con la configuración vigente; requiere ps.mu.
+func (c *Consensus) votes(ps *partState, id string) bool {
+	return slices.Contains(c.members(ps.conf.Voters), id) || slices.Contains(ps.conf.Joint, id)
+}
+
+// voter indica si este nodo vota y puede liderar; requiere ps.mu. Sin
+// configuración conocida sólo vota en particiones de todos los nodos: con
+// réplicas asignadas puede ser una réplica nueva que aún se copia, y si se
+// eligiera a sí misma con el log vacío perdería lo comprometido.
+func (c *Consensus) voter(ps *partState) bool {
+	if !ps.known {
+		return ps.target == nil
+	}
+	return c.votes(ps, c.self)
+}
+
+// quorate indica si has se cumple en la mayoría de los votantes y, durante
+// un cambio, también en la mayoría de Joint; requiere ps.mu.
+func (c *Consensus) quorate(ps *partState, has func(id string) bool) bool {
+	majority := func(ids []string) bool {
+		n := 0
+		for _, id := range ids {
+			if has(id) {
+				n++
+			}
+		}
+		return n >= len(ids)/2+1
+	}
+	return majority(c.members(ps.conf.Voters)) && (ps.conf.Joint == nil || majority(ps.conf.Joint))
+}
+
+// matched es el end confirmado de id (el propio, si es este nodo);
+// requiere ps.mu.
+func (c *Consensus) matched(ps *partState, id string) uint64 {
+	if id == c.self {
+		return ps.end
+	}
+	return ps.match[id]
+}
+
+/*──────────  cambio (líder)  ──────────*/
+
+// reconfigure da el siguiente paso hacia las réplicas del catálogo;
+// requiere ps.mu.
+func (c *Consensus) reconfigure(ps *partState) {
+	if ps.leader != c.self || !ps.placed || ps.commit < ps.conf.End {
+		return // la configuración vigente aún no está comprometida
+	}
+	if ps.conf.Joint != nil {
+		c.appendConfig(ps, partConfig{Voters: ps.conf.Joint})
+		return
+	}
+	cur, want := c.members(ps.conf.Voters), c.members(ps.target)
+	if sameSet(cur, want) {
+		if !slices.Contains(cur, c.self) {
+			c.stepDown(ps, ps.epoch) // ya no es réplica
+		}
+		return
+	}
+	for _, id := range want {
+		if id != c.self && !slices.Contains(cur, id) && ps.match[id] < ps.commit {
+			return // learners que aún se ponen al día
+		}
+	}
+	c.appendConfig(ps, partConfig{Voters: slices.Clone(cur), Joint: slices.Clone(want)})
+}
+
+// appendConfig escribe la configuración y la adopta ya, sin esperar a
+// comprometerla; requiere ps.mu.
+func (c *Consensus) appendConfig(ps *partState, conf partConfig) {
+	off, err := c.appendControl(c.ctx, ps, ControlConfig, conf)
+	if err != nil {
+		log.Printf("[raft] %s:%d registro de configuración: %v", ps.topic, ps.part, err)
+		return
+	}
+	log.Printf("[raft] %s:%d configuración %v → %v (joint %v)",
+		ps.topic, ps.part, ps.conf.Voters, conf.Voters, conf.Joint)
+	c.adopt(ps, conf, off)
+	c.advanceCommit(ps)
+	ps.notify()
+}
+
+/*──────────  cambio (cualquier nodo)  ──────────*/
+
+// adopt fija conf, escrita en off, como la vigente; requiere ps.mu.
+func (c *Consensus) adopt(ps *partState, conf partConfig, off uint64) {
+	if ps.known {
+		prev := ps.conf
+		ps.prev = &prev
+	}
+	conf.End = off + 1
+	ps.conf, ps.known = conf, true
+	c.saveConfig(ps)
+}
+
+// truncated vuelve a la configuración anterior si la vigente se escribió
+// en from o después (el líder no la tiene); requiere ps.mu.
+func (c *Consensus) truncated(ps *partState, from uint64) {
+	if !ps.known || ps.conf.End == 0 || ps.conf.End <= from {
+		return
+	}
+	if ps.prev == nil {
+		ps.conf, ps.known = partConfig{}, false
+	} else {
+		ps.conf, ps.prev = *ps.prev, nil
+	}
+	c.saveConfig(ps)
+}
+
+// configOf devuelve la configuración que lleva el payload de un registro
+// de control; ok = false si no lleva ninguna (versiones anteriores).
+func configOf(payload []byte) (conf partConfig, ok bool) {
+	if len(payload) == 0 || json.Unmarshal(payload, &conf) != nil {
+		return conf, false
+	}
+	conf.End = 0
+	return conf, true
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, id := range a {
+		if !slices.Contains(b, id) {
+			return false
+		}
+	}
+	return true
+}
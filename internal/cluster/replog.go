@@ -0,0 +1,401 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/ports/outbound"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// AppliedStore persiste hasta dónde se aplicó un log replicado y borra
+// el prefijo del log que ya recoge un snapshot.
+type AppliedStore interface {
+	LoadApplied(name string) (next uint64, err error)
+	SaveApplied(name string, next uint64) error
+	// Compact borra las entradas del log name por debajo de before, sin
+	// tocar su HWM.
+	Compact(name string, before uint64) error
+}
+
+// Snapshotter vuelca y sustituye, de una vez, el estado local que
+// construye un log replicado.
+type Snapshotter interface {
+	Snapshot() ([]byte, error)
+	Restore(raw []byte) error
+}
+
+const (
+	applyPage     = 512     // entradas que se leen del log cada vez al aplicar
+	snapshotEvery = 10000   // entradas aplicadas entre dos snapshots
+	snapshotChunk = 1 << 20 // bytes de snapshot por entrada (< MaxBatchBytes)
+
+	// snapshotHeader marca las entradas que llevan un snapshot, en trozos:
+	// "<from>:<i>/<n>" es el trozo i de n del estado con todo lo anterior
+	// a from aplicado.
+	snapshotHeader = "mom-snapshot"
+)
+
+// replicatedLog es una máquina de estados sobre la partición 0 de un
+// tópico interno del consenso: las entradas se proponen en su líder
+// (directamente o reenviadas por un seguidor) y cada nodo las aplica, en
+// orden, sobre su estado local. Es la base de ReplicatedMeta y
+// ReplicatedQueues.
+//
+// Cada snapshotEvery entradas el líder vuelca el estado (snapshot) y lo
+// escribe en el propio log; cuando un nodo aplica el último trozo borra
+// lo anterior a su from. Un seguidor cuyo log empieza por encima de lo
+// que aplicó (el líder ya había compactado) restaura ese snapshot y sigue
+// aplicando desde from.
+type replicatedLog struct {
+	topic   string
+	op      string // ForwardRequest.op con la que se reenvía al líder
+	cons    *Consensus
+	fan     *Fanout
+	store   outbound.MessageStore
+	applied AppliedStore
+	apply   func(ctx context.Context, m model.Message) (any, error)
+
+	// snapshot y restore vuelcan y sustituyen el estado local; con
+	// snapshot nil el log no se compacta.
+	snapshot func() ([]byte, error)
+	restore  func(ctx context.Context, raw []byte) error
+	every    uint64
+
+	mu       sync.Mutex
+	waiters  map[uuid.UUID]chan applyResult // propuestas locales pendientes
+	next     uint64                         // próxima entrada por aplicar
+	progress chan struct{}                  // se cierra (y renueva) al avanzar next
+}
+
+type applyResult struct {
+	val any
+	err error
+}
+
+func newReplicatedLog(topic, op string, cons *Consensus, fan *Fanout, store outbound.MessageStore,
+	applied AppliedStore, apply func(context.Context, model.Message) (any, error)) *replicatedLog {
+
+	return &replicatedLog{topic: topic, op: op, cons: cons, fan: fan, store: store,
+		applied: applied, apply: apply, every: snapshotEvery, waiters: map[uuid.UUID]chan applyResult{},
+		progress: make(chan struct{})}
+}
+
+// propose escribe la entrada id en el log y espera a aplicarla en este
+// nodo: devuelve lo que devolvió apply, idéntico en todos los nodos.
+func (l *replicatedLog) propose(ctx context.Context, id uuid.UUID, payload []byte, user string) (any, error) {
+	done := make(chan applyResult, 1)
+	l.mu.Lock()
+	l.waiters[id] = done
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.waiters, id)
+		l.mu.Unlock()
+	}()
+
+	err := l.submit(ctx, id, payload, user)
+	var nl *NotLeaderError
+	if errors.As(err, &nl) && nl.Leader != "" {
+		err = l.forward(ctx, nl.Leader, id, payload, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// comprometida: falta que este nodo llegue a aplicarla
+	ctx, cancel := context.WithTimeout(ctx, l.cons.opts.CommitTimeout)
+	defer cancel()
+	select {
+	case r := <-done:
+		return r.val, r.err
+	case <-ctx.Done():
+		return nil, errs.Unavailable("%s entry committed but not applied on this node yet", l.topic)
+	}
+}
+
+// submit propone la entrada si este nodo es el líder del log.
+func (l *replicatedLog) submit(ctx context.Context, id uuid.UUID, payload []byte, user string) error {
+	m := model.Message{ID: id, Topic: l.topic, Producer: user, Payload: payload}
+	_, err := l.cons.Propose(ctx, m, model.AcksAll)
+	return err
+}
+
+func (l *replicatedLog) forward(ctx context.Context, leader string, id uuid.UUID, payload []byte, user string) error {
+	cli := l.fan.client(leader)
+	if cli == nil {
+		return errs.Unavailable("%s leader %s is not connected", l.topic, leader)
+	}
+	rep, err := cli.Forward(tracing.OutgoingGRPC(ctx), &pb.ForwardRequest{
+		Op: l.op, Id: id.String(), Payload: payload, User: user,
+	})
+	if err != nil {
+		return errs.Unavailable("forward %s entry to %s: %v", l.topic, leader, err)
+	}
+	return decodeError(rep)
+}
+
+// serveForward atiende la entrada que reenvía un seguidor: sólo se propone
+// aquí (nunca se reenvía otra vez); el seguidor espera su propia
+// aplicación.
+func (l *replicatedLog) serveForward(ctx context.Context, in *pb.ForwardRequest) error {
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return errs.Invalid("invalid entry id %q", in.Id)
+	}
+	return l.submit(ctx, id, in.Payload, in.User)
+}
+
+// caughtUp espera, como mucho ElectionTimeout, a que este nodo aplique
+// todo lo comprometido hasta ahora, y dice si lo consiguió.
+func (l *replicatedLog) caughtUp(ctx context.Context) bool {
+	ps := l.cons.state(l.topic, 0)
+	ps.mu.Lock()
+	commit := ps.commit
+	ps.mu.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, l.cons.opts.ElectionTimeout)
+	defer cancel()
+	for {
+		l.mu.Lock()
+		next, ch := l.next, l.progress
+		l.mu.Unlock()
+		if next >= commit {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ch:
+		}
+	}
+}
+
+// advance publica el progreso del bucle de aplicación.
+func (l *replicatedLog) advance(next uint64) {
+	l.mu.Lock()
+	l.next = next
+	close(l.progress)
+	l.progress = make(chan struct{})
+	l.mu.Unlock()
+}
+
+// isLeader indica si este nodo lidera el log ahora mismo.
+func (l *replicatedLog) isLeader() bool {
+	ps := l.cons.state(l.topic, 0)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.leader == l.cons.self
+}
+
+// Start registra la partición en el consenso y aplica sus entradas
+// comprometidas hasta que se cancele ctx; wg cuenta el bucle.
+func (l *replicatedLog) Start(ctx context.Context, wg *sync.WaitGroup) {
+	ps := l.cons.state(l.topic, 0)
+	next, err := l.applied.LoadApplied(l.topic)
+	if err != nil {
+		log.Printf("[%s] progreso: %v (se reaplica desde 0)", l.topic, err)
+	}
+	l.advance(next)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		last := next // offset del último snapshot (o del arranque)
+		var seen snapshotSeen
+		for {
+			ps.mu.Lock()
+			commit, ch := ps.commit, ps.changed
+			ps.mu.Unlock()
+			wait := func() bool {
+				select {
+				case <-ctx.Done():
+					return false
+				case <-ch:
+					return true
+				}
+			}
+			if next >= commit {
+				if !wait() {
+					return
+				}
+				continue
+			}
+			msgs, err := l.store.Read(ctx, l.topic, 0, next, int(min(commit-next, applyPage)))
+			if err != nil || len(msgs) == 0 {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("[%s] leer desde %d: %v", l.topic, next, err)
+				if !wait() {
+					return
+				}
+				continue
+			}
+			if from := msgs[0].Offset; from > next {
+				// lo que falta ya se compactó: se restaura el snapshot
+				if err := l.install(ctx, from, commit); err != nil {
+					log.Printf("[%s] aplicado hasta %d y el log empieza en %d: %v", l.topic, next, from, err)
+					if !wait() {
+						return
+					}
+					continue
+				}
+				next, last, seen = from, from, snapshotSeen{}
+				l.advance(next)
+				if err := l.applied.SaveApplied(l.topic, next); err != nil {
+					log.Printf("[%s] guardar progreso: %v", l.topic, err)
+				}
+				continue
+			}
+			for _, m := range msgs {
+				if m.Offset >= commit {
+					break
+				}
+				if p, ok := pieceOf(m); ok {
+					// el estado ya incluye todo lo anterior: sólo se compacta
+					if seen.add(p) {
+						last = max(last, p.from)
+						if err := l.applied.Compact(l.topic, p.from); err != nil {
+							log.Printf("[%s] compactar hasta %d: %v", l.topic, p.from, err)
+						}
+					}
+				} else if !m.IsControl() {
+					val, err := l.apply(ctx, m)
+					l.done(m.ID, applyResult{val, err})
+				}
+				next = m.Offset + 1
+			}
+			if err := l.applied.SaveApplied(l.topic, next); err != nil {
+				log.Printf("[%s] guardar progreso: %v", l.topic, err)
+			}
+			l.advance(next)
+			if l.snapshot != nil && next-last >= l.every && l.isLeader() {
+				last = next
+				l.writeSnapshot(ctx, wg, next)
+			}
+		}
+	}()
+}
+
+/*──────────  snapshots  ──────────*/
+
+// snapPiece es la cabecera snapshotHeader de una entrada.
+type snapPiece struct {
+	from uint64
+	i, n int
+}
+
+func pieceOf(m model.Message) (snapPiece, bool) {
+	var p snapPiece
+	h := m.Headers[snapshotHeader]
+	if h == "" {
+		return p, false
+	}
+	if _, err := fmt.Sscanf(h, "%d:%d/%d", &p.from, &p.i, &p.n); err != nil || p.n <= 0 {
+		return p, false
+	}
+	return p, true
+}
+
+// snapshotSeen sigue, en orden de log, los trozos de un snapshot; los
+// trozos de un líder que no llegó a escribirlos todos se descartan al
+// empezar otro.
+type snapshotSeen struct {
+	from uint64
+	want int
+	data [][]byte
+}
+
+// add anota el trozo p, y su payload si se pasa, y dice si completa el
+// snapshot.
+func (s *snapshotSeen) add(p snapPiece, payload ...[]byte) bool {
+	switch {
+	case p.i == 0:
+		*s = snapshotSeen{from: p.from}
+	case p.from != s.from || p.i != s.want:
+		*s = snapshotSeen{}
+		return false
+	}
+	s.want++
+	s.data = append(s.data, payload...)
+	return s.want == p.n
+}
+
+// writeSnapshot vuelca el estado, con todo lo anterior a from aplicado, y
+// lo propone en trozos en segundo plano. Se llama desde el bucle de
+// aplicación: nada cambia el estado mientras se vuelca.
+func (l *replicatedLog) writeSnapshot(ctx context.Context, wg *sync.WaitGroup, from uint64) {
+	raw, err := l.snapshot()
+	if err != nil {
+		log.Printf("[%s] snapshot en %d: %v", l.topic, from, err)
+		return
+	}
+	var chunks [][]byte
+	for len(raw) > snapshotChunk {
+		chunks, raw = append(chunks, raw[:snapshotChunk]), raw[snapshotChunk:]
+	}
+	chunks = append(chunks, raw)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, c := range chunks {
+			m := model.Message{ID: uuid.New(), Topic: l.topic, Payload: c, Headers: map[string]string{
+				snapshotHeader: fmt.Sprintf("%d:%d/%d", from, i, len(chunks)),
+			}}
+			if _, err := l.cons.Propose(ctx, m, model.AcksAll); err != nil {
+				log.Printf("[%s] snapshot en %d, trozo %d/%d: %v", l.topic, from, i, len(chunks), err)
+				return
+			}
+		}
+	}()
+}
+
+// install busca en el log comprometido, desde from, el snapshot que
+// empieza en from y lo restaura.
+func (l *replicatedLog) install(ctx context.Context, from, commit uint64) error {
+	if l.restore == nil {
+		return errors.New("log is not compacted by snapshots")
+	}
+	var seen snapshotSeen
+	for next := from; next < commit; {
+		msgs, err := l.store.Read(ctx, l.topic, 0, next, int(min(commit-next, applyPage)))
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, m := range msgs {
+			if m.Offset >= commit {
+				next = commit
+				break
+			}
+			if p, ok := pieceOf(m); ok && p.from == from && seen.add(p, m.Payload) {
+				log.Printf("[%s] restaurando el snapshot de %d (offset %d)", l.topic, from, m.Offset)
+				return l.restore(ctx, bytes.Join(seen.data, nil))
+			}
+			next = m.Offset + 1
+		}
+	}
+	return fmt.Errorf("no committed snapshot starts at %d yet", from)
+}
+
+// done entrega el resultado a la propuesta local que lo espera, si la hay.
+func (l *replicatedLog) done(id uuid.UUID, r applyResult) {
+	l.mu.Lock()
+	ch := l.waiters[id]
+	l.mu.Unlock()
+	if ch != nil {
+		select {
+		case ch <- r:
+		default: // ya entregado
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package cluster
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"time"
+
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	getRangeMax    = 1000    // mensajes por respuesta de GetRange
+	rangePage      = 512     // mensajes que StreamRange lee del store cada vez
+	rangeChunkMax  = 1 << 20 // bytes por chunk: muy por debajo de los 4 MiB de gRPC
+	catchUpRetries = 3       // reanudaciones de un catch-up cortado
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// chunkCRC es el CRC-32C de offset, uuid y payload de cada mensaje.
+func chunkCRC(batch []*pb.Message) uint32 {
+	var crc uint32
+	var off [8]byte
+	for _, m := range batch {
+		binary.BigEndian.PutUint64(off[:], m.Offset)
+		crc = crc32.Update(crc, castagnoli, off[:])
+		crc = crc32.Update(crc, castagnoli, []byte(m.Uuid))
+		crc = crc32.Update(crc, castagnoli, m.Payload)
+	}
+	return crc
+}
+
+/*──────────  servidor  ──────────*/
+
+// StreamRange envía el rango en chunks de como mucho max_bytes. Lee del
+// store una página cada vez y no lee la siguiente hasta haber enviado la
+// anterior; Send se bloquea cuando se llena la ventana de control de flujo
+// de HTTP/2, así que un receptor lento frena la lectura en vez de
+// acumularla en memoria. Con consenso sólo sirve lo comprometido.
+func (s *replicaSrv) StreamRange(in *pb.StreamRangeRequest, stream pb.Replicator_StreamRangeServer) (err error) {
+	ctx, span := tracing.StartKind(tracing.IncomingGRPC(stream.Context()), "replicaSrv.StreamRange",
+		trace.SpanKindServer, attribute.String("mom.topic", in.Topic), attribute.Int("mom.part", int(in.Part)))
+	defer func() { tracing.End(span, err) }()
+
+	if s.cons != nil {
+		if err := s.cons.CheckReplica(in.Topic, int(in.Part)); err != nil {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		commit := s.cons.Committed(in.Topic, int(in.Part))
+		if in.From >= commit {
+			return stream.Send(&pb.RangeChunk{Next: in.From, Crc32C: chunkCRC(nil), Last: true})
+		}
+		if in.To == 0 || in.To > commit {
+			in.To = commit
+		}
+	}
+
+	limit := int(in.MaxBytes)
+	if limit <= 0 || limit > rangeChunkMax {
+		limit = rangeChunkMax
+	}
+	chunk := &pb.RangeChunk{Next: in.From}
+	size := 0
+	send := func(last bool) error {
+		chunk.Crc32C, chunk.Last = chunkCRC(chunk.Batch), last
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		chunk, size = &pb.RangeChunk{Next: chunk.Next}, 0
+		return nil
+	}
+
+	for {
+		n := uint64(rangePage)
+		if in.To != 0 {
+			if chunk.Next >= in.To {
+				return send(true)
+			}
+			n = min(n, in.To-chunk.Next)
+		}
+		msgs, err := s.store.Read(ctx, in.Topic, int(in.Part), chunk.Next, int(n))
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if in.To != 0 && m.Offset >= in.To {
+				break
+			}
+			pm := ToPB(m)
+			sz := proto.Size(pm)
+			if len(chunk.Batch) > 0 && size+sz > limit {
+				if err := send(false); err != nil {
+					return err
+				}
+			}
+			chunk.Batch = append(chunk.Batch, pm)
+			chunk.Next, size = m.Offset+1, size+sz
+		}
+		if uint64(len(msgs)) < n {
+			return send(true) // no hay más
+		}
+	}
+}
+
+/*──────────  cliente  ──────────*/
+
+// fetchRange entrega a sink, en orden, los mensajes de [from, to) que tiene
+// peerID. Si el stream se corta o un chunk no pasa el checksum, se reanuda
+// desde el último chunk bueno; un error de sink corta sin reintentar.
+func (f *Fanout) fetchRange(ctx context.Context, peerID string, topic string, part int,
+	from, to uint64, sink func(*pb.Message) error) (err error) {
+
+	if f == nil {
+		return nil
+	}
+	ctx, span := tracing.Start(ctx, "Fanout.fetchRange", attribute.String("mom.peer", peerID),
+		attribute.String("mom.topic", topic), attribute.Int("mom.part", part))
+	defer func() { tracing.End(span, err) }()
+
+	next := from
+	for attempt := 0; ; attempt++ {
+		var done bool
+		next, done, err = f.streamRange(ctx, peerID, topic, part, next, to, sink)
+		if done || attempt == catchUpRetries || ctx.Err() != nil {
+			return err
+		}
+		log.Printf("[cluster] catch-up %s:%d desde %s, se reanuda en %d: %v", topic, part, peerID, next, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 200 * time.Millisecond):
+		}
+	}
+}
+
+// streamRange hace una pasada de StreamRange desde from y devuelve hasta
+// dónde llegó; done indica que no hay que reintentar.
+func (f *Fanout) streamRange(ctx context.Context, peerID string, topic string, part int,
+	from, to uint64, sink func(*pb.Message) error) (next uint64, done bool, err error) {
+
+	cli := f.client(peerID)
+	if cli == nil {
+		return from, true, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := cli.StreamRange(tracing.OutgoingGRPC(ctx), &pb.StreamRangeRequest{
+		Topic: topic, Part: uint32(part), From: from, To: to,
+	})
+	if err != nil {
+		return from, false, err
+	}
+
+	next = from
+	for {
+		c, err := stream.Recv()
+		if status.Code(err) == codes.Unimplemented {
+			return from, true, getRange(ctx, cli, topic, part, from, to, sink)
+		}
+		if err == io.EOF {
+			return next, true, nil
+		}
+		if err != nil {
+			return next, false, err
+		}
+		if chunkCRC(c.Batch) != c.Crc32C {
+			return next, false, fmt.Errorf("chunk %s:%d [%d, %d): checksum mismatch", topic, part, next, c.Next)
+		}
+		for _, m := range c.Batch {
+			if err := sink(m); err != nil {
+				return next, true, err
+			}
+		}
+		next = c.Next
+		if c.Last {
+			return next, true, nil
+		}
+	}
+}
+
+// getRange es el catch-up contra un peer sin StreamRange.
+func getRange(ctx context.Context, cli pb.ReplicatorClient, topic string, part int,
+	from, to uint64, sink func(*pb.Message) error) error {
+
+	resp, err := cli.GetRange(tracing.OutgoingGRPC(ctx), &pb.RangeRequest{
+		Topic: topic, Part: uint32(part), From: from, To: to,
+	})
+	if err != nil {
+		return err
+	}
+	for _, m := range resp.Batch {
+		if err := sink(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
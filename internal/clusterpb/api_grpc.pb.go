@@ -20,9 +20,16 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	Replicator_Replicate_FullMethodName = "/cluster.Replicator/Replicate"
-	Replicator_GetRange_FullMethodName  = "/cluster.Replicator/GetRange"
-	Replicator_Ping_FullMethodName      = "/cluster.Replicator/Ping"
+	Replicator_Replicate_FullMethodName   = "/cluster.Replicator/Replicate"
+	Replicator_GetRange_FullMethodName    = "/cluster.Replicator/GetRange"
+	Replicator_StreamRange_FullMethodName = "/cluster.Replicator/StreamRange"
+	Replicator_Ping_FullMethodName        = "/cluster.Replicator/Ping"
+	Replicator_Status_FullMethodName      = "/cluster.Replicator/Status"
+	Replicator_Append_FullMethodName      = "/cluster.Replicator/Append"
+	Replicator_Vote_FullMethodName        = "/cluster.Replicator/Vote"
+	Replicator_Forward_FullMethodName     = "/cluster.Replicator/Forward"
+	Replicator_Digest_FullMethodName      = "/cluster.Replicator/Digest"
+	Replicator_Gossip_FullMethodName      = "/cluster.Replicator/Gossip"
 )
 
 // ReplicatorClient is the client API for Replicator service.
@@ -31,7 +38,14 @@ const (
 type ReplicatorClient interface {
 	Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*ReplicateAck, error)
 	GetRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeBatch, error)
+	StreamRange(ctx context.Context, in *StreamRangeRequest, opts ...grpc.CallOption) (Replicator_StreamRangeClient, error)
 	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Status(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeStatus, error)
+	Append(ctx context.Context, in *AppendRequest, opts ...grpc.CallOption) (*AppendReply, error)
+	Vote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*VoteReply, error)
+	Forward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*ForwardReply, error)
+	Digest(ctx context.Context, in *DigestRequest, opts ...grpc.CallOption) (*DigestReply, error)
+	Gossip(ctx context.Context, in *GossipRequest, opts ...grpc.CallOption) (*GossipReply, error)
 }
 
 type replicatorClient struct {
@@ -62,6 +76,39 @@ func (c *replicatorClient) GetRange(ctx context.Context, in *RangeRequest, opts
 	return out, nil
 }
 
+func (c *replicatorClient) StreamRange(ctx context.Context, in *StreamRangeRequest, opts ...grpc.CallOption) (Replicator_StreamRangeClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replicator_ServiceDesc.Streams[0], Replicator_StreamRange_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &replicatorStreamRangeClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Replicator_StreamRangeClient interface {
+	Recv() (*RangeChunk, error)
+	grpc.ClientStream
+}
+
+type replicatorStreamRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *replicatorStreamRangeClient) Recv() (*RangeChunk, error) {
+	m := new(RangeChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *replicatorClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(emptypb.Empty)
@@ -72,13 +119,80 @@ func (c *replicatorClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...
 	return out, nil
 }
 
+func (c *replicatorClient) Status(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeStatus)
+	err := c.cc.Invoke(ctx, Replicator_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicatorClient) Append(ctx context.Context, in *AppendRequest, opts ...grpc.CallOption) (*AppendReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AppendReply)
+	err := c.cc.Invoke(ctx, Replicator_Append_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicatorClient) Vote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*VoteReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VoteReply)
+	err := c.cc.Invoke(ctx, Replicator_Vote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicatorClient) Forward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*ForwardReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ForwardReply)
+	err := c.cc.Invoke(ctx, Replicator_Forward_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicatorClient) Digest(ctx context.Context, in *DigestRequest, opts ...grpc.CallOption) (*DigestReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DigestReply)
+	err := c.cc.Invoke(ctx, Replicator_Digest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicatorClient) Gossip(ctx context.Context, in *GossipRequest, opts ...grpc.CallOption) (*GossipReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GossipReply)
+	err := c.cc.Invoke(ctx, Replicator_Gossip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ReplicatorServer is the server API for Replicator service.
 // All implementations must embed UnimplementedReplicatorServer
 // for forward compatibility
 type ReplicatorServer interface {
 	Replicate(context.Context, *ReplicateRequest) (*ReplicateAck, error)
 	GetRange(context.Context, *RangeRequest) (*RangeBatch, error)
+	StreamRange(*StreamRangeRequest, Replicator_StreamRangeServer) error
 	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	Status(context.Context, *emptypb.Empty) (*NodeStatus, error)
+	Append(context.Context, *AppendRequest) (*AppendReply, error)
+	Vote(context.Context, *VoteRequest) (*VoteReply, error)
+	Forward(context.Context, *ForwardRequest) (*ForwardReply, error)
+	Digest(context.Context, *DigestRequest) (*DigestReply, error)
+	Gossip(context.Context, *GossipRequest) (*GossipReply, error)
 	mustEmbedUnimplementedReplicatorServer()
 }
 
@@ -92,9 +206,30 @@ func (UnimplementedReplicatorServer) Replicate(context.Context, *ReplicateReques
 func (UnimplementedReplicatorServer) GetRange(context.Context, *RangeRequest) (*RangeBatch, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRange not implemented")
 }
+func (UnimplementedReplicatorServer) StreamRange(*StreamRangeRequest, Replicator_StreamRangeServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRange not implemented")
+}
 func (UnimplementedReplicatorServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedReplicatorServer) Status(context.Context, *emptypb.Empty) (*NodeStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedReplicatorServer) Append(context.Context, *AppendRequest) (*AppendReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Append not implemented")
+}
+func (UnimplementedReplicatorServer) Vote(context.Context, *VoteRequest) (*VoteReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Vote not implemented")
+}
+func (UnimplementedReplicatorServer) Forward(context.Context, *ForwardRequest) (*ForwardReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Forward not implemented")
+}
+func (UnimplementedReplicatorServer) Digest(context.Context, *DigestRequest) (*DigestReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Digest not implemented")
+}
+func (UnimplementedReplicatorServer) Gossip(context.Context, *GossipRequest) (*GossipReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Gossip not implemented")
+}
 func (UnimplementedReplicatorServer) mustEmbedUnimplementedReplicatorServer() {}
 
 // UnsafeReplicatorServer may be embedded to opt out of forward compatibility for this service.
@@ -144,6 +279,27 @@ func _Replicator_GetRange_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Replicator_StreamRange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRangeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplicatorServer).StreamRange(m, &replicatorStreamRangeServer{ServerStream: stream})
+}
+
+type Replicator_StreamRangeServer interface {
+	Send(*RangeChunk) error
+	grpc.ServerStream
+}
+
+type replicatorStreamRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *replicatorStreamRangeServer) Send(m *RangeChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Replicator_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -162,6 +318,114 @@ func _Replicator_Ping_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Replicator_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).Status(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replicator_Append_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).Append(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_Append_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).Append(ctx, req.(*AppendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replicator_Vote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).Vote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_Vote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).Vote(ctx, req.(*VoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replicator_Forward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).Forward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_Forward_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).Forward(ctx, req.(*ForwardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replicator_Digest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DigestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).Digest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_Digest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).Digest(ctx, req.(*DigestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replicator_Gossip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GossipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).Gossip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_Gossip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).Gossip(ctx, req.(*GossipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Replicator_ServiceDesc is the grpc.ServiceDesc for Replicator service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -181,7 +445,37 @@ var Replicator_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Replicator_Ping_Handler,
 		},
+		{
+			MethodName: "Status",
+			Handler:    _Replicator_Status_Handler,
+		},
+		{
+			MethodName: "Append",
+			Handler:    _Replicator_Append_Handler,
+		},
+		{
+			MethodName: "Vote",
+			Handler:    _Replicator_Vote_Handler,
+		},
+		{
+			MethodName: "Forward",
+			Handler:    _Replicator_Forward_Handler,
+		},
+		{
+			MethodName: "Digest",
+			Handler:    _Replicator_Digest_Handler,
+		},
+		{
+			MethodName: "Gossip",
+			Handler:    _Replicator_Gossip_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRange",
+			Handler:       _Replicator_StreamRange_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/cluster/api.proto",
 }
@@ -0,0 +1,392 @@
+// Package config reúne en un solo tipo toda la configuración del broker.
+//
+// Precedencia (de menor a mayor): valores por defecto → archivo YAML/JSON
+// → variables de entorno MOM_* → flags de la línea de comandos.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// Duration acepta "15s", "2m"… en YAML/JSON y se imprime igual.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) { return []byte(time.Duration(d).String()), nil }
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// D devuelve el valor como time.Duration.
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+/*──────────  secciones  ──────────*/
+
+type REST struct {
+	Addr         string   `yaml:"addr"`
+	ReadTimeout  Duration `yaml:"read_timeout"`
+	WriteTimeout Duration `yaml:"write_timeout"`
+}
+
+type GRPC struct {
+	// Addr sobrescribe la dirección de escucha; vacío → host del nodo en
+	// cluster.nodes.
+	Addr string `yaml:"addr"`
+}
+
+type Storage struct {
+	DataDir         string   `yaml:"data_dir"`
+	InFlightTTL     Duration `yaml:"inflight_ttl"`     // tiempo para hacer ack
+	RequeueInterval Duration `yaml:"requeue_interval"` // barrido de mensajes caducados
+}
+
+type Auth struct {
+	Open   bool              `yaml:"open"`   // acepta cualquier token no vacío
+	Tokens map[string]string `yaml:"tokens"` // token → usuario
+	// Admins son los usuarios de Tokens que pueden usar las rutas /admin.
+	Admins []string `yaml:"admins"`
+	// Secret firma los tokens de las cuentas de /admin/users; en un
+	// clúster debe ser el mismo en todos los nodos (vacío = uno al azar).
+	Secret   string   `yaml:"secret"`
+	TokenTTL Duration `yaml:"token_ttl"`
+}
+
+type Retention struct {
+	// Topics activa la retención tópico a tópico: conserva como mucho los
+	// últimos N mensajes por partición. Los tópicos que no figuran se
+	// guardan enteros, y los internos (__*) no se pueden recortar.
+	Topics   map[string]uint64 `yaml:"topics"`
+	Interval Duration          `yaml:"interval"`
+}
+
+type Quota struct {
+	MsgsPerSec  float64 `yaml:"msgs_per_sec"`
+	BytesPerSec float64 `yaml:"bytes_per_sec"`
+	MaxTopics   int     `yaml:"max_topics"`
+	MaxQueues   int     `yaml:"max_queues"`
+	MaxStorage  int64   `yaml:"max_storage_bytes"`
+	// UsageInterval: cada cuánto se mide el uso guardado (bytes, tópicos y
+	// colas) con lo que hay en disco.
+	UsageInterval Duration `yaml:"usage_interval"`
+}
+
+// Model convierte la sección al tipo de dominio.
+func (q Quota) Model() model.Quota {
+	return model.Quota{
+		MsgsPerSec:  q.MsgsPerSec,
+		BytesPerSec: q.BytesPerSec,
+		MaxTopics:   q.MaxTopics,
+		MaxQueues:   q.MaxQueues,
+		MaxStorage:  q.MaxStorage,
+	}
+}
+
+type Lag struct {
+	Interval  Duration `yaml:"interval"`
+	Threshold uint64   `yaml:"threshold"` // 0 = sin alertas
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter"` // otlp | stdout | none
+	Endpoint string `yaml:"endpoint"` // vacío = OTEL_EXPORTER_OTLP_ENDPOINT
+}
+
+type Cluster struct {
+	// File es el cluster.json heredado; sólo se lee si Nodes está vacío.
+	File  string         `yaml:"file"`
+	Nodes []cluster.Node `yaml:"nodes"`
+	// Secret autentica a los nodos entre sí en el puerto gRPC; el mismo en
+	// todos (obligatorio en un clúster).
+	Secret      string `yaml:"secret"`
+	ReadyMaxLag uint64 `yaml:"ready_max_lag"`
+	// WatchInterval relee File para aplicar cambios de membresía en
+	// caliente (0 = no vigilar).
+	WatchInterval Duration `yaml:"watch_interval"`
+	// Quorum: réplicas (contando al líder) que confirman un publish;
+	// 0 = mayoría del clúster.
+	Quorum          int      `yaml:"quorum"`
+	ElectionTimeout Duration `yaml:"election_timeout"`
+	Heartbeat       Duration `yaml:"heartbeat"`
+	CommitTimeout   Duration `yaml:"commit_timeout"`
+	// Forward decide qué hace un nodo que no es dueño de la operación:
+	// proxy (la reenvía por gRPC), redirect (307 al REST del dueño, que
+	// debe figurar en nodes[].rest) u off (503).
+	Forward string `yaml:"forward"`
+	// SuspectAfter y DeadAfter: tiempo sin latidos de un peer (directos o
+	// por gossip) para darlo por sospechoso o por muerto. Los latidos van
+	// cada timeouts.health_probe.
+	SuspectAfter Duration `yaml:"suspect_after"`
+	DeadAfter    Duration `yaml:"dead_after"`
+	// ReplicationFactor: réplicas por partición de los tópicos nuevos
+	// (0 = todos los nodos).
+	ReplicationFactor int `yaml:"replication_factor"`
+	// CatchUpRate: bytes/s hacia una réplica que se pone al día, p. ej.
+	// tras una reasignación (0 = sin límite).
+	CatchUpRate int64 `yaml:"catchup_rate"`
+
+	fromFile bool // Nodes salió de File
+}
+
+// Mirror ajusta la copia de tópicos desde otros clústeres; los mirrors se
+// definen en /admin/mirrors.
+type Mirror struct {
+	Interval     Duration `yaml:"interval"`      // espera cuando el origen no tiene nada nuevo
+	SyncInterval Duration `yaml:"sync_interval"` // revisión de mirrors y del HWM del origen
+	Batch        int      `yaml:"batch"`         // mensajes por lectura del origen
+	Timeout      Duration `yaml:"timeout"`       // plazo de cada petición al origen
+	// Sources son las URLs (esquema y host) de los clústeres de origen
+	// permitidos; vacío, no se pueden crear mirrors.
+	Sources []string `yaml:"sources"`
+}
+
+type Timeouts struct {
+	Shutdown    Duration `yaml:"shutdown"`
+	HealthProbe Duration `yaml:"health_probe"`
+	Reconcile   Duration `yaml:"reconcile"`
+}
+
+// Config es la configuración efectiva del proceso.
+type Config struct {
+	NodeID    string    `yaml:"node_id"`
+	REST      REST      `yaml:"rest"`
+	GRPC      GRPC      `yaml:"grpc"`
+	Storage   Storage   `yaml:"storage"`
+	Auth      Auth      `yaml:"auth"`
+	Retention Retention `yaml:"retention"`
+	Quota     Quota     `yaml:"quota"`
+	Lag       Lag       `yaml:"lag"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Cluster   Cluster   `yaml:"cluster"`
+	Mirror    Mirror    `yaml:"mirror"`
+	Timeouts  Timeouts  `yaml:"timeouts"`
+}
+
+// Default reproduce los valores que tenían los flags de buildServer.
+func Default() *Config {
+	return &Config{
+		REST: REST{Addr: ":8080", ReadTimeout: Duration(30 * time.Second), WriteTimeout: Duration(30 * time.Second)},
+		Storage: Storage{
+			DataDir:         "./data",
+			InFlightTTL:     Duration(30 * time.Second),
+			RequeueInterval: Duration(5 * time.Second),
+		},
+		Auth:      Auth{Open: true, TokenTTL: Duration(12 * time.Hour)},
+		Retention: Retention{Interval: Duration(time.Minute)},
+		Quota:     Quota{UsageInterval: Duration(30 * time.Second)},
+		Lag:       Lag{Interval: Duration(15 * time.Second)},
+		Tracing:   Tracing{Exporter: "none"},
+		Cluster: Cluster{
+			File:              "cluster.json",
+			ReadyMaxLag:       1000,
+			WatchInterval:     Duration(10 * time.Second),
+			ElectionTimeout:   Duration(1500 * time.Millisecond),
+			Heartbeat:         Duration(300 * time.Millisecond),
+			CommitTimeout:     Duration(5 * time.Second),
+			Forward:           "proxy",
+			SuspectAfter:      Duration(15 * time.Second),
+			DeadAfter:         Duration(30 * time.Second),
+			ReplicationFactor: 3,
+			CatchUpRate:       10 << 20,
+		},
+		Mirror: Mirror{
+			Interval:     Duration(time.Second),
+			SyncInterval: Duration(15 * time.Second),
+			Batch:        100,
+			Timeout:      Duration(10 * time.Second),
+		},
+		Timeouts: Timeouts{
+			Shutdown:    Duration(15 * time.Second),
+			HealthProbe: Duration(5 * time.Second),
+			Reconcile:   Duration(30 * time.Second),
+		},
+	}
+}
+
+// ClusterConfig devuelve la membresía en el formato de internal/cluster;
+// nil si el nodo corre en single-node.
+func (c *Config) ClusterConfig() *cluster.Config {
+	if len(c.Cluster.Nodes) == 0 {
+		return nil
+	}
+	return &cluster.Config{Nodes: c.Cluster.Nodes}
+}
+
+// MembershipFile es el archivo que respalda la membresía; "" si los nodos
+// vienen en línea en la configuración (los cambios no se persisten).
+func (c *Config) MembershipFile() string {
+	if c.Cluster.fromFile {
+		return c.Cluster.File
+	}
+	return ""
+}
+
+// ConsensusOptions traduce la sección cluster al formato de internal/cluster.
+func (c *Config) ConsensusOptions() cluster.ConsensusOptions {
+	return cluster.ConsensusOptions{
+		Quorum:          c.Cluster.Quorum,
+		ElectionTimeout: c.Cluster.ElectionTimeout.D(),
+		Heartbeat:       c.Cluster.Heartbeat.D(),
+		CommitTimeout:   c.Cluster.CommitTimeout.D(),
+		CatchUpRate:     c.Cluster.CatchUpRate,
+	}
+}
+
+// GRPCAddr es la dirección donde escucha el Replicator.
+func (c *Config) GRPCAddr() string {
+	if c.GRPC.Addr != "" {
+		return c.GRPC.Addr
+	}
+	if cc := c.ClusterConfig(); cc != nil {
+		if n := cc.Self(c.NodeID); n != nil {
+			return n.Host
+		}
+	}
+	return ""
+}
+
+/*──────────  validación  ──────────*/
+
+// Validate devuelve todos los problemas encontrados a la vez, uno por línea.
+func (c *Config) Validate() error {
+	var bad []error
+	fail := func(field, format string, a ...any) {
+		bad = append(bad, fmt.Errorf("%s: "+format, append([]any{field}, a...)...))
+	}
+	positive := func(field string, d Duration) {
+		if d <= 0 {
+			fail(field, "must be > 0 (got %s)", d.D())
+		}
+	}
+
+	if c.REST.Addr == "" {
+		fail("rest.addr", "required")
+	}
+	if c.REST.ReadTimeout < 0 || c.REST.WriteTimeout < 0 {
+		fail("rest", "timeouts must be >= 0")
+	}
+	if c.Storage.DataDir == "" {
+		fail("storage.data_dir", "required")
+	}
+	positive("storage.inflight_ttl", c.Storage.InFlightTTL)
+	positive("storage.requeue_interval", c.Storage.RequeueInterval)
+	if !c.Auth.Open && len(c.Auth.Tokens) == 0 {
+		fail("auth.tokens", "required when auth.open is false")
+	}
+	positive("auth.token_ttl", c.Auth.TokenTTL)
+	for _, u := range c.Auth.Admins {
+		if !slices.Contains(slices.Collect(maps.Values(c.Auth.Tokens)), u) {
+			fail("auth.admins", "%q has no token in auth.tokens", u)
+		}
+	}
+	if len(c.Retention.Topics) > 0 {
+		positive("retention.interval", c.Retention.Interval)
+	}
+	for _, topic := range slices.Sorted(maps.Keys(c.Retention.Topics)) {
+		if n := c.Retention.Topics[topic]; strings.HasPrefix(topic, model.InternalTopicPrefix) {
+			fail("retention.topics."+topic, "internal topics are never trimmed")
+		} else if n == 0 {
+			fail("retention.topics."+topic, "must keep at least 1 message (omit the topic to keep all)")
+		}
+	}
+	positive("quota.usage_interval", c.Quota.UsageInterval)
+	if c.Quota.MsgsPerSec < 0 || c.Quota.BytesPerSec < 0 ||
+		c.Quota.MaxTopics < 0 || c.Quota.MaxQueues < 0 || c.Quota.MaxStorage < 0 {
+		fail("quota", "values must be >= 0")
+	}
+	positive("lag.interval", c.Lag.Interval)
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		fail("tracing.exporter", "must be otlp, stdout or none (got %q)", c.Tracing.Exporter)
+	}
+	positive("mirror.interval", c.Mirror.Interval)
+	positive("mirror.sync_interval", c.Mirror.SyncInterval)
+	positive("mirror.timeout", c.Mirror.Timeout)
+	for _, s := range c.Mirror.Sources {
+		if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("mirror.sources", "%q is not an http(s) URL", s)
+		}
+	}
+	if c.Mirror.Batch <= 0 {
+		fail("mirror.batch", "must be > 0 (got %d)", c.Mirror.Batch)
+	}
+	positive("timeouts.shutdown", c.Timeouts.Shutdown)
+	positive("timeouts.health_probe", c.Timeouts.HealthProbe)
+	positive("timeouts.reconcile", c.Timeouts.Reconcile)
+	if c.Cluster.WatchInterval < 0 {
+		fail("cluster.watch_interval", "must be >= 0")
+	}
+	positive("cluster.election_timeout", c.Cluster.ElectionTimeout)
+	positive("cluster.heartbeat", c.Cluster.Heartbeat)
+	positive("cluster.commit_timeout", c.Cluster.CommitTimeout)
+	if c.Cluster.Heartbeat >= c.Cluster.ElectionTimeout {
+		fail("cluster.heartbeat", "must be shorter than cluster.election_timeout")
+	}
+	if c.Cluster.SuspectAfter <= c.Timeouts.HealthProbe {
+		fail("cluster.suspect_after", "must be longer than timeouts.health_probe")
+	}
+	if c.Cluster.DeadAfter <= c.Cluster.SuspectAfter {
+		fail("cluster.dead_after", "must be longer than cluster.suspect_after")
+	}
+	if c.Cluster.ReplicationFactor < 0 {
+		fail("cluster.replication_factor", "must be >= 0")
+	}
+	if c.Cluster.CatchUpRate < 0 {
+		fail("cluster.catchup_rate", "must be >= 0")
+	}
+	if cfg := c.ClusterConfig(); cfg != nil && cfg.Self(c.NodeID) != nil && c.Cluster.Secret == "" {
+		fail("cluster.secret", "required when this node is part of a cluster")
+	}
+	switch c.Cluster.Forward {
+	case "proxy", "off":
+	case "redirect":
+		for i, n := range c.Cluster.Nodes {
+			if n.REST == "" {
+				fail(fmt.Sprintf("cluster.nodes[%d].rest", i), "required when cluster.forward is redirect")
+			}
+		}
+	default:
+		fail("cluster.forward", "must be proxy, redirect or off (got %q)", c.Cluster.Forward)
+	}
+	if c.Cluster.Quorum < 0 {
+		fail("cluster.quorum", "must be >= 0 (0 = majority)")
+	} else if n := len(c.Cluster.Nodes); n > 0 && c.Cluster.Quorum > n {
+		fail("cluster.quorum", "%d exceeds the %d nodes of the cluster", c.Cluster.Quorum, n)
+	}
+
+	ids, hosts := map[string]bool{}, map[string]bool{}
+	for i, n := range c.Cluster.Nodes {
+		field := fmt.Sprintf("cluster.nodes[%d]", i)
+		switch {
+		case n.ID == "":
+			fail(field+".id", "required")
+		case ids[n.ID]:
+			fail(field+".id", "duplicated %q", n.ID)
+		}
+		switch {
+		case n.Host == "":
+			fail(field+".host", "required")
+		case hosts[n.Host]:
+			fail(field+".host", "duplicated %q", n.Host)
+		}
+		ids[n.ID], hosts[n.Host] = true, true
+	}
+	if c.NodeID != "" && !ids[c.NodeID] {
+		fail("node_id", "%q is not listed in cluster.nodes", c.NodeID)
+	}
+	return errors.Join(bad...)
+}
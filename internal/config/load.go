@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	"gopkg.in/yaml.v3"
+)
+
+// binding une un campo con su flag y su variable de entorno.
+type binding struct {
+	flag, env, usage string
+	field            func(*Config) any // puntero al campo
+}
+
+var bindings = []binding{
+	{"node-id", "MOM_NODE_ID", "ID de este nodo en cluster.nodes", func(c *Config) any { return &c.NodeID }},
+	{"http", "MOM_HTTP_ADDR", "REST bind", func(c *Config) any { return &c.REST.Addr }},
+	{"http-read-timeout", "MOM_HTTP_READ_TIMEOUT", "plazo para leer una petición REST", func(c *Config) any { return &c.REST.ReadTimeout }},
+	{"http-write-timeout", "MOM_HTTP_WRITE_TIMEOUT", "plazo para escribir una respuesta REST", func(c *Config) any { return &c.REST.WriteTimeout }},
+	{"grpc", "MOM_GRPC_ADDR", "gRPC bind (vacío = host del nodo)", func(c *Config) any { return &c.GRPC.Addr }},
+	{"data", "MOM_DATA_DIR", "Badger dir", func(c *Config) any { return &c.Storage.DataDir }},
+	{"inflight-ttl", "MOM_INFLIGHT_TTL", "tiempo para hacer ack antes de reencolar", func(c *Config) any { return &c.Storage.InFlightTTL }},
+	{"requeue-interval", "MOM_REQUEUE_INTERVAL", "cada cuánto se reencolan mensajes caducados", func(c *Config) any { return &c.Storage.RequeueInterval }},
+	{"auth-open", "MOM_AUTH_OPEN", "acepta cualquier token no vacío", func(c *Config) any { return &c.Auth.Open }},
+	{"auth-secret", "MOM_AUTH_SECRET", "clave que firma los tokens de login (igual en todo el clúster)", func(c *Config) any { return &c.Auth.Secret }},
+	{"retention-interval", "MOM_RETENTION_INTERVAL", "cada cuánto se aplica la retención", func(c *Config) any { return &c.Retention.Interval }},
+	{"quota-msgs", "MOM_QUOTA_MSGS", "msgs/s por usuario (0 = sin límite)", func(c *Config) any { return &c.Quota.MsgsPerSec }},
+	{"quota-bytes", "MOM_QUOTA_BYTES", "bytes/s por usuario (0 = sin límite)", func(c *Config) any { return &c.Quota.BytesPerSec }},
+	{"quota-topics", "MOM_QUOTA_TOPICS", "máx. tópicos por usuario", func(c *Config) any { return &c.Quota.MaxTopics }},
+	{"quota-queues", "MOM_QUOTA_QUEUES", "máx. colas por usuario", func(c *Config) any { return &c.Quota.MaxQueues }},
+	{"quota-storage", "MOM_QUOTA_STORAGE", "máx. bytes almacenados por usuario", func(c *Config) any { return &c.Quota.MaxStorage }},
+	{"lag-interval", "MOM_LAG_INTERVAL", "cada cuánto se calcula el lag", func(c *Config) any { return &c.Lag.Interval }},
+	{"lag-threshold", "MOM_LAG_THRESHOLD", "lag que dispara alerta (0 = sin alertas)", func(c *Config) any { return &c.Lag.Threshold }},
+	{"trace-exporter", "MOM_TRACE_EXPORTER", "exportador de trazas: otlp | stdout | none", func(c *Config) any { return &c.Tracing.Exporter }},
+	{"otlp-endpoint", "MOM_OTLP_ENDPOINT", "host:port del colector OTLP/gRPC (vacío = OTEL_EXPORTER_OTLP_ENDPOINT)", func(c *Config) any { return &c.Tracing.Endpoint }},
+	{"cluster-secret", "MOM_CLUSTER_SECRET", "secreto compartido con el que se autentican los nodos (gRPC)", func(c *Config) any { return &c.Cluster.Secret }},
+	{"cluster", "MOM_CLUSTER_FILE", "cluster config (si no hay cluster.nodes)", func(c *Config) any { return &c.Cluster.File }},
+	{"cluster-watch", "MOM_CLUSTER_WATCH", "cada cuánto se relee el archivo de membresía (0 = nunca)", func(c *Config) any { return &c.Cluster.WatchInterval }},
+	{"quorum", "MOM_QUORUM", "réplicas que confirman un publish (0 = mayoría)", func(c *Config) any { return &c.Cluster.Quorum }},
+	{"election-timeout", "MOM_ELECTION_TIMEOUT", "sin latidos del líder durante este plazo → elección", func(c *Config) any { return &c.Cluster.ElectionTimeout }},
+	{"heartbeat", "MOM_HEARTBEAT", "intervalo de latidos del líder de partición", func(c *Config) any { return &c.Cluster.Heartbeat }},
+	{"commit-timeout", "MOM_COMMIT_TIMEOUT", "espera máxima del quórum al publicar", func(c *Config) any { return &c.Cluster.CommitTimeout }},
+	{"suspect-after", "MOM_SUSPECT_AFTER", "sin latidos de un peer durante este plazo → sospechoso", func(c *Config) any { return &c.Cluster.SuspectAfter }},
+	{"dead-after", "MOM_DEAD_AFTER", "sin latidos de un peer durante este plazo → muerto", func(c *Config) any { return &c.Cluster.DeadAfter }},
+	{"replication-factor", "MOM_REPLICATION_FACTOR", "réplicas por partición de los tópicos nuevos (0 = todos los nodos)", func(c *Config) any { return &c.Cluster.ReplicationFactor }},
+	{"catchup-rate", "MOM_CATCHUP_RATE", "bytes/s hacia una réplica que se pone al día (0 = sin límite)", func(c *Config) any { return &c.Cluster.CatchUpRate }},
+	{"forward", "MOM_FORWARD", "operaciones de otro nodo: proxy | redirect | off", func(c *Config) any { return &c.Cluster.Forward }},
+	{"ready-max-lag", "MOM_READY_MAX_LAG", "lag de réplica máximo para /readyz", func(c *Config) any { return &c.Cluster.ReadyMaxLag }},
+	{"mirror-interval", "MOM_MIRROR_INTERVAL", "espera del mirror cuando el origen no tiene nada nuevo", func(c *Config) any { return &c.Mirror.Interval }},
+	{"mirror-batch", "MOM_MIRROR_BATCH", "mensajes por lectura del clúster de origen", func(c *Config) any { return &c.Mirror.Batch }},
+	{"shutdown-timeout", "MOM_SHUTDOWN_TIMEOUT", "plazo para drenar peticiones al apagar", func(c *Config) any { return &c.Timeouts.Shutdown }},
+	{"health-interval", "MOM_HEALTH_INTERVAL", "cada cuánto se sondean los peers", func(c *Config) any { return &c.Timeouts.HealthProbe }},
+	{"reconcile-interval", "MOM_RECONCILE_INTERVAL", "cada cuánto corre la anti-entropía entre réplicas", func(c *Config) any { return &c.Timeouts.Reconcile }},
+}
+
+// Load construye la configuración efectiva a partir de args (sin el nombre
+// del programa). El archivo se indica con -config o MOM_CONFIG.
+func Load(name string, args []string) (*Config, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("MOM_CONFIG"), "archivo YAML/JSON de configuración (env MOM_CONFIG)")
+
+	// los flags se validan al parsear pero se aplican al final
+	scratch, flags := Default(), map[string]string{}
+	for _, b := range bindings {
+		b := b
+		usage := fmt.Sprintf("%s (env %s, por defecto %s)", b.usage, b.env, show(b.field(Default())))
+		parse := func(v string) error {
+			if err := assign(b.field(scratch), v); err != nil {
+				return err
+			}
+			flags[b.flag] = v
+			return nil
+		}
+		if _, ok := b.field(scratch).(*bool); ok {
+			fs.BoolFunc(b.flag, usage, parse)
+		} else {
+			fs.Func(b.flag, usage, parse)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *file != "" {
+		if err := c.readFile(*file); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyEnv(); err != nil {
+		return nil, err
+	}
+	for _, b := range bindings {
+		if v, ok := flags[b.flag]; ok {
+			_ = assign(b.field(c), v) // ya validado en el parseo
+		}
+	}
+	if err := c.loadMembership(); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return c, nil
+}
+
+// readFile mezcla el archivo sobre los valores actuales; las claves
+// desconocidas son un error para detectar erratas.
+func (c *Config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("NODE_ID"); v != "" { // nombre heredado
+		c.NodeID = v
+	}
+	for _, b := range bindings {
+		v, ok := os.LookupEnv(b.env)
+		if !ok {
+			continue
+		}
+		if err := assign(b.field(c), v); err != nil {
+			return fmt.Errorf("env %s: %w", b.env, err)
+		}
+	}
+	return nil
+}
+
+// loadMembership lee cluster.file si la membresía no viene en línea. Sólo
+// se tolera que falte el cluster.json por defecto (→ single-node).
+func (c *Config) loadMembership() error {
+	if len(c.Cluster.Nodes) > 0 || c.Cluster.File == "" {
+		return nil
+	}
+	cc, err := cluster.Load(c.Cluster.File)
+	if errors.Is(err, os.ErrNotExist) && c.Cluster.File == Default().Cluster.File {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cluster.file %s: %w", c.Cluster.File, err)
+	}
+	c.Cluster.Nodes, c.Cluster.fromFile = cc.Nodes, true
+	return nil
+}
+
+// Print escribe la configuración efectiva en YAML, sin los tokens ni el
+// secreto.
+func (c *Config) Print(w io.Writer) error {
+	cp := *c
+	if cp.Auth.Secret != "" {
+		cp.Auth.Secret = "<redacted>"
+	}
+	if cp.Cluster.Secret != "" {
+		cp.Cluster.Secret = "<redacted>"
+	}
+	cp.Auth.Tokens = map[string]string{}
+	users := make([]string, 0, len(c.Auth.Tokens))
+	for _, u := range c.Auth.Tokens {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	for i, u := range users {
+		cp.Auth.Tokens[fmt.Sprintf("<redacted-%d>", i+1)] = u
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&cp); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+/*──────────  conversión de strings  ──────────*/
+
+func assign(p any, v string) error {
+	var err error
+	switch p := p.(type) {
+	case *string:
+		*p = v
+	case *bool:
+		*p, err = strconv.ParseBool(v)
+	case *int:
+		*p, err = strconv.Atoi(v)
+	case *int64:
+		*p, err = strconv.ParseInt(v, 10, 64)
+	case *uint64:
+		*p, err = strconv.ParseUint(v, 10, 64)
+	case *float64:
+		*p, err = strconv.ParseFloat(v, 64)
+	case *Duration:
+		err = p.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("unsupported type %T", p)
+	}
+	return err
+}
+
+func show(p any) string {
+	if d, ok := p.(*Duration); ok {
+		return d.D().String()
+	}
+	v := reflect.ValueOf(p).Elem().Interface()
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,60 @@
+// Package errs define los errores de dominio compartidos por adapters,
+// casos de uso y la capa REST. Cada error concreto envuelve uno de los
+// centinelas (Err*), de modo que errors.Is(err, errs.ErrNotFound) sirve
+// para decidir la respuesta sin depender del adapter que lo generó.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrAlreadyExists   = errors.New("already exists")
+	ErrForbidden       = errors.New("forbidden")
+	ErrOutOfRange      = errors.New("out of range")
+	ErrConflict        = errors.New("conflict")
+	ErrUnavailable     = errors.New("unavailable")
+	ErrInvalid         = errors.New("invalid argument")
+	ErrUnauthenticated = errors.New("unauthenticated")
+)
+
+var kinds = []error{
+	ErrNotFound, ErrAlreadyExists, ErrForbidden, ErrOutOfRange,
+	ErrConflict, ErrUnavailable, ErrInvalid, ErrUnauthenticated,
+}
+
+// New crea un error de tipo kind con mensaje "<detalle>: <kind>".
+func New(kind error, format string, a ...any) error {
+	return fmt.Errorf(format+": %w", append(a, kind)...)
+}
+
+func NotFound(format string, a ...any) error      { return New(ErrNotFound, format, a...) }
+func AlreadyExists(format string, a ...any) error { return New(ErrAlreadyExists, format, a...) }
+func Forbidden(format string, a ...any) error     { return New(ErrForbidden, format, a...) }
+func OutOfRange(format string, a ...any) error    { return New(ErrOutOfRange, format, a...) }
+func Conflict(format string, a ...any) error      { return New(ErrConflict, format, a...) }
+func Unavailable(format string, a ...any) error   { return New(ErrUnavailable, format, a...) }
+func Invalid(format string, a ...any) error       { return New(ErrInvalid, format, a...) }
+
+// Kind devuelve el centinela que envuelve err, o nil si no es de dominio.
+func Kind(err error) error {
+	for _, k := range kinds {
+		if errors.Is(err, k) {
+			return k
+		}
+	}
+	return nil
+}
+
+// ByName devuelve el centinela cuyo texto es name (el que viaja entre
+// nodos), o nil si no hay ninguno.
+func ByName(name string) error {
+	for _, k := range kinds {
+		if k.Error() == name {
+			return k
+		}
+	}
+	return nil
+}
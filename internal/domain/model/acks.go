@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Acks es la confirmación que espera el productor antes de recibir
+// respuesta.
+type Acks int
+
+const (
+	AcksNone   Acks = 0  // se responde sin esperar la escritura
+	AcksLeader Acks = 1  // basta con la escritura en el líder
+	AcksAll    Acks = -1 // además, el quórum de réplicas configurado
+)
+
+// ParseAcks acepta 0|none, 1|leader y all|-1; "" equivale a all.
+func ParseAcks(s string) (Acks, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "all", "-1":
+		return AcksAll, nil
+	case "1", "leader":
+		return AcksLeader, nil
+	case "0", "none":
+		return AcksNone, nil
+	}
+	return 0, fmt.Errorf("acks must be 0, 1 or all (got %q)", s)
+}
+
+func (a Acks) String() string {
+	switch a {
+	case AcksNone:
+		return "0"
+	case AcksLeader:
+		return "1"
+	}
+	return "all"
+}
+
+// ReplicationError se devuelve con acks=all cuando el mensaje quedó
+// escrito en el líder pero no lo confirmaron suficientes réplicas a
+// tiempo. Puede comprometerse más tarde: reintentar puede duplicarlo.
+type ReplicationError struct {
+	Topic    string
+	Part     int
+	Offset   uint64
+	Acked    int // réplicas (con el líder) que lo tienen
+	Required int
+	Timeout  time.Duration
+}
+
+func (e *ReplicationError) Error() string {
+	return fmt.Sprintf("offset %d of %s:%d acknowledged by %d/%d replicas within %s",
+		e.Offset, e.Topic, e.Part, e.Acked, e.Required, e.Timeout)
+}
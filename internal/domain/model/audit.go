@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// AuditEvent registra una operación administrativa o de autenticación.
+type AuditEvent struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`    // usuario ("" si no se autenticó)
+	Action   string    `json:"action"`   // ej.: topic.create, auth.login
+	Resource string    `json:"resource"` // ej.: topic:pagos, queue:jobs
+	Result   string    `json:"result"`   // success | failure
+	Status   int       `json:"status"`   // código HTTP devuelto
+	SourceIP string    `json:"source_ip"`
+}
+
+// AuditFilter selecciona eventos; los campos vacíos no filtran.
+type AuditFilter struct {
+	Since    time.Time
+	Until    time.Time
+	Actor    string
+	Resource string
+	Limit    int
+}
+
+// Match indica si el evento cumple el filtro.
+func (f AuditFilter) Match(ev AuditEvent) bool {
+	if !f.Since.IsZero() && ev.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && ev.Time.After(f.Until) {
+		return false
+	}
+	if f.Actor != "" && ev.Actor != f.Actor {
+		return false
+	}
+	if f.Resource != "" && ev.Resource != f.Resource {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// BackupManifest describe un backup del almacenamiento de un nodo: guarda
+// lo escrito entre las versiones Since y Version de Badger (Since = 0 →
+// completo). El siguiente incremental empieza en Version+1.
+type BackupManifest struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Since     uint64    `json:"since"`
+	Version   uint64    `json:"version"`
+	Bytes     int64     `json:"bytes"`  // tamaño del volcado de Badger
+	SHA256    string    `json:"sha256"` // del volcado de Badger
+}
+
+// Incremental indica si el backup depende de otros anteriores.
+func (m BackupManifest) Incremental() bool { return m.Since > 0 }
+
+// Next es el since del siguiente backup incremental.
+func (m BackupManifest) Next() uint64 { return m.Version + 1 }
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// PartitionStats resume el contenido de una partición.
+// EndOffset es el próximo offset a asignar (HWM).
+type PartitionStats struct {
+	Partition   int    `json:"partition"`
+	StartOffset uint64 `json:"start_offset"`
+	EndOffset   uint64 `json:"end_offset"`
+	Messages    int64  `json:"messages"`
+	Bytes       int64  `json:"bytes"`
+}
+
+type TopicDescription struct {
+	Topic
+	PartitionStats []PartitionStats `json:"partition_stats"`
+}
+
+// QueueStats: Depth son los mensajes pendientes, InFlight los entregados
+// sin ack todavía.
+type QueueStats struct {
+	Depth    int   `json:"depth"`
+	InFlight int   `json:"in_flight"`
+	Bytes    int64 `json:"bytes"`
+}
+
+type QueueDescription struct {
+	Queue
+	QueueStats
+}
+
+// GroupOffset es el offset confirmado por un grupo en una partición.
+type GroupOffset struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Committed uint64 `json:"committed"`
+	EndOffset uint64 `json:"end_offset"`
+	Lag       uint64 `json:"lag"`
+}
+
+// ConsumerLag es el lag de un grupo en una partición concreta.
+type ConsumerLag struct {
+	Group string `json:"group"`
+	GroupOffset
+}
+
+// LagAlert se publica en LagAlertTopic cuando el lag supera el umbral.
+type LagAlert struct {
+	ConsumerLag
+	Threshold uint64    `json:"threshold"`
+	Time      time.Time `json:"time"`
+}
+
+type GroupDescription struct {
+	Group   string        `json:"group"`
+	Offsets []GroupOffset `json:"offsets"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// ExportQuery elige los mensajes de un tópico que se exportan.
+type ExportQuery struct {
+	Topic     string
+	Partition int    // -1 → todas
+	From      uint64 // primer offset
+	To        uint64 // offset final, excluido (0 → hasta el final)
+	Key       string // "" → cualquier clave
+	// Since y Until acotan por Message.Time (cero → sin límite); con
+	// alguno de los dos los mensajes sin TimeHeader no pasan Match.
+	Since, Until time.Time
+}
+
+// Timed indica si q filtra por tiempo.
+func (q ExportQuery) Timed() bool { return !q.Since.IsZero() || !q.Until.IsZero() }
+
+// Match indica si m pasa los filtros de clave y tiempo.
+func (q ExportQuery) Match(m Message) bool {
+	if q.Key != "" && m.Key != q.Key {
+		return false
+	}
+	if !q.Timed() {
+		return true
+	}
+	t := m.Time()
+	if t.IsZero() {
+		return false
+	}
+	return (q.Since.IsZero() || !t.Before(q.Since)) && (q.Until.IsZero() || t.Before(q.Until))
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Readiness es la respuesta de /readyz: cada check vale "ok" o el motivo
+// del fallo.
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// PeerStatus es lo último que se sabe de un peer gracias al sondeo.
+type PeerStatus struct {
+	ID        string            `json:"id"`
+	Host      string            `json:"host"`
+	Alive     bool              `json:"alive"`
+	State     MemberState       `json:"state"`
+	Since     *time.Time        `json:"state_since,omitempty"` // desde cuándo está en State
+	LastPing  *time.Time        `json:"last_ping,omitempty"`   // último sondeo correcto
+	LastError string            `json:"last_error,omitempty"`
+	Lag       map[string]uint64 `json:"lag"` // topic:part → mensajes que nos faltan respecto a él
+}
+
+// ClusterStatus resume el clúster desde el punto de vista de este nodo.
+type ClusterStatus struct {
+	Self   string       `json:"self"`
+	Leader string       `json:"leader"`
+	Peers  []PeerStatus `json:"peers"`
+	// PartitionLeaders: "topic:part" → líder del log replicado.
+	PartitionLeaders map[string]string `json:"partition_leaders,omitempty"`
+	// Conflicts: últimos rangos que la anti-entropía encontró distintos.
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+	// Events: últimos cambios de estado de los nodos.
+	Events []MemberEvent `json:"events,omitempty"`
+}
+
+// MemberState es el estado de un nodo según el detector de fallos.
+type MemberState string
+
+const (
+	MemberAlive   MemberState = "alive"   // latidos recientes
+	MemberSuspect MemberState = "suspect" // sin latidos un rato; sigue contando
+	MemberDead    MemberState = "dead"    // fuera de la elección de líder y del fan-out
+)
+
+// MemberEvent es un cambio de estado de un nodo.
+type MemberEvent struct {
+	Node string      `json:"node"`
+	From MemberState `json:"from"`
+	To   MemberState `json:"to"`
+	At   time.Time   `json:"at"`
+}
+
+// Conflict es un rango de offsets en el que este nodo no coincidía con la
+// réplica de referencia (mensajes distintos o huecos) y que se volvió a
+// copiar de ella.
+type Conflict struct {
+	Topic    string    `json:"topic"`
+	Part     int       `json:"partition"`
+	From     uint64    `json:"from"`
+	To       uint64    `json:"to"` // exclusivo
+	Peer     string    `json:"peer"`
+	Repaired bool      `json:"repaired"`
+	Error    string    `json:"error,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Node es un miembro del clúster tal como se gestiona por la API admin.
+type Node struct {
+	ID   string `json:"id"`
+	Host string `json:"host"`           // host:port gRPC
+	REST string `json:"rest,omitempty"` // host:port REST (redirecciones)
+}
+
+// RedirectError indica que la operación la atiende otro nodo; la capa REST
+// responde 307 hacia Addr (host:port REST del dueño).
+type RedirectError struct {
+	Leader string
+	Addr   string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("operation is served by node %s (%s)", e.Leader, e.Addr)
+}
@@ -1,6 +1,19 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ControlHeader marca registros internos del log (p. ej. el que escribe
+// un líder nuevo); los consumidores no los reciben.
+const ControlHeader = "mom-control"
+
+// TimeHeader guarda cuándo se publicó el mensaje (ms Unix); viaja en las
+// cabeceras para que las réplicas y los mirrors conserven el mismo.
+const TimeHeader = "mom-time"
 
 type Message struct {
 	ID       uuid.UUID
@@ -10,4 +23,19 @@ type Message struct {
 	Topic    string
 	PartID   int
 	Producer string
+	Headers  map[string]string // metadatos (p. ej. traceparent W3C)
+	Epoch    uint64            // término del líder que asignó el offset
+}
+
+// IsControl indica si es un registro interno del log.
+func (m Message) IsControl() bool { return m.Headers[ControlHeader] != "" }
+
+// Time es el instante de publicación; cero en mensajes anteriores a
+// TimeHeader.
+func (m Message) Time() time.Time {
+	ms, err := strconv.ParseInt(m.Headers[TimeHeader], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
 }
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Cabeceras que el mirror añade a cada mensaje copiado: de qué mirror y
+// tópico de origen viene y qué offset tenía allí. Con ellas se traducen
+// offsets y se evita duplicar tras un cambio de líder.
+const (
+	MirrorSourceHeader = "mom-mirror-source" // <mirror>/<tópico de origen>
+	MirrorOffsetHeader = "mom-mirror-offset"
+)
+
+// Mirror copia tópicos de otro clúster del broker (Source, su API REST) a
+// este. Cada tópico se escribe en Target(topic): Rename manda y, si no lo
+// nombra, se antepone Prefix.
+type Mirror struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`          // URL REST del clúster de origen
+	Token  string `json:"token,omitempty"` // X-Token en el origen
+	// Group es el grupo de consumo en el origen; "" → "mirror-<name>".
+	Group     string            `json:"group,omitempty"`
+	Topics    []string          `json:"topics"`
+	Prefix    string            `json:"prefix,omitempty"`
+	Rename    map[string]string `json:"rename,omitempty"`
+	Paused    bool              `json:"paused,omitempty"`
+	Creator   string            `json:"creator"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Target es el tópico local donde se copia topic.
+func (m Mirror) Target(topic string) string {
+	if t := m.Rename[topic]; t != "" {
+		return t
+	}
+	return m.Prefix + topic
+}
+
+// SourceGroup es el grupo con el que se lee del origen.
+func (m Mirror) SourceGroup() string {
+	if m.Group != "" {
+		return m.Group
+	}
+	return "mirror-" + m.Name
+}
+
+// Redacted oculta el token para devolver el mirror por la API.
+func (m Mirror) Redacted() Mirror {
+	if m.Token != "" {
+		m.Token = strings.Repeat("*", 8)
+	}
+	return m
+}
+
+// MirrorCheckpoint es el próximo offset de origen por copiar de una
+// partición.
+type MirrorCheckpoint struct {
+	Topic     string `json:"topic"` // tópico de origen
+	Partition int    `json:"partition"`
+	Next      uint64 `json:"next"`
+}
+
+// MirrorPartition es el estado de la copia de una partición.
+type MirrorPartition struct {
+	Topic      string `json:"topic"`
+	Target     string `json:"target"`
+	Partition  int    `json:"partition"`
+	Checkpoint uint64 `json:"checkpoint"` // próximo offset de origen por copiar
+	SourceEnd  uint64 `json:"source_end"` // HWM de la partición en el origen
+	Lag        uint64 `json:"lag"`
+	// Active: la copia este nodo (el líder local de la partición destino).
+	Active bool   `json:"active"`
+	Error  string `json:"error,omitempty"`
+}
+
+type MirrorStatus struct {
+	Mirror
+	Partitions []MirrorPartition `json:"partitions"`
+}
+
+// OffsetTranslation traduce un offset del origen al del tópico local:
+// Offset es el primer mensaje local copiado de SourceOffset o de uno
+// posterior (el que debe leer un grupo que migra de clúster).
+type OffsetTranslation struct {
+	Mirror       string `json:"mirror"`
+	Topic        string `json:"topic"`
+	Target       string `json:"target"`
+	Partition    int    `json:"partition"`
+	SourceOffset uint64 `json:"source_offset"`
+	Offset       uint64 `json:"offset"`
+}
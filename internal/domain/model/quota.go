@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ámbitos a los que se puede asignar una cuota.
+const (
+	ScopeUser  = "user"
+	ScopeTopic = "topic"
+	ScopeQueue = "queue"
+)
+
+// Quota agrupa los límites configurables de un usuario, tópico o cola.
+// Un valor 0 significa "sin límite".
+type Quota struct {
+	MsgsPerSec  float64 `json:"msgs_per_sec"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	MaxTopics   int     `json:"max_topics"`
+	MaxQueues   int     `json:"max_queues"`
+	MaxStorage  int64   `json:"max_storage_bytes"`
+}
+
+// QuotaSetting es una cuota asignada expresamente a un usuario, tópico o
+// cola; se guarda en el catálogo.
+type QuotaSetting struct {
+	Scope string `json:"scope"`
+	Name  string `json:"name"`
+	Quota Quota  `json:"quota"`
+}
+
+// QuotaRef identifica la cuota de un usuario, tópico o cola.
+type QuotaRef struct {
+	Scope string
+	Name  string
+}
+
+// QuotaUsage es el consumo actual asociado a una cuota.
+type QuotaUsage struct {
+	Topics      int   `json:"topics"`
+	Queues      int   `json:"queues"`
+	StoredBytes int64 `json:"stored_bytes"`
+}
+
+// QuotaError se devuelve cuando una operación supera su cuota.
+// RetryAfter es 0 si esperar no sirve (p. ej. límite de almacenamiento).
+type QuotaError struct {
+	Scope      string
+	Name       string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s %q: %s", e.Scope, e.Name, e.Reason)
+}
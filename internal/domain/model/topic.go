@@ -1,11 +1,53 @@
 package model
 
+import "time"
+
+// Tópicos internos del broker (no se pueden crear desde la API).
+const (
+	InternalTopicPrefix = "__"
+	AuditTopic          = "__audit"
+	LagAlertTopic       = "__lag_alerts"
+	// MetaTopic es el log replicado del catálogo (partición 0); la
+	// retención no lo recorta.
+	MetaTopic = "__meta"
+	// QueueTopic es el log replicado de las colas (partición 0): altas,
+	// entregas, acks y reencolados. Tampoco se recorta.
+	QueueTopic = "__queues"
+)
+
+// NodeLocal indica los tópicos internos que cada nodo escribe sólo en su
+// log (auditoría y alertas de lag): no se replican, así que su diferencia
+// entre nodos no es retraso de réplica.
+func NodeLocal(topic string) bool {
+	return topic == AuditTopic || topic == LagAlertTopic
+}
+
 type Topic struct {
-	Name       string
-	Partitions int
-	Creator    string
+	Name       string    `json:"name"`
+	Partitions int       `json:"partitions"`
+	Creator    string    `json:"creator"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Replicas: nodos que guardan cada partición; el primero es el líder
+	// preferido. Vacío → todos los nodos del clúster (single-node o
+	// tópicos anteriores a la asignación).
+	Replicas [][]string `json:"replicas,omitempty"`
+}
+
+// ReplicationFactor es el nº de réplicas por partición (0 → todos los
+// nodos).
+func (t Topic) ReplicationFactor() int {
+	if len(t.Replicas) == 0 {
+		return 0
+	}
+	return len(t.Replicas[0])
 }
 
 func (t Topic) IsValid() bool {
 	return t.Name != "" && t.Partitions > 0
 }
+
+type Queue struct {
+	Name      string    `json:"name"`
+	Creator   string    `json:"creator"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,7 +1,61 @@
 package model
 
+import (
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// User es una cuenta gestionada con /admin/users. Hash es la contraseña
+// derivada (nunca la contraseña); Redacted lo quita antes de responder.
 type User struct {
-	Username string
-	Password string
-	Token    string
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin"`
+	Hash      string    `json:"hash,omitempty"`
+	Creator   string    `json:"creator"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Redacted devuelve u sin el hash de la contraseña.
+func (u User) Redacted() User {
+	u.Hash = ""
+	return u
+}
+
+// Operaciones que controla una ACL.
+const (
+	OpRead   = "read"   // consumir, leer offsets, describir
+	OpWrite  = "write"  // publicar, encolar
+	OpManage = "manage" // crear y borrar
+)
+
+// ACL concede a Principal (un usuario o "*") las operaciones Ops sobre los
+// recursos Resource: "topic:<patrón>" o "queue:<patrón>", con los comodines
+// de path.Match ("topic:logs-*").
+//
+// Un recurso que no cubre ninguna ACL queda abierto a todos; en cuanto una
+// lo cubre, sólo pueden usarlo quienes tengan la operación concedida.
+type ACL struct {
+	ID        string    `json:"id"`
+	Principal string    `json:"principal"`
+	Resource  string    `json:"resource"`
+	Ops       []string  `json:"ops"`
+	Creator   string    `json:"creator"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Covers indica si la ACL habla del recurso kind:name.
+func (a ACL) Covers(kind, name string) bool {
+	k, pattern, ok := strings.Cut(a.Resource, ":")
+	if !ok || k != kind {
+		return false
+	}
+	match, _ := path.Match(pattern, name)
+	return match
+}
+
+// Grants indica si la ACL concede op a user sobre kind:name.
+func (a ACL) Grants(user, op, kind, name string) bool {
+	return (a.Principal == "*" || a.Principal == user) && slices.Contains(a.Ops, op) && a.Covers(kind, name)
 }
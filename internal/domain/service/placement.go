@@ -0,0 +1,98 @@
+package service
+
+import (
+	"hash/fnv"
+	"slices"
+)
+
+// Place reparte rf réplicas de cada una de las parts particiones entre
+// nodes. La partición p empieza en el nodo (start+p) mod n y sigue en
+// orden, así que los líderes preferidos (la primera réplica) quedan
+// repartidos; start sale del hash del tópico para que no todos los tópicos
+// empiecen en el mismo nodo. rf se acota a len(nodes).
+func Place(nodes []string, topic string, parts, rf int) [][]string {
+	n := len(nodes)
+	if n == 0 || parts <= 0 {
+		return nil
+	}
+	rf = min(max(rf, 1), n)
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(topic))
+	start := int(h.Sum32() % uint32(n))
+
+	out := make([][]string, parts)
+	for p := range out {
+		out[p] = make([]string, rf)
+		for i := range rf {
+			out[p][i] = nodes[(start+p+i)%n]
+		}
+	}
+	return out
+}
+
+// Rebalance ajusta una asignación existente a nodes con rf réplicas por
+// partición moviendo lo mínimo: conserva las réplicas que siguen en el
+// clúster (y su orden, para no cambiar de líder sin motivo), completa con
+// los nodos menos cargados y, si algún nodo queda por encima de la media,
+// le quita réplicas en favor de los que quedan por debajo.
+func Rebalance(cur [][]string, nodes []string, rf int) [][]string {
+	n := len(nodes)
+	if n == 0 {
+		return nil
+	}
+	rf = min(max(rf, 1), n)
+	load := make(map[string]int, n)
+	for _, id := range nodes {
+		load[id] = 0
+	}
+
+	out := make([][]string, len(cur))
+	for p, reps := range cur {
+		for _, id := range reps {
+			if _, ok := load[id]; ok && len(out[p]) < rf && !slices.Contains(out[p], id) {
+				out[p] = append(out[p], id)
+				load[id]++
+			}
+		}
+	}
+	for p := range out {
+		for len(out[p]) < rf {
+			id := lightest(nodes, load, out[p])
+			out[p] = append(out[p], id)
+			load[id]++
+		}
+	}
+
+	// techo de réplicas por nodo: ceil(total / n)
+	limit := (len(out)*rf + n - 1) / n
+	for p := range out {
+		for i, id := range out[p] {
+			if load[id] <= limit {
+				continue
+			}
+			to := lightest(nodes, load, out[p])
+			if to == "" || load[to]+1 >= load[id] {
+				continue
+			}
+			out[p][i] = to
+			load[id]--
+			load[to]++
+		}
+	}
+	return out
+}
+
+// lightest devuelve el nodo con menos réplicas que no esté en skip (en
+// empate, el primero de nodes).
+func lightest(nodes []string, load map[string]int, skip []string) string {
+	best := ""
+	for _, id := range nodes {
+		if slices.Contains(skip, id) {
+			continue
+		}
+		if best == "" || load[id] < load[best] {
+			best = id
+		}
+	}
+	return best
+}
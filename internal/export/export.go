@@ -0,0 +1,207 @@
+// Package export pasa mensajes de tópicos a formatos portables —JSON
+// Lines, CSV y protobuf delimitado (clusterpb.Message)— y de vuelta.
+package export
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// Format es el formato de un archivo exportado.
+type Format string
+
+const (
+	JSONL Format = "jsonl" // un objeto JSON por línea; payload en base64
+	CSV   Format = "csv"   // con cabecera; headers en JSON y payload en base64
+	Proto Format = "pb"    // clusterpb.Message precedidos de su tamaño (varint)
+)
+
+// exportPage son los mensajes leídos del store en cada Read.
+const exportPage = 500
+
+// ParseFormat acepta jsonl, csv y pb (y los alias ndjson y protobuf).
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "jsonl", "ndjson", "json":
+		return JSONL, nil
+	case "csv":
+		return CSV, nil
+	case "pb", "protobuf", "proto":
+		return Proto, nil
+	}
+	return "", errs.Invalid("unknown export format %q (jsonl, csv or pb)", s)
+}
+
+// FormatOf deduce el formato de la extensión de name; "" si no la reconoce.
+func FormatOf(name string) Format {
+	f, err := ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+	if err != nil {
+		return ""
+	}
+	return f
+}
+
+// ContentType es el tipo MIME con el que se sirve f.
+func ContentType(f Format) string {
+	switch f {
+	case JSONL:
+		return "application/x-ndjson"
+	case CSV:
+		return "text/csv"
+	}
+	return "application/octet-stream"
+}
+
+// Writer escribe mensajes en un formato; Close vuelca lo pendiente (no
+// cierra el io.Writer de debajo).
+type Writer interface {
+	Write(m model.Message) error
+	Close() error
+}
+
+// Reader lee mensajes de un archivo exportado; devuelve io.EOF al final.
+type Reader interface {
+	Read() (model.Message, error)
+}
+
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case JSONL:
+		return newJSONLWriter(w), nil
+	case CSV:
+		return newCSVWriter(w), nil
+	case Proto:
+		return newProtoWriter(w), nil
+	}
+	return nil, errs.Invalid("unknown export format %q", f)
+}
+
+func NewReader(r io.Reader, f Format) (Reader, error) {
+	switch f {
+	case JSONL:
+		return newJSONLReader(r), nil
+	case CSV:
+		return newCSVReader(r), nil
+	case Proto:
+		return newProtoReader(r), nil
+	}
+	return nil, errs.Invalid("unknown export format %q", f)
+}
+
+/*──────────  export / import  ──────────*/
+
+// Source es lo que Export necesita del store (outbound.MessageStore).
+type Source interface {
+	Read(ctx context.Context, topic string, part int, from uint64, max int) ([]model.Message, error)
+}
+
+// Export escribe en w los mensajes de q; parts es el nº de particiones del
+// tópico. Los registros de control no se exportan. Con filtro de tiempo,
+// un mensaje sin TimeHeader es un error: no se sabe si entra. Devuelve
+// cuántos mensajes escribió.
+func Export(ctx context.Context, src Source, w Writer, q model.ExportQuery, parts int) (int, error) {
+	list := []int{q.Partition}
+	if q.Partition < 0 {
+		list = make([]int, parts)
+		for p := range list {
+			list[p] = p
+		}
+	} else if q.Partition >= parts {
+		return 0, errs.OutOfRange("partition %d of %q", q.Partition, q.Topic)
+	}
+
+	n := 0
+	for _, p := range list {
+		from := q.From
+		for q.To == 0 || from < q.To {
+			msgs, err := src.Read(ctx, q.Topic, p, from, exportPage)
+			if err != nil {
+				return n, err
+			}
+			if len(msgs) == 0 {
+				break
+			}
+			for _, m := range msgs {
+				if q.To != 0 && m.Offset >= q.To {
+					break
+				}
+				if m.IsControl() {
+					continue
+				}
+				if q.Timed() && m.Time().IsZero() {
+					return n, errs.Invalid("%s:%d offset %d has no %s header: filter it by offset instead of since/until",
+						q.Topic, p, m.Offset, model.TimeHeader)
+				}
+				if !q.Match(m) {
+					continue
+				}
+				if err := w.Write(m); err != nil {
+					return n, err
+				}
+				n++
+			}
+			from = msgs[len(msgs)-1].Offset + 1
+		}
+	}
+	return n, nil
+}
+
+// Sink es lo que Import necesita del store (outbound.MessageStore).
+type Sink interface {
+	Append(ctx context.Context, m model.Message) (uint64, error)
+	AppendWithOffset(ctx context.Context, m model.Message) error
+}
+
+// ImportOptions: Topic y Partition (>= 0) sustituyen los del archivo;
+// Partitions es el nº de particiones del tópico destino. Con Preserve cada
+// mensaje conserva su offset e ID (los offsets que ya existen no se
+// pisan); sin él se añade al final de la partición con un ID nuevo.
+type ImportOptions struct {
+	Topic      string
+	Partition  int
+	Partitions int
+	Preserve   bool
+}
+
+// Import carga los mensajes de r; devuelve cuántos leyó.
+func Import(ctx context.Context, dst Sink, r Reader, opts ImportOptions) (int, error) {
+	n := 0
+	for {
+		m, err := r.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if m.IsControl() {
+			continue
+		}
+		if opts.Topic != "" {
+			m.Topic = opts.Topic
+		}
+		if opts.Partition >= 0 {
+			m.PartID = opts.Partition
+		}
+		if m.PartID < 0 || m.PartID >= opts.Partitions {
+			return n, errs.OutOfRange("message %d: partition %d of %q", n, m.PartID, m.Topic)
+		}
+		m.Epoch = 0 // no lo escribió ningún líder de este clúster
+		if opts.Preserve {
+			err = dst.AppendWithOffset(ctx, m)
+		} else {
+			m.ID = uuid.New()
+			_, err = dst.Append(ctx, m)
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
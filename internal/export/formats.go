@@ -0,0 +1,229 @@
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/cluster"
+	pb "github.com/MateoRamirezRubio1/project_MOM/internal/clusterpb"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/errs"
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// record es la forma de un mensaje en JSON Lines. Time sale de TimeHeader
+// para facilitar el análisis; al importar sólo se usa si falta la cabecera.
+type record struct {
+	Topic     string            `json:"topic"`
+	Partition int               `json:"partition"`
+	Offset    uint64            `json:"offset"`
+	ID        string            `json:"id"`
+	Key       string            `json:"key"`
+	Producer  string            `json:"producer,omitempty"`
+	Time      *time.Time        `json:"time,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   []byte            `json:"payload"`
+}
+
+func toRecord(m model.Message) record {
+	r := record{Topic: m.Topic, Partition: m.PartID, Offset: m.Offset, ID: m.ID.String(),
+		Key: m.Key, Producer: m.Producer, Headers: m.Headers, Payload: m.Payload}
+	if t := m.Time(); !t.IsZero() {
+		r.Time = &t
+	}
+	return r
+}
+
+func (r record) message() (model.Message, error) {
+	m := model.Message{Topic: r.Topic, PartID: r.Partition, Offset: r.Offset, Key: r.Key,
+		Producer: r.Producer, Headers: r.Headers, Payload: r.Payload}
+	if r.ID != "" {
+		id, err := uuid.Parse(r.ID)
+		if err != nil {
+			return m, errs.Invalid("message id %q: %v", r.ID, err)
+		}
+		m.ID = id
+	}
+	if r.Time != nil && m.Headers[model.TimeHeader] == "" {
+		if m.Headers == nil {
+			m.Headers = map[string]string{}
+		}
+		m.Headers[model.TimeHeader] = strconv.FormatInt(r.Time.UnixMilli(), 10)
+	}
+	return m, nil
+}
+
+/*──────────  JSON Lines  ──────────*/
+
+type jsonlWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (w *jsonlWriter) Write(m model.Message) error { return w.enc.Encode(toRecord(m)) }
+func (w *jsonlWriter) Close() error                { return w.buf.Flush() }
+
+type jsonlReader struct {
+	dec  *json.Decoder
+	line int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	return &jsonlReader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+func (r *jsonlReader) Read() (model.Message, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return model.Message{}, err
+		}
+		return model.Message{}, errs.Invalid("jsonl record %d: %v", r.line+1, err)
+	}
+	r.line++
+	return rec.message()
+}
+
+/*──────────  CSV  ──────────*/
+
+var csvHeader = []string{"topic", "partition", "offset", "id", "key", "producer", "time", "headers", "payload"}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter { return &csvWriter{w: csv.NewWriter(w)} }
+
+func (w *csvWriter) Write(m model.Message) error {
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	r := toRecord(m)
+	ts := ""
+	if r.Time != nil {
+		ts = r.Time.Format(time.RFC3339Nano)
+	}
+	headers := ""
+	if len(r.Headers) > 0 {
+		js, _ := json.Marshal(r.Headers)
+		headers = string(js)
+	}
+	return w.w.Write([]string{r.Topic, strconv.Itoa(r.Partition), strconv.FormatUint(r.Offset, 10),
+		r.ID, r.Key, r.Producer, ts, headers, base64.StdEncoding.EncodeToString(r.Payload)})
+}
+
+// Close escribe al menos la cabecera, para que un export vacío se pueda
+// importar.
+func (w *csvWriter) Close() error {
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	header bool
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = len(csvHeader)
+	return &csvReader{r: cr}
+}
+
+func (r *csvReader) Read() (model.Message, error) {
+	row, err := r.r.Read()
+	if err == nil && !r.header {
+		if !slices.Equal(row, csvHeader) {
+			return model.Message{}, errs.Invalid("csv header must be %v", csvHeader)
+		}
+		r.header = true
+		row, err = r.r.Read()
+	}
+	if err == io.EOF {
+		return model.Message{}, err
+	}
+	if err != nil {
+		return model.Message{}, errs.Invalid("csv: %v", err)
+	}
+	line, _ := r.r.FieldPos(0)
+	bad := func(field string, err error) (model.Message, error) {
+		return model.Message{}, errs.Invalid("csv line %d: %s: %v", line, field, err)
+	}
+
+	rec := record{Topic: row[0], ID: row[3], Key: row[4], Producer: row[5]}
+	if rec.Partition, err = strconv.Atoi(row[1]); err != nil {
+		return bad("partition", err)
+	}
+	if rec.Offset, err = strconv.ParseUint(row[2], 10, 64); err != nil {
+		return bad("offset", err)
+	}
+	if row[6] != "" {
+		t, err := time.Parse(time.RFC3339Nano, row[6])
+		if err != nil {
+			return bad("time", err)
+		}
+		rec.Time = &t
+	}
+	if row[7] != "" {
+		if err := json.Unmarshal([]byte(row[7]), &rec.Headers); err != nil {
+			return bad("headers", err)
+		}
+	}
+	if rec.Payload, err = base64.StdEncoding.DecodeString(row[8]); err != nil {
+		return bad("payload", err)
+	}
+	return rec.message()
+}
+
+/*──────────  protobuf delimitado  ──────────*/
+
+type protoWriter struct{ buf *bufio.Writer }
+
+func newProtoWriter(w io.Writer) *protoWriter { return &protoWriter{buf: bufio.NewWriter(w)} }
+
+func (w *protoWriter) Write(m model.Message) error {
+	_, err := protodelim.MarshalTo(w.buf, cluster.ToPB(m))
+	return err
+}
+
+func (w *protoWriter) Close() error { return w.buf.Flush() }
+
+type protoReader struct {
+	r *bufio.Reader
+	n int
+}
+
+func newProtoReader(r io.Reader) *protoReader { return &protoReader{r: bufio.NewReader(r)} }
+
+func (r *protoReader) Read() (model.Message, error) {
+	var m pb.Message
+	if err := protodelim.UnmarshalFrom(r.r, &m); err != nil {
+		if err == io.EOF {
+			return model.Message{}, err
+		}
+		return model.Message{}, errs.Invalid("pb message %d: %v", r.n+1, err)
+	}
+	r.n++
+	return cluster.FromPB(&m), nil
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"github.com/dgraph-io/badger/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sample es un valor con sus etiquetas, en el orden declarado.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// gaugeFunc calcula sus valores en cada scrape llamando a fn. Se usa para
+// estado que ya vive en otro sitio (Badger, HWM en RAM, colas) y así no
+// hay que actualizar gauges desde cada camino de escritura.
+type gaugeFunc struct {
+	desc *prometheus.Desc
+	fn   func() []Sample
+}
+
+func (g *gaugeFunc) Describe(ch chan<- *prometheus.Desc) { ch <- g.desc }
+
+func (g *gaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range g.fn() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, s.Value, s.Labels...)
+	}
+}
+
+// RegisterGaugeFunc registra un gauge calculado bajo demanda.
+func RegisterGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	prometheus.MustRegister(GaugeFunc(name, help, labels, fn))
+}
+
+// GaugeFunc es el gauge de RegisterGaugeFunc sin registrar.
+func GaugeFunc(name, help string, labels []string, fn func() []Sample) prometheus.Collector {
+	return &gaugeFunc{desc: prometheus.NewDesc(name, help, labels, nil), fn: fn}
+}
+
+// RegisterBadger expone el tamaño del LSM y del value log de db.
+func RegisterBadger(db *badger.DB) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "mom_badger_lsm_bytes",
+			Help: "Size of Badger's LSM tree on disk.",
+		}, func() float64 { lsm, _ := db.Size(); return float64(lsm) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "mom_badger_vlog_bytes",
+			Help: "Size of Badger's value log on disk.",
+		}, func() float64 { _, vlog := db.Size(); return float64(vlog) }),
+	)
+}
@@ -0,0 +1,116 @@
+// Package metrics reúne las métricas Prometheus del broker y el handler
+// que las expone en /metrics.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// Operations cuenta publish/pull/enqueue/dequeue/ack por recurso y resultado.
+	Operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_operations_total",
+		Help: "Broker operations by type, topic/queue and result.",
+	}, []string{"op", "resource", "result"})
+
+	// OperationLatency mide la duración de esas mismas operaciones.
+	OperationLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mom_operation_duration_seconds",
+		Help:    "Latency of broker operations.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5 ms … ~4 s
+	}, []string{"op", "resource"})
+
+	// ConsumerLag = HWM - offset confirmado, por grupo/tópico/partición.
+	ConsumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mom_consumer_lag",
+		Help: "Messages between the partition high watermark and the group's committed offset.",
+	}, []string{"group", "topic", "partition"})
+
+	// LagAlerts cuenta las alertas publicadas en el tópico interno.
+	LagAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_consumer_lag_alerts_total",
+		Help: "Lag threshold alerts published.",
+	}, []string{"group", "topic"})
+
+	// ReplicationErrors cuenta los Append del consenso que fallan por peer.
+	ReplicationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_replication_send_errors_total",
+		Help: "Failed replication Append calls to peers.",
+	}, []string{"peer"})
+
+	// Forwarded cuenta las operaciones reenviadas a su dueño (op, nodo).
+	Forwarded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_forwarded_requests_total",
+		Help: "Client operations forwarded to the owning node.",
+	}, []string{"op", "node"})
+
+	// ReconcileDuration mide cada pasada de la anti-entropía.
+	ReconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "mom_reconcile_duration_seconds",
+		Help:    "Duration of an anti-entropy pass.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	})
+
+	// AntiEntropyConflicts cuenta los rangos reparados por la anti-entropía.
+	AntiEntropyConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_antientropy_conflicts_total",
+		Help: "Offset ranges found divergent from the authoritative replica.",
+	}, []string{"topic"})
+
+	// MembershipChanges cuenta los cambios de estado del detector de fallos.
+	MembershipChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_membership_changes_total",
+		Help: "Peer state transitions seen by the failure detector.",
+	}, []string{"node", "state"})
+
+	// MirrorLag = HWM en el origen - checkpoint, por mirror/tópico/partición.
+	MirrorLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mom_mirror_lag",
+		Help: "Messages of the source partition not yet copied by the mirror.",
+	}, []string{"mirror", "topic", "partition"})
+
+	// Mirrored cuenta los mensajes copiados desde el clúster de origen.
+	Mirrored = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_mirrored_messages_total",
+		Help: "Messages copied from the source cluster by each mirror.",
+	}, []string{"mirror", "topic"})
+
+	// AuthFailures cuenta las peticiones rechazadas por AuthMiddleware.
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mom_auth_failures_total",
+		Help: "Requests rejected by authentication.",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		Operations, OperationLatency,
+		ConsumerLag, LagAlerts,
+		ReplicationErrors, Forwarded, ReconcileDuration, AntiEntropyConflicts,
+		MembershipChanges,
+		MirrorLag, Mirrored,
+		AuthFailures,
+	)
+}
+
+// Unknown es la etiqueta de recurso de las operaciones cuyo tópico o cola
+// no se pudo validar: un nombre inventado en la petición no crea series.
+const Unknown = "_unknown"
+
+// Observe registra una operación que empezó en start; err decide el resultado.
+// Pensado para usarse con defer y retornos con nombre.
+func Observe(op, resource string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	Operations.WithLabelValues(op, resource, result).Inc()
+	OperationLatency.WithLabelValues(op, resource).Observe(time.Since(start).Seconds())
+}
+
+// Handler sirve el registro por defecto en formato Prometheus.
+func Handler() http.Handler { return promhttp.Handler() }
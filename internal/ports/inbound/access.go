@@ -0,0 +1,24 @@
+package inbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// Access gestiona las cuentas y las ACLs (/login, /admin/users,
+// /admin/acls). Los usuarios se devuelven sin el hash de la contraseña.
+type Access interface {
+	Login(ctx context.Context, user, password string) (token string, err error)
+
+	ListUsers(ctx context.Context) ([]model.User, error)
+	CreateUser(ctx context.Context, name, password string, admin bool, creator string) error
+	// UpdateUser cambia la contraseña y/o el rol; nil deja el valor actual.
+	UpdateUser(ctx context.Context, name string, password *string, admin *bool) error
+	DeleteUser(ctx context.Context, name string) error
+
+	ListACLs(ctx context.Context) ([]model.ACL, error)
+	// AddACL devuelve la ACL creada, con su ID.
+	AddACL(ctx context.Context, a model.ACL, creator string) (model.ACL, error)
+	DeleteACL(ctx context.Context, id string) error
+}
@@ -1,16 +1,41 @@
 package inbound
 
-import "context"
+import (
+	"context"
+	"io"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
 
 // Admin expone todas las operaciones de gestión (tópicos y colas).
 type Admin interface {
 	// Tópicos
-	CreateTopic(ctx context.Context, name string, partitions int, user string) error
+	// CreateTopic con replicationFactor 0 usa el factor por defecto.
+	CreateTopic(ctx context.Context, name string, partitions, replicationFactor int, user string) error
 	ListTopics(ctx context.Context) ([]string, error)
 	DeleteTopic(ctx context.Context, name, user string) error
+	DescribeTopic(ctx context.Context, name string) (model.TopicDescription, error)
+	// ReassignTopic fija las réplicas de cada partición; con replicas nil
+	// las reparte de nuevo sobre los nodos actuales.
+	ReassignTopic(ctx context.Context, name string, replicas [][]string, replicationFactor int) (model.Topic, error)
+	// ExportTopic escribe en w los mensajes de q en format (jsonl, csv o
+	// pb); devuelve cuántos escribió.
+	ExportTopic(ctx context.Context, w io.Writer, format string, q model.ExportQuery) (int, error)
 
 	// Colas
 	CreateQueue(ctx context.Context, name, user string) error
 	ListQueues(ctx context.Context) ([]string, error)
 	DeleteQueue(ctx context.Context, name, user string) error
+	DescribeQueue(ctx context.Context, name string) (model.QueueDescription, error)
+
+	// Cuotas
+	GetQuota(ctx context.Context, scope, name string) (model.Quota, model.QuotaUsage, error)
+	SetQuota(ctx context.Context, scope, name string, q model.Quota) error
+
+	// Auditoría
+	QueryAudit(ctx context.Context, f model.AuditFilter) ([]model.AuditEvent, error)
+
+	// Backup escribe en w un backup del almacenamiento de este nodo con lo
+	// escrito desde la versión since (0 = completo).
+	Backup(ctx context.Context, w io.Writer, since uint64) (model.BackupManifest, error)
 }
@@ -0,0 +1,14 @@
+package inbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// Cluster gestiona la membresía del clúster en caliente.
+type Cluster interface {
+	Nodes(ctx context.Context) ([]model.Node, error)
+	AddNode(ctx context.Context, n model.Node) error
+	RemoveNode(ctx context.Context, id string) error
+}
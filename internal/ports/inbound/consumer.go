@@ -11,4 +11,8 @@ type Consumer interface {
 	Pull(ctx context.Context, topic, group string, part int, max int) ([]model.Message, error)
 	// Commit offset leído
 	Commit(ctx context.Context, topic, group string, part int, offset uint64) error
+	// Offsets confirmados y lag de un grupo
+	DescribeGroup(ctx context.Context, group string) (model.GroupDescription, error)
+	// Lag por grupo/tópico/partición ("" = todos)
+	Lag(ctx context.Context, group, topic string) ([]model.ConsumerLag, error)
 }
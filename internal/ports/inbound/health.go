@@ -0,0 +1,13 @@
+package inbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// Health alimenta /healthz, /readyz y /cluster/status.
+type Health interface {
+	Ready(ctx context.Context) model.Readiness
+	ClusterStatus(ctx context.Context) model.ClusterStatus
+}
@@ -0,0 +1,20 @@
+package inbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// Mirror gestiona la copia de tópicos desde otros clústeres.
+type Mirror interface {
+	ListMirrors(ctx context.Context) ([]model.Mirror, error)
+	// CreateMirror crea los tópicos destino que falten con las mismas
+	// particiones que en el origen.
+	CreateMirror(ctx context.Context, m model.Mirror, user string) error
+	MirrorStatus(ctx context.Context, name string) (model.MirrorStatus, error)
+	PauseMirror(ctx context.Context, name string, paused bool) error
+	DeleteMirror(ctx context.Context, name string) error
+	// TranslateOffset traduce un offset del tópico de origen al destino.
+	TranslateOffset(ctx context.Context, name, topic string, part int, offset uint64) (model.OffsetTranslation, error)
+}
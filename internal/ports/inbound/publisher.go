@@ -1,7 +1,14 @@
 package inbound
 
-import "context"
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
 
 type Publisher interface {
-	Publish(ctx context.Context, topic, key, payload, user string) (part int, offset uint64, err error)
+	// Publish con acks=0 responde antes de escribir: offset no es válido.
+	Publish(ctx context.Context, topic, key, payload, user string, acks model.Acks) (part int, offset uint64, err error)
+	// Flush espera las publicaciones acks=0 pendientes o a que venza ctx.
+	Flush(ctx context.Context) error
 }
@@ -0,0 +1,13 @@
+package outbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// AuditLog es el registro append-only de eventos de administración y auth.
+type AuditLog interface {
+	Record(ctx context.Context, ev model.AuditEvent) error
+	Query(ctx context.Context, f model.AuditFilter) ([]model.AuditEvent, error)
+}
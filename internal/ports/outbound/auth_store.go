@@ -4,4 +4,13 @@ import "context"
 
 type AuthStore interface {
 	Validate(ctx context.Context, token string) (username string, ok bool)
+	// IsAdmin indica si username puede usar las rutas /admin.
+	IsAdmin(ctx context.Context, username string) bool
+	// Login comprueba la contraseña de username y devuelve un token.
+	Login(ctx context.Context, username, password string) (token string, err error)
+	// Hash deriva lo que se guarda de una contraseña (model.User.Hash).
+	Hash(password string) (string, error)
+	// Authorize devuelve un error ErrForbidden si username no puede hacer
+	// op (model.OpRead…) sobre el recurso kind:name.
+	Authorize(ctx context.Context, username, op, kind, name string) error
 }
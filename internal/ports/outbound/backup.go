@@ -0,0 +1,16 @@
+package outbound
+
+import (
+	"context"
+	"io"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// Backup vuelca el almacenamiento del nodo: mensajes, HWM, catálogo,
+// offsets de grupos, colas y estado de los logs replicados.
+type Backup interface {
+	// Backup escribe en w una copia consistente de lo escrito desde la
+	// versión since (0 = todo) sin parar el nodo.
+	Backup(ctx context.Context, w io.Writer, since uint64) (model.BackupManifest, error)
+}
@@ -0,0 +1,9 @@
+package outbound
+
+import "context"
+
+// HealthChecker lo implementan los adapters que pueden decir si están
+// operativos (p. ej. Badger abierto).
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
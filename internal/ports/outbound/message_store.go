@@ -14,9 +14,15 @@ type MessageStore interface {
 	Read(ctx context.Context, topic string, part int,
 		from uint64, max int) ([]model.Message, error)
 	Delete(ctx context.Context, topic string, part int, offset uint64) error
+	// Truncate borra los offsets >= from y deja el HWM en from.
+	Truncate(ctx context.Context, topic string, part int, from uint64) error
+	PartitionStats(ctx context.Context, topic string, part int) (model.PartitionStats, error)
+	// HighWatermark devuelve el próximo offset a asignar (persistido).
+	HighWatermark(ctx context.Context, topic string, part int) (uint64, error)
 
 	// colas ------------------------------
 	Enqueue(ctx context.Context, queue string, msg model.Message) error
 	Dequeue(ctx context.Context, queue string) (*model.Message, error)
 	Ack(ctx context.Context, queue string, id uuid.UUID) error
+	QueueStats(ctx context.Context, queue string) (model.QueueStats, error)
 }
@@ -1,21 +1,60 @@
 package outbound
 
-import "context"
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
 
 // MetaStore almacena metadatos de tópicos, colas y offsets.
 type MetaStore interface {
 	// ­­­­­­­­­­­­­ TOPICS ­­­­­­­­­­­­
-	CreateTopic(ctx context.Context, name string, partitions int, creator string) error
+	// CreateTopic guarda el tópico; replicas son los nodos de cada
+	// partición (nil → todos).
+	CreateTopic(ctx context.Context, name string, partitions int, replicas [][]string, creator string) error
 	GetTopic(ctx context.Context, name string) (partitions int, err error)
 	ListTopics(ctx context.Context) ([]string, error)
 	DeleteTopic(ctx context.Context, name, user string) error
+	DescribeTopic(ctx context.Context, name string) (model.Topic, error)
+	// SetReplicas cambia la asignación de réplicas de un tópico existente.
+	SetReplicas(ctx context.Context, name string, replicas [][]string) error
 
 	// ­­­­­­­­­­­­­ QUEUES ­­­­­­­­­­­­
 	CreateQueue(ctx context.Context, name, creator string) error
 	ListQueues(ctx context.Context) ([]string, error)
 	DeleteQueue(ctx context.Context, name, user string) error
+	DescribeQueue(ctx context.Context, name string) (model.Queue, error)
 
 	// ­­­­­­­­­­­­­ OFFSETS (consumer groups) ­­­­­­­­­­­­
 	GetOffset(ctx context.Context, group, topic string, part int) (uint64, error)
 	CommitOffset(ctx context.Context, group, topic string, part int, offset uint64) error
+	// GroupOffsets lista los offsets confirmados del grupo (sin EndOffset/Lag).
+	GroupOffsets(ctx context.Context, group string) ([]model.GroupOffset, error)
+	ListGroups(ctx context.Context) ([]string, error)
+
+	// ­­­­­­­­­­­­­ MIRRORS ­­­­­­­­­­­­
+	// PutMirror crea o sustituye la definición de un mirror.
+	PutMirror(ctx context.Context, m model.Mirror) error
+	GetMirror(ctx context.Context, name string) (model.Mirror, error)
+	ListMirrors(ctx context.Context) ([]model.Mirror, error)
+	// DeleteMirror borra el mirror y sus checkpoints.
+	DeleteMirror(ctx context.Context, name string) error
+	// CommitMirror guarda el próximo offset de origen por copiar.
+	CommitMirror(ctx context.Context, name, topic string, part int, next uint64) error
+	MirrorCheckpoints(ctx context.Context, name string) ([]model.MirrorCheckpoint, error)
+
+	// ­­­­­­­­­­­­­ USERS & ACLs ­­­­­­­­­­­­
+	// PutUser crea o sustituye la cuenta.
+	PutUser(ctx context.Context, u model.User) error
+	GetUser(ctx context.Context, name string) (model.User, error)
+	ListUsers(ctx context.Context) ([]model.User, error)
+	DeleteUser(ctx context.Context, name string) error
+	PutACL(ctx context.Context, a model.ACL) error
+	ListACLs(ctx context.Context) ([]model.ACL, error)
+	DeleteACL(ctx context.Context, id string) error
+
+	// ­­­­­­­­­­­­­ QUOTAS ­­­­­­­­­­­­
+	// PutQuota crea o sustituye la cuota de (Scope, Name).
+	PutQuota(ctx context.Context, s model.QuotaSetting) error
+	ListQuotas(ctx context.Context) ([]model.QuotaSetting, error)
 }
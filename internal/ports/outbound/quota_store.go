@@ -0,0 +1,22 @@
+package outbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// QuotaStore guarda las cuotas y aplica los límites (token bucket).
+// Todas las operaciones que rechazan devuelven *model.QuotaError.
+type QuotaStore interface {
+	// Take consume msgs mensajes y bytes bytes de la cuota de cada ref, de
+	// todas o de ninguna: si una rechaza no se cobra nada. En tópicos y
+	// colas suma bytes al almacenamiento usado, suyo y del usuario dueño.
+	Take(ctx context.Context, msgs, bytes int, refs ...model.QuotaRef) error
+	// Reserve / Release llevan la cuenta de tópicos y colas de un usuario.
+	Reserve(ctx context.Context, user, kind string) error
+	Release(ctx context.Context, user, kind string)
+
+	GetQuota(ctx context.Context, scope, name string) (model.Quota, model.QuotaUsage, error)
+	SetQuota(ctx context.Context, scope, name string, q model.Quota) error
+}
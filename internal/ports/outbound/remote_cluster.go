@@ -0,0 +1,19 @@
+package outbound
+
+import (
+	"context"
+
+	"github.com/MateoRamirezRubio1/project_MOM/internal/domain/model"
+)
+
+// RemoteCluster es la API REST de otro clúster del broker (el origen de un
+// mirror). Pull sólo devuelve mensajes comprometidos en el origen.
+type RemoteCluster interface {
+	DescribeTopic(ctx context.Context, topic string) (model.TopicDescription, error)
+	Pull(ctx context.Context, topic, group string, part, max int) ([]model.Message, error)
+	Commit(ctx context.Context, topic, group string, part int, offset uint64) error
+}
+
+// RemoteDialer devuelve el cliente de la API REST en base, autenticado con
+// token.
+type RemoteDialer func(base, token string) RemoteCluster
@@ -0,0 +1,140 @@
+// Package tracing configura OpenTelemetry y ofrece los helpers que usan
+// REST, casos de uso, storage y cluster para crear spans y propagar el
+// contexto W3C (traceparent) por HTTP, metadata gRPC y cabeceras de mensaje.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const instrumentation = "github.com/MateoRamirezRubio1/project_MOM"
+
+var propagator = propagation.TraceContext{}
+
+// Setup instala el TracerProvider global. exporter puede ser "otlp"
+// (gRPC hacia endpoint), "stdout" o "none"; con "none" los spans no se
+// exportan pero el contexto se sigue propagando.
+func Setup(ctx context.Context, exporter, endpoint, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithInsecure()}
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (otlp|stdout|none)", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start abre un span hijo del que venga en ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartKind es como Start pero indicando el tipo (server, client, …).
+func StartKind(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End cierra el span marcándolo como error si err != nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Detach conserva el span de ctx pero no su cancelación; para trabajo que
+// sigue después de responder (p. ej. la réplica asíncrona).
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+/*──────────  propagación  ──────────*/
+
+// Inject escribe traceparent/tracestate en headers (cabeceras de mensaje).
+func Inject(ctx context.Context, headers map[string]string) {
+	propagator.Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// Extract continúa la traza guardada en las cabeceras de un mensaje.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return propagator.Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// ExtractHTTP continúa la traza de una petición REST.
+func ExtractHTTP(ctx context.Context, h propagation.HeaderCarrier) context.Context {
+	return propagator.Extract(ctx, h)
+}
+
+// InjectHTTP añade el contexto de traza a una petición REST saliente.
+func InjectHTTP(ctx context.Context, h propagation.HeaderCarrier) {
+	propagator.Inject(ctx, h)
+}
+
+// OutgoingGRPC añade el contexto de traza a la metadata saliente.
+func OutgoingGRPC(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	propagator.Inject(ctx, mdCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// IncomingGRPC recupera el contexto de traza de la metadata entrante.
+func IncomingGRPC(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return propagator.Extract(ctx, mdCarrier(md))
+}
+
+// mdCarrier adapta metadata.MD a propagation.TextMapCarrier.
+type mdCarrier metadata.MD
+
+func (c mdCarrier) Get(k string) string {
+	if v := metadata.MD(c).Get(k); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+func (c mdCarrier) Set(k, v string) { metadata.MD(c).Set(k, v) }
+func (c mdCarrier) Keys() []string {
+	out := make([]string, 0, len(c))
+	for k := range c {
+		out = append(out, k)
+	}
+	return out
+}